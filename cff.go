@@ -0,0 +1,904 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file supports OpenType fonts with PostScript outlines. The Compact
+// Font Format (CFF) table of such a font, or the CFF2 table of a variable
+// font, is parsed and rebuilt as a CID-keyed CFF font program that contains
+// only the glyphs of the runes used in the document. Each glyph of the subset
+// is assigned the CID that equals its rune so that the Identity-H encoding,
+// the widths array and the ToUnicode map that are written for TrueType fonts
+// apply unchanged. Subroutines are inlined and CFF2 blend operations are
+// resolved, so the subset does not depend on subroutine or variation data.
+
+// cffOperandType is a number in a DICT or charstring. raw holds the original
+// encoding, which is reused when the value is written unchanged.
+type cffOperandType struct {
+	raw []byte
+	val float64
+}
+
+// cffDictEntryType is an operator with its operands.
+type cffDictEntryType struct {
+	op       int // operators with escape byte are 1200 + second byte
+	operands []cffOperandType
+}
+
+type cffDictType []cffDictEntryType
+
+// cffFDType is a font DICT with its private DICT and local subroutines. The
+// font DICT is empty for name-keyed fonts.
+type cffFDType struct {
+	dict    cffDictType
+	private cffDictType
+	subrs   [][]byte
+}
+
+// cffFontType holds the parts of a CFF or CFF2 table that are needed to build
+// a subset.
+type cffFontType struct {
+	version       int // 1 for CFF, 2 for CFF2
+	nameStr       string
+	topDict       cffDictType
+	charStrings   [][]byte
+	globalSubrs   [][]byte
+	fds           []cffFDType
+	fdSelect      []int     // font DICT index of each glyph; nil selects the first
+	regionIndexes [][]int   // CFF2: regions of each item variation data
+	scalars       []float64 // CFF2: factor of each region for the selected instance
+}
+
+const (
+	cffOpCharStrings = 17
+	cffOpPrivate     = 18
+	cffOpSubrs       = 19
+	cffOpVsindex     = 22
+	cffOpBlend       = 23
+	cffOpVstore      = 24
+	cffOpROS         = 1230
+	cffOpCIDCount    = 1234
+	cffOpFDArray     = 1236
+	cffOpFDSelect    = 1237
+	cffOpCharset     = 15
+	cffOpCSType      = 1206
+)
+
+// get returns the operands of the first entry with the specified operator.
+func (d cffDictType) get(op int) ([]cffOperandType, bool) {
+	for _, e := range d {
+		if e.op == op {
+			return e.operands, true
+		}
+	}
+	return nil, false
+}
+
+// getInt returns the integer value of operand j of op, or def if op is not
+// present.
+func (d cffDictType) getInt(op, j, def int) int {
+	if ops, ok := d.get(op); ok && j < len(ops) {
+		return int(ops[j].val)
+	}
+	return def
+}
+
+// cffIndex reads the INDEX structure at pos and returns its elements and the
+// position that follows it. CFF2 uses 32-bit element counts.
+func cffIndex(data []byte, pos int, cff2 bool) (items [][]byte, end int, err error) {
+	count := 0
+	switch {
+	case cff2 && pos+4 <= len(data):
+		count = int(binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+	case !cff2 && pos+2 <= len(data):
+		count = int(binary.BigEndian.Uint16(data[pos:]))
+		pos += 2
+	default:
+		return nil, pos, fmt.Errorf("CFF INDEX out of range")
+	}
+	if count == 0 {
+		return nil, pos, nil
+	}
+	if pos >= len(data) {
+		return nil, pos, fmt.Errorf("CFF INDEX out of range")
+	}
+	offSize := int(data[pos])
+	pos++
+	if offSize < 1 || offSize > 4 || pos+(count+1)*offSize > len(data) {
+		return nil, pos, fmt.Errorf("invalid CFF INDEX")
+	}
+	offset := func(j int) int {
+		v := 0
+		for _, b := range data[pos+j*offSize : pos+(j+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+	base := pos + (count+1)*offSize - 1
+	items = make([][]byte, count)
+	for j := 0; j < count; j++ {
+		start, stop := base+offset(j), base+offset(j+1)
+		if start > stop || stop > len(data) {
+			return nil, pos, fmt.Errorf("invalid CFF INDEX offset")
+		}
+		items[j] = data[start:stop]
+	}
+	return items, base + offset(count), nil
+}
+
+// cffReadOperand decodes the number that begins at data[pos] and returns it
+// with the position that follows it. dict selects the DICT encoding of b0 ==
+// 29, 30 and 255.
+func cffReadOperand(data []byte, pos int, dict bool) (op cffOperandType, end int, err error) {
+	b0 := int(data[pos])
+	need := func(n int) bool {
+		if pos+n > len(data) {
+			err = fmt.Errorf("truncated CFF number")
+			return false
+		}
+		return true
+	}
+	switch {
+	case b0 >= 32 && b0 <= 246:
+		op.val = float64(b0 - 139)
+		end = pos + 1
+	case b0 >= 247 && b0 <= 250:
+		if need(2) {
+			op.val = float64((b0-247)*256 + int(data[pos+1]) + 108)
+			end = pos + 2
+		}
+	case b0 >= 251 && b0 <= 254:
+		if need(2) {
+			op.val = float64(-(b0-251)*256 - int(data[pos+1]) - 108)
+			end = pos + 2
+		}
+	case b0 == 28:
+		if need(3) {
+			op.val = float64(int16(binary.BigEndian.Uint16(data[pos+1:])))
+			end = pos + 3
+		}
+	case b0 == 29 && dict:
+		if need(5) {
+			op.val = float64(int32(binary.BigEndian.Uint32(data[pos+1:])))
+			end = pos + 5
+		}
+	case b0 == 30 && dict:
+		var s strings.Builder
+		end = pos + 1
+		done := false
+		for !done && end < len(data) {
+			for _, nb := range []byte{data[end] >> 4, data[end] & 15} {
+				if done {
+					break
+				}
+				switch {
+				case nb <= 9:
+					s.WriteByte('0' + nb)
+				case nb == 0xa:
+					s.WriteByte('.')
+				case nb == 0xb:
+					s.WriteByte('E')
+				case nb == 0xc:
+					s.WriteString("E-")
+				case nb == 0xe:
+					s.WriteByte('-')
+				case nb == 0xf:
+					done = true
+				}
+			}
+			end++
+		}
+		op.val, _ = strconv.ParseFloat(s.String(), 64)
+	case b0 == 255 && !dict:
+		if need(5) {
+			op.val = float64(int32(binary.BigEndian.Uint32(data[pos+1:]))) / 65536
+			end = pos + 5
+		}
+	default:
+		err = fmt.Errorf("invalid CFF number")
+	}
+	if err == nil {
+		op.raw = data[pos:end]
+	}
+	return
+}
+
+// cffParseDict decodes a DICT. Blend operators of CFF2 private DICTs are
+// resolved with the font's scalars.
+func (cf *cffFontType) cffParseDict(data []byte) (dict cffDictType, err error) {
+	var operands []cffOperandType
+	vsindex := 0
+	for pos := 0; pos < len(data); {
+		b0 := int(data[pos])
+		if b0 <= 27 && b0 != 28 {
+			op := b0
+			pos++
+			if b0 == 12 {
+				if pos >= len(data) {
+					return nil, fmt.Errorf("truncated CFF DICT")
+				}
+				op = 1200 + int(data[pos])
+				pos++
+			}
+			switch op {
+			case cffOpBlend:
+				operands, err = cf.blend(operands, vsindex, true)
+				if err != nil {
+					return
+				}
+				continue
+			case cffOpVsindex:
+				if len(operands) > 0 {
+					vsindex = int(operands[0].val)
+				}
+			}
+			dict = append(dict, cffDictEntryType{op: op, operands: operands})
+			operands = nil
+			continue
+		}
+		var operand cffOperandType
+		operand, pos, err = cffReadOperand(data, pos, true)
+		if err != nil {
+			return
+		}
+		operands = append(operands, operand)
+	}
+	return
+}
+
+// blend replaces the operands of a CFF2 blend operator at the end of stack
+// with the values interpolated for the font's scalars.
+func (cf *cffFontType) blend(stack []cffOperandType, vsindex int, dict bool) ([]cffOperandType, error) {
+	if len(stack) == 0 || vsindex < 0 || vsindex >= len(cf.regionIndexes) {
+		return nil, fmt.Errorf("invalid CFF2 blend")
+	}
+	regions := cf.regionIndexes[vsindex]
+	n := int(stack[len(stack)-1].val)
+	stack = stack[:len(stack)-1]
+	start := len(stack) - n*(len(regions)+1)
+	if n < 0 || start < 0 {
+		return nil, fmt.Errorf("CFF2 blend stack underflow")
+	}
+	deltas := stack[start+n:]
+	out := append([]cffOperandType(nil), stack[:start+n]...)
+	for j := 0; j < n; j++ {
+		v := out[start+j].val
+		changed := false
+		for r, idx := range regions {
+			if idx < len(cf.scalars) && cf.scalars[idx] != 0 {
+				v += deltas[j*len(regions)+r].val * cf.scalars[idx]
+				changed = true
+			}
+		}
+		if changed {
+			out[start+j] = cffOperandType{raw: cffEncodeNumber(v, dict), val: v}
+		}
+	}
+	return out, nil
+}
+
+// cffEncodeNumber returns the DICT or charstring encoding of v.
+func cffEncodeNumber(v float64, dict bool) []byte {
+	if v == math.Trunc(v) && math.Abs(v) <= 32767 {
+		n := int(v)
+		switch {
+		case n >= -107 && n <= 107:
+			return []byte{byte(n + 139)}
+		case n >= 108 && n <= 1131:
+			n -= 108
+			return []byte{byte(n>>8 + 247), byte(n)}
+		case n >= -1131 && n <= -108:
+			n = -n - 108
+			return []byte{byte(n>>8 + 251), byte(n)}
+		}
+		return []byte{28, byte(n >> 8), byte(n)}
+	}
+	if !dict {
+		b := []byte{255, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(int32(math.Round(v*65536))))
+		return b
+	}
+	s := strings.ToUpper(strconv.FormatFloat(v, 'g', -1, 64))
+	s = strings.Replace(strings.Replace(s, "E+", "E", 1), "E-", "C", 1)
+	var nibbles []byte
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			nibbles = append(nibbles, byte(c-'0'))
+		case c == '.':
+			nibbles = append(nibbles, 0xa)
+		case c == 'E':
+			nibbles = append(nibbles, 0xb)
+		case c == 'C':
+			nibbles = append(nibbles, 0xc)
+		case c == '-':
+			nibbles = append(nibbles, 0xe)
+		}
+	}
+	nibbles = append(nibbles, 0xf)
+	if len(nibbles)%2 == 1 {
+		nibbles = append(nibbles, 0xf)
+	}
+	b := []byte{30}
+	for j := 0; j < len(nibbles); j += 2 {
+		b = append(b, nibbles[j]<<4|nibbles[j+1])
+	}
+	return b
+}
+
+// cffEncodeInt returns the fixed-size DICT encoding of n, which is used for
+// offsets so that the size of a DICT does not depend on their values.
+func cffEncodeInt(n int) []byte {
+	b := []byte{29, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(int32(n)))
+	return b
+}
+
+// cffParse reads the CFF or CFF2 table specified by data. The variations of a
+// CFF2 font are resolved for the normalized axis coordinates coords; nil
+// selects the default instance.
+func cffParse(data []byte, coords []float64) (cf *cffFontType, err error) {
+	if len(data) < 5 {
+		return nil, fmt.Errorf("CFF table is too short")
+	}
+	cf = &cffFontType{version: int(data[0])}
+	hdrSize := int(data[2])
+	pos := hdrSize
+	var topData []byte
+	switch cf.version {
+	case 1:
+		var names, tops [][]byte
+		if names, pos, err = cffIndex(data, pos, false); err != nil {
+			return
+		}
+		if tops, pos, err = cffIndex(data, pos, false); err != nil {
+			return
+		}
+		if len(names) == 0 || len(tops) == 0 {
+			return nil, fmt.Errorf("CFF table contains no font")
+		}
+		cf.nameStr = string(names[0])
+		topData = tops[0]
+		if _, pos, err = cffIndex(data, pos, false); err != nil { // String INDEX
+			return
+		}
+	case 2:
+		topLen := int(binary.BigEndian.Uint16(data[3:]))
+		if hdrSize+topLen > len(data) {
+			return nil, fmt.Errorf("invalid CFF2 header")
+		}
+		topData = data[hdrSize : hdrSize+topLen]
+		pos = hdrSize + topLen
+	default:
+		return nil, fmt.Errorf("unsupported CFF version %d", cf.version)
+	}
+	cff2 := cf.version == 2
+	if cf.globalSubrs, _, err = cffIndex(data, pos, cff2); err != nil {
+		return
+	}
+	if cf.topDict, err = cf.cffParseDict(topData); err != nil {
+		return
+	}
+	if cf.topDict.getInt(cffOpCSType, 0, 2) != 2 {
+		return nil, fmt.Errorf("unsupported CFF charstring type")
+	}
+	if off := cf.topDict.getInt(cffOpVstore, 0, 0); off > 0 {
+		var store *itemVariationStoreType
+		if store, err = parseItemVariationStore(data, off+2); err != nil {
+			return
+		}
+		for _, ivd := range store.data {
+			cf.regionIndexes = append(cf.regionIndexes, ivd.regionIndexes)
+		}
+		if coords != nil {
+			cf.scalars = store.regionScalars(coords)
+		}
+	}
+	off := cf.topDict.getInt(cffOpCharStrings, 0, 0)
+	if off <= 0 {
+		return nil, fmt.Errorf("CFF font has no charstrings")
+	}
+	if cf.charStrings, _, err = cffIndex(data, off, cff2); err != nil {
+		return
+	}
+	readPrivate := func(dict cffDictType) (fd cffFDType, err error) {
+		fd.dict = dict
+		ops, ok := dict.get(cffOpPrivate)
+		if !ok || len(ops) < 2 {
+			return
+		}
+		size, off := int(ops[0].val), int(ops[1].val)
+		if off < 0 || size < 0 || off+size > len(data) {
+			return fd, fmt.Errorf("invalid CFF private DICT")
+		}
+		if fd.private, err = cf.cffParseDict(data[off : off+size]); err != nil {
+			return
+		}
+		if subrs := fd.private.getInt(cffOpSubrs, 0, 0); subrs > 0 {
+			fd.subrs, _, err = cffIndex(data, off+subrs, cff2)
+		}
+		return
+	}
+	if off := cf.topDict.getInt(cffOpFDArray, 0, 0); off > 0 {
+		var dicts [][]byte
+		if dicts, _, err = cffIndex(data, off, cff2); err != nil {
+			return
+		}
+		for _, b := range dicts {
+			var dict cffDictType
+			if dict, err = cf.cffParseDict(b); err != nil {
+				return
+			}
+			var fd cffFDType
+			if fd, err = readPrivate(dict); err != nil {
+				return
+			}
+			cf.fds = append(cf.fds, fd)
+		}
+		if off := cf.topDict.getInt(cffOpFDSelect, 0, 0); off > 0 {
+			if cf.fdSelect, err = cffParseFDSelect(data, off, len(cf.charStrings)); err != nil {
+				return
+			}
+		}
+	} else {
+		var fd cffFDType
+		if fd, err = readPrivate(cf.topDict); err != nil {
+			return
+		}
+		fd.dict = nil
+		cf.fds = []cffFDType{fd}
+	}
+	if len(cf.fds) == 0 {
+		return nil, fmt.Errorf("CFF font has no font DICT")
+	}
+	return
+}
+
+// cffParseFDSelect returns the font DICT index of each glyph.
+func cffParseFDSelect(data []byte, pos, nGlyphs int) (fds []int, err error) {
+	errFDSelect := fmt.Errorf("invalid CFF FDSelect")
+	if pos >= len(data) {
+		return nil, errFDSelect
+	}
+	fds = make([]int, nGlyphs)
+	format := data[pos]
+	switch format {
+	case 0:
+		if pos+1+nGlyphs > len(data) {
+			return nil, errFDSelect
+		}
+		for j := range fds {
+			fds[j] = int(data[pos+1+j])
+		}
+	case 3, 4:
+		// Format 3 has 16-bit glyph indexes and 8-bit font DICT indexes,
+		// format 4 (CFF2) 32-bit glyph indexes and 16-bit font DICT indexes
+		gSize, fSize := 2, 1
+		if format == 4 {
+			gSize, fSize = 4, 2
+		}
+		read := func(p, size int) int {
+			v := 0
+			for _, b := range data[p : p+size] {
+				v = v<<8 | int(b)
+			}
+			return v
+		}
+		if pos+1+gSize > len(data) {
+			return nil, errFDSelect
+		}
+		nRanges := read(pos+1, gSize)
+		p := pos + 1 + gSize
+		if p+nRanges*(gSize+fSize)+gSize > len(data) {
+			return nil, errFDSelect
+		}
+		for j := 0; j < nRanges; j++ {
+			first := read(p, gSize)
+			fd := read(p+gSize, fSize)
+			next := read(p+gSize+fSize, gSize)
+			for g := first; g < next && g < nGlyphs; g++ {
+				fds[g] = fd
+			}
+			p += gSize + fSize
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CFF FDSelect format %d", format)
+	}
+	return
+}
+
+// cffSubrBias returns the bias added to subroutine numbers.
+func cffSubrBias(count int) int {
+	switch {
+	case count < 1240:
+		return 107
+	case count < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// cffCharStringType accumulates a charstring with inlined subroutines.
+type cffCharStringType struct {
+	cf      *cffFontType
+	fd      *cffFDType
+	out     []byte
+	stack   []cffOperandType
+	stems   int
+	vsindex int
+	done    bool
+}
+
+// flatten appends the operations of charstring cs to the output.
+func (cs *cffCharStringType) flatten(data []byte, depth int) error {
+	if depth > 10 {
+		return fmt.Errorf("CFF subroutines nested too deeply")
+	}
+	cff2 := cs.cf.version == 2
+	for pos := 0; pos < len(data) && !cs.done; {
+		b0 := int(data[pos])
+		if b0 >= 32 || b0 == 28 {
+			operand, end, err := cffReadOperand(data, pos, false)
+			if err != nil {
+				return err
+			}
+			cs.stack = append(cs.stack, operand)
+			pos = end
+			continue
+		}
+		op := b0
+		pos++
+		if b0 == 12 {
+			if pos >= len(data) {
+				return fmt.Errorf("truncated CFF charstring")
+			}
+			op = 1200 + int(data[pos])
+			pos++
+		}
+		switch {
+		case op == 10 || op == 29: // callsubr, callgsubr
+			subrs := cs.cf.globalSubrs
+			if op == 10 {
+				subrs = cs.fd.subrs
+			}
+			if len(cs.stack) == 0 {
+				return fmt.Errorf("CFF subroutine call without index")
+			}
+			j := int(cs.stack[len(cs.stack)-1].val) + cffSubrBias(len(subrs))
+			cs.stack = cs.stack[:len(cs.stack)-1]
+			if j < 0 || j >= len(subrs) {
+				return fmt.Errorf("CFF subroutine %d out of range", j)
+			}
+			if err := cs.flatten(subrs[j], depth+1); err != nil {
+				return err
+			}
+		case op == 11: // return
+			return nil
+		case op == 15 && cff2: // vsindex
+			if len(cs.stack) > 0 {
+				cs.vsindex = int(cs.stack[len(cs.stack)-1].val)
+			}
+			cs.stack = cs.stack[:0]
+		case op == 16 && cff2: // blend
+			stack, err := cs.cf.blend(cs.stack, cs.vsindex, false)
+			if err != nil {
+				return err
+			}
+			cs.stack = stack
+		default:
+			switch op {
+			case 1, 3, 18, 23, 19, 20: // stem hints and masks
+				cs.stems += len(cs.stack) / 2
+			}
+			cs.emit(op)
+			if op == 19 || op == 20 {
+				n := (cs.stems + 7) / 8
+				if pos+n > len(data) {
+					return fmt.Errorf("truncated CFF hint mask")
+				}
+				cs.out = append(cs.out, data[pos:pos+n]...)
+				pos += n
+			}
+			if op == 14 {
+				cs.done = true
+			}
+		}
+	}
+	return nil
+}
+
+// emit appends the operands on the stack and operator op to the output.
+func (cs *cffCharStringType) emit(op int) {
+	for _, operand := range cs.stack {
+		cs.out = append(cs.out, operand.raw...)
+	}
+	cs.stack = cs.stack[:0]
+	if op >= 1200 {
+		cs.out = append(cs.out, 12, byte(op-1200))
+	} else {
+		cs.out = append(cs.out, byte(op))
+	}
+}
+
+// charString returns the charstring of glyph gid without subroutine calls.
+func (cf *cffFontType) charString(gid int) ([]byte, error) {
+	if gid < 0 || gid >= len(cf.charStrings) {
+		return nil, fmt.Errorf("CFF glyph %d out of range", gid)
+	}
+	fdIdx := 0
+	if cf.fdSelect != nil {
+		fdIdx = cf.fdSelect[gid]
+	}
+	if fdIdx >= len(cf.fds) {
+		return nil, fmt.Errorf("CFF font DICT %d out of range", fdIdx)
+	}
+	fd := &cf.fds[fdIdx]
+	cs := cffCharStringType{cf: cf, fd: fd, vsindex: fd.private.getInt(cffOpVsindex, 0, 0)}
+	if err := cs.flatten(cf.charStrings[gid], 0); err != nil {
+		return nil, err
+	}
+	if !cs.done {
+		// CFF2 charstrings have no endchar operator
+		cs.emit(14)
+	}
+	return cs.out, nil
+}
+
+// cffWriteIndex returns the CFF encoding of an INDEX with the specified
+// elements.
+func cffWriteIndex(items [][]byte) []byte {
+	b := []byte{byte(len(items) >> 8), byte(len(items))}
+	if len(items) == 0 {
+		return b
+	}
+	total := 1
+	for _, item := range items {
+		total += len(item)
+	}
+	offSize := 1
+	for total >= 1<<(8*uint(offSize)) {
+		offSize++
+	}
+	b = append(b, byte(offSize))
+	put := func(v int) {
+		for j := offSize - 1; j >= 0; j-- {
+			b = append(b, byte(v>>(8*uint(j))))
+		}
+	}
+	off := 1
+	put(off)
+	for _, item := range items {
+		off += len(item)
+		put(off)
+	}
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+// cffWriteDict returns the encoding of the entries of dict except those with
+// operators listed in skip.
+func cffWriteDict(dict cffDictType, skip ...int) (b []byte) {
+	for _, e := range dict {
+		omit := false
+		for _, op := range skip {
+			omit = omit || e.op == op
+		}
+		if omit {
+			continue
+		}
+		for _, operand := range e.operands {
+			if len(operand.raw) > 0 && operand.raw[0] == 255 {
+				// Fixed point charstring encoding is not valid in a DICT
+				b = append(b, cffEncodeNumber(operand.val, true)...)
+			} else {
+				b = append(b, operand.raw...)
+			}
+		}
+		if e.op >= 1200 {
+			b = append(b, 12, byte(e.op-1200))
+		} else {
+			b = append(b, byte(e.op))
+		}
+	}
+	return
+}
+
+// cffAppendOp appends an operator with integer operands to b. Operands are
+// written in the fixed-size encoding.
+func cffAppendOp(b []byte, op int, vals ...int) []byte {
+	for _, v := range vals {
+		b = append(b, cffEncodeInt(v)...)
+	}
+	if op >= 1200 {
+		return append(b, 12, byte(op-1200))
+	}
+	return append(b, byte(op))
+}
+
+// subset returns a CID-keyed CFF font program that contains glyph 0 followed
+// by the glyphs specified by gids. The CID of each glyph is given by the
+// corresponding element of cids.
+func (cf *cffFontType) subset(nameStr string, gids, cids []int) ([]byte, error) {
+	charStrings := make([][]byte, 0, len(gids)+1)
+	fdSel := make([]int, 0, len(gids)+1)
+	for _, gid := range append([]int{0}, gids...) {
+		cs, err := cf.charString(gid)
+		if err != nil {
+			return nil, err
+		}
+		charStrings = append(charStrings, cs)
+		fd := 0
+		if cf.fdSelect != nil {
+			fd = cf.fdSelect[gid]
+		}
+		fdSel = append(fdSel, fd)
+	}
+	// charset, format 0
+	charset := []byte{0}
+	for _, cid := range cids {
+		charset = append(charset, byte(cid>>8), byte(cid))
+	}
+	// FDSelect, format 3
+	var ranges []byte
+	nRanges := 0
+	for j, fd := range fdSel {
+		if j == 0 || fd != fdSel[j-1] {
+			ranges = append(ranges, byte(j>>8), byte(j), byte(fd))
+			nRanges++
+		}
+	}
+	fdSelect := append([]byte{3, byte(nRanges >> 8), byte(nRanges)}, ranges...)
+	fdSelect = append(fdSelect, byte(len(fdSel)>>8), byte(len(fdSel)))
+	charStringIndex := cffWriteIndex(charStrings)
+	// Private DICTs, without subroutines and variation operators
+	privates := make([][]byte, len(cf.fds))
+	for j, fd := range cf.fds {
+		privates[j] = cffWriteDict(fd.private, cffOpSubrs, cffOpVsindex, cffOpBlend, cffOpVstore)
+	}
+	// Top DICT entries that are kept are numeric; string-valued entries would
+	// require the String INDEX of the original font.
+	keep := cffDictType{}
+	for _, e := range cf.topDict {
+		switch e.op {
+		case 5, 1201, 1202, 1203, 1204, 1205, 1207, 1208:
+			keep = append(keep, e)
+		}
+	}
+	topDict := func(charsetOff, fdSelectOff, charStringsOff, fdArrayOff int) []byte {
+		b := cffAppendOp(nil, cffOpROS, 391, 392, 0)
+		b = append(b, cffWriteDict(keep)...)
+		b = cffAppendOp(b, cffOpCIDCount, 65536)
+		b = cffAppendOp(b, cffOpCharset, charsetOff)
+		b = cffAppendOp(b, cffOpFDSelect, fdSelectOff)
+		b = cffAppendOp(b, cffOpCharStrings, charStringsOff)
+		return cffAppendOp(b, cffOpFDArray, fdArrayOff)
+	}
+	fdArray := func(privateOff int) []byte {
+		dicts := make([][]byte, len(cf.fds))
+		for j, fd := range cf.fds {
+			b := cffWriteDict(fd.dict, cffOpPrivate, 1238)
+			dicts[j] = cffAppendOp(b, cffOpPrivate, len(privates[j]), privateOff)
+			privateOff += len(privates[j])
+		}
+		return cffWriteIndex(dicts)
+	}
+	header := []byte{1, 0, 4, 4}
+	nameIndex := cffWriteIndex([][]byte{[]byte(nameStr)})
+	stringIndex := cffWriteIndex([][]byte{[]byte("Adobe"), []byte("Identity")})
+	globalSubrIndex := cffWriteIndex(nil)
+	// All offsets have fixed-size encodings, so the layout can be computed
+	// with placeholder values.
+	pos := len(header) + len(nameIndex) + len(cffWriteIndex([][]byte{topDict(0, 0, 0, 0)})) +
+		len(stringIndex) + len(globalSubrIndex)
+	charsetOff := pos
+	fdSelectOff := charsetOff + len(charset)
+	charStringsOff := fdSelectOff + len(fdSelect)
+	fdArrayOff := charStringsOff + len(charStringIndex)
+	privateOff := fdArrayOff + len(fdArray(0))
+	var b []byte
+	b = append(b, header...)
+	b = append(b, nameIndex...)
+	b = append(b, cffWriteIndex([][]byte{topDict(charsetOff, fdSelectOff, charStringsOff, fdArrayOff)})...)
+	b = append(b, stringIndex...)
+	b = append(b, globalSubrIndex...)
+	b = append(b, charset...)
+	b = append(b, fdSelect...)
+	b = append(b, charStringIndex...)
+	b = append(b, fdArray(privateOff)...)
+	for _, p := range privates {
+		b = append(b, p...)
+	}
+	return b, nil
+}
+
+// generateCutFontCFF returns an OpenType font with PostScript outlines that
+// contains the glyphs of usedRunes. The glyph of each rune is assigned the
+// CID that equals the rune.
+func (utf *utf8FontFile) generateCutFontCFF(usedRunes map[int]int) ([]byte, error) {
+	tableName := "CFF "
+	if _, ok := utf.tableDescriptions[tableName]; !ok {
+		tableName = "CFF2"
+	}
+	if _, ok := utf.tableDescriptions[tableName]; !ok {
+		return nil, fmt.Errorf("font has no CFF table")
+	}
+	var coords []float64
+	if utf.variation != nil {
+		coords = utf.variation.coords
+	}
+	cf, err := cffParse(utf.getTableData(tableName), coords)
+	if err != nil {
+		return nil, err
+	}
+	utf.outTablesData = make(map[string][]byte)
+	utf.LastRune = 0
+	utf.SeekTable("hhea")
+	utf.skip(34)
+	metricsCount := utf.readUint16()
+	if utf.generateCMAP() == nil {
+		return nil, fmt.Errorf("font has no Unicode cmap")
+	}
+
+	var runes []int
+	for _, r := range usedRunes {
+		if _, ok := utf.charSymbolDictionary[r]; ok && r > 0 && r <= 0xFFFF {
+			runes = append(runes, r)
+		}
+		utf.LastRune = max(utf.LastRune, r)
+	}
+	sort.Ints(runes)
+	// Remove duplicates
+	cids := runes[:0]
+	for j, r := range runes {
+		if j == 0 || r != runes[j-1] {
+			cids = append(cids, r)
+		}
+	}
+	gids := make([]int, len(cids))
+	metrics := func(gid int) []byte {
+		if utf.variation != nil {
+			return utf.variation.cffMetrics(gid)
+		}
+		return utf.getMetrics(metricsCount, gid)
+	}
+	hmtxData := metrics(0)
+	utf.CodeSymbolDictionary = make(map[int]int)
+	for j, r := range cids {
+		gids[j] = utf.charSymbolDictionary[r]
+		hmtxData = append(hmtxData, metrics(gids[j])...)
+		utf.CodeSymbolDictionary[r] = j + 1
+	}
+	nameStr := cf.nameStr
+	if nameStr == "" {
+		nameStr = "Font"
+	}
+	cffData, err := cf.subset(nameStr, gids, cids)
+	if err != nil {
+		return nil, err
+	}
+	numSymbols := len(gids) + 1
+
+	utf.setOutTable("CFF ", cffData)
+	utf.setOutTable("cmap", utf.generateCMAPTable(utf.CodeSymbolDictionary, numSymbols))
+	utf.setOutTable("hmtx", hmtxData)
+	utf.setOutTable("name", utf.getTableData("name"))
+	utf.setOutTable("OS/2", utf.getTableData("OS/2"))
+	utf.setOutTable("head", utf.getTableData("head"))
+	hheaData := utf.getTableData("hhea")
+	hheaData = utf.insertUint16(hheaData, 34, numSymbols)
+	utf.setOutTable("hhea", hheaData)
+	utf.setOutTable("maxp", append(packUint32(0x00005000), packUint16(numSymbols)...))
+	postTable := utf.getTableData("post")
+	postTable = append(append([]byte{0x00, 0x03, 0x00, 0x00}, postTable[4:16]...), make([]byte, 16)...)
+	utf.setOutTable("post", postTable)
+	return utf.assembleTables(), nil
+}
@@ -0,0 +1,276 @@
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cjkFontType describes a Chinese, Japanese or Korean font that PDF viewers
+// supply themselves, so that it need not be embedded in the document.
+type cjkFontType struct {
+	name       string // PostScript name of the font
+	ordering   string // character collection of the Adobe registry
+	supplement int    // supplement of the character collection
+	cmap       string // predefined CMap from UCS-2 to CIDs, horizontal writing mode
+	desc       FontDescType
+	// Widths of the proportional Latin glyphs for U+0020 to U+007E, which
+	// are CIDs 1 to 95 in all four character collections
+	widths [95]int
+	// Half-width characters other than Latin, in thousandths of an em: the
+	// first and last rune, and the first CID
+	halfWidth [][3]int
+	// Characters of the character collection in addition to those of
+	// cjkCommonRanges, as first and last rune
+	ranges [][2]int
+}
+
+// cjkCommonRanges lists the characters that all four character collections
+// contain, as first and last rune. Blocks that a collection covers only in
+// part, such as the CJK Unified Ideographs, are listed in full.
+var cjkCommonRanges = [][2]int{
+	{0x0020, 0x007E}, // Basic Latin
+	{0x00A7, 0x00A8}, {0x00B0, 0x00B1}, {0x00D7, 0x00D7}, {0x00F7, 0x00F7},
+	{0x0391, 0x03A1}, {0x03A3, 0x03A9}, {0x03B1, 0x03C1}, {0x03C3, 0x03C9}, // Greek
+	{0x0401, 0x0401}, {0x0410, 0x044F}, {0x0451, 0x0451}, // Cyrillic
+	{0x2010, 0x2026}, {0x2030, 0x203B}, // General Punctuation
+	{0x2103, 0x2103}, {0x2116, 0x2116}, {0x2160, 0x2169}, // Letterlike symbols, Roman numerals
+	{0x2190, 0x2199}, {0x2200, 0x22FF}, // Arrows, Mathematical Operators
+	{0x2460, 0x2473}, {0x2500, 0x257F}, {0x25A0, 0x25FF}, // Circled numbers, Box Drawing, shapes
+	{0x2605, 0x2606}, {0x2640, 0x2642},
+	{0x3000, 0x303F},                   // CJK Symbols and Punctuation
+	{0x4E00, 0x9FA5},                   // CJK Unified Ideographs
+	{0xFF01, 0xFF5E}, {0xFFE0, 0xFFE5}, // Full-width forms
+}
+
+// cjkFonts holds the CJK fonts that can be used without embedding, keyed by
+// lowercase name.
+var cjkFonts = map[string]cjkFontType{
+	"stsong-light": {
+		name: "STSong-Light", ordering: "GB1", supplement: 2, cmap: "UniGB-UCS2-H",
+		desc: FontDescType{Ascent: 880, Descent: -120, CapHeight: 880, Flags: 6,
+			FontBBox: fontBoxType{-25, -254, 1000, 880}, StemV: 93, MissingWidth: 1000},
+		widths: [95]int{
+			207, 270, 342, 467, 462, 797, 710, 239, 374, 374, 423, 605, 238, 375, 238, 334,
+			462, 462, 462, 462, 462, 462, 462, 462, 462, 462, 238, 238, 605, 605, 605, 344,
+			748, 684, 560, 695, 739, 563, 511, 729, 793, 318, 312, 666, 526, 896, 758, 772,
+			544, 772, 628, 465, 607, 753, 711, 972, 647, 620, 607, 374, 333, 374, 606, 500,
+			239, 417, 503, 427, 529, 415, 264, 444, 518, 241, 230, 495, 228, 793, 527, 524,
+			524, 504, 338, 336, 277, 517, 450, 652, 466, 452, 407, 370, 258, 370, 605},
+		ranges: [][2]int{
+			// Pinyin
+			{0x00E0, 0x00E1}, {0x00E8, 0x00EA}, {0x00EC, 0x00ED}, {0x00F2, 0x00F3},
+			{0x00F9, 0x00FA}, {0x00FC, 0x00FC}, {0x0101, 0x0101}, {0x0113, 0x0113},
+			{0x011B, 0x011B}, {0x012B, 0x012B}, {0x0144, 0x0144}, {0x0148, 0x0148},
+			{0x014D, 0x014D}, {0x016B, 0x016B}, {0x01CE, 0x01CE}, {0x01D0, 0x01D0},
+			{0x01D2, 0x01D2}, {0x01D4, 0x01D4}, {0x01D6, 0x01D6}, {0x01D8, 0x01D8},
+			{0x01DA, 0x01DA}, {0x01DC, 0x01DC},
+			{0x2170, 0x2179}, {0x2474, 0x249B}, // Small Roman numerals, enclosed numbers
+			{0x3041, 0x3094}, {0x30A1, 0x30F6}, {0x30FB, 0x30FE}, // Kana
+			{0x3105, 0x3129}, {0x3220, 0x3229}, // Bopomofo, parenthesized ideographs
+			{0xFE30, 0xFE4F}, // CJK Compatibility Forms
+		},
+	},
+	"msung-light": {
+		name: "MSung-Light", ordering: "CNS1", supplement: 0, cmap: "UniCNS-UCS2-H",
+		desc: FontDescType{Ascent: 880, Descent: -120, CapHeight: 880, Flags: 6,
+			FontBBox: fontBoxType{-160, -259, 1015, 888}, StemV: 93, MissingWidth: 1000},
+		widths: [95]int{
+			250, 250, 408, 668, 490, 875, 698, 250, 240, 240, 417, 667, 250, 313, 250, 520,
+			500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 250, 250, 667, 667, 667, 396,
+			921, 677, 615, 719, 760, 625, 552, 771, 802, 354, 354, 781, 604, 927, 750, 823,
+			563, 823, 729, 542, 698, 771, 729, 948, 771, 677, 635, 344, 520, 344, 469, 500,
+			250, 469, 521, 427, 521, 438, 271, 469, 531, 250, 250, 458, 240, 802, 531, 500,
+			521, 521, 365, 333, 292, 521, 458, 677, 479, 458, 427, 480, 496, 480, 667},
+		ranges: [][2]int{
+			{0x3105, 0x3129}, {0x3220, 0x3229}, // Bopomofo, parenthesized ideographs
+			{0xFE30, 0xFE6B}, // CJK Compatibility Forms, Small Form Variants
+		},
+	},
+	"kozminpro-regular": {
+		name: "KozMinPro-Regular", ordering: "Japan1", supplement: 2, cmap: "UniJIS-UCS2-H",
+		desc: FontDescType{Ascent: 880, Descent: -120, CapHeight: 742, Flags: 6,
+			FontBBox: fontBoxType{-195, -272, 1110, 1075}, StemV: 86, MissingWidth: 1000},
+		widths: [95]int{
+			278, 299, 353, 614, 614, 721, 735, 216, 323, 323, 449, 529, 219, 306, 219, 453,
+			614, 614, 614, 614, 614, 614, 614, 614, 614, 614, 219, 219, 529, 529, 529, 486,
+			744, 646, 604, 617, 681, 567, 537, 647, 738, 320, 433, 637, 566, 904, 710, 716,
+			605, 716, 623, 517, 601, 690, 668, 990, 681, 634, 578, 316, 614, 316, 529, 500,
+			387, 509, 566, 478, 565, 503, 337, 549, 580, 275, 266, 544, 276, 854, 579, 550,
+			578, 566, 410, 444, 340, 575, 512, 760, 503, 529, 453, 326, 380, 326, 387},
+		// Half-width katakana
+		halfWidth: [][3]int{{0xFF61, 0xFF9F, 327}},
+		ranges: [][2]int{
+			{0x00B4, 0x00B4}, {0x00B6, 0x00B6},
+			{0x2170, 0x2179}, {0x2474, 0x2493}, // Small Roman numerals, enclosed numbers
+			{0x3041, 0x3094}, {0x30A1, 0x30FE}, // Kana
+			{0x3200, 0x32FF}, {0x3300, 0x33FF}, // Enclosed CJK letters, CJK Compatibility
+			{0xFF61, 0xFF9F}, // Half-width katakana
+		},
+	},
+	"hysmyeongjo-medium": {
+		name: "HYSMyeongJo-Medium", ordering: "Korea1", supplement: 1, cmap: "UniKS-UCS2-H",
+		desc: FontDescType{Ascent: 880, Descent: -120, CapHeight: 880, Flags: 6,
+			FontBBox: fontBoxType{0, -148, 1001, 880}, StemV: 91, MissingWidth: 1000},
+		widths: [95]int{
+			333, 416, 416, 833, 625, 916, 833, 250, 500, 500, 500, 833, 291, 833, 291, 375,
+			625, 625, 625, 625, 625, 625, 625, 625, 625, 625, 333, 333, 833, 833, 916, 500,
+			1000, 791, 708, 708, 750, 708, 666, 750, 791, 375, 500, 791, 666, 916, 791, 750,
+			666, 750, 708, 666, 791, 791, 750, 1000, 708, 708, 666, 500, 375, 500, 500, 500,
+			333, 541, 583, 541, 583, 583, 375, 583, 583, 291, 333, 583, 291, 875, 583, 583,
+			583, 583, 458, 541, 375, 583, 583, 833, 625, 625, 500, 583, 583, 583, 750},
+		ranges: [][2]int{
+			// Latin-1 and Latin Extended letters of KS X 1001
+			{0x00A1, 0x00BF}, {0x00C6, 0x00C6}, {0x00D0, 0x00D0}, {0x00D8, 0x00D8},
+			{0x00DE, 0x00DF}, {0x00E6, 0x00E6}, {0x00F0, 0x00F0}, {0x00F8, 0x00F8},
+			{0x00FE, 0x00FE}, {0x0110, 0x0111}, {0x0126, 0x0127}, {0x0131, 0x0133},
+			{0x0138, 0x0138}, {0x013F, 0x0142}, {0x0149, 0x014B}, {0x0152, 0x0153},
+			{0x0166, 0x0167},
+			{0x2170, 0x2179}, {0x2474, 0x24FF}, // Small Roman numerals, enclosed letters
+			{0x3041, 0x3093}, {0x30A1, 0x30F6}, // Kana
+			{0x3131, 0x318E}, {0x3200, 0x327F}, // Hangul compatibility jamo, enclosed
+			{0x3380, 0x33DD}, // CJK Compatibility
+			{0xAC00, 0xD7A3}, // Hangul Syllables
+			{0xF900, 0xFA0B}, // CJK Compatibility Ideographs
+		},
+	},
+}
+
+// AddCJKFont imports a Chinese, Japanese or Korean font that PDF viewers
+// supply, so that it is not embedded in the document. This keeps documents
+// with CJK text small, but their appearance depends on the fonts that are
+// available to the viewer; Adobe Reader, for example, may ask to install a
+// font pack. The font is used with SetFont() like any other font and the text
+// is passed as UTF-8 strings.
+//
+// fontStr is one of the following font names (case insensitive):
+// "STSong-Light" for Simplified Chinese, "MSung-Light" for Traditional
+// Chinese, "KozMinPro-Regular" for Japanese and "HYSMyeongJo-Medium" for
+// Korean. Text is encoded with the predefined UCS-2 CMap of the character
+// collection of the font (UniGB-UCS2-H, UniCNS-UCS2-H, UniJIS-UCS2-H or
+// UniKS-UCS2-H), so characters outside the Basic Multilingual Plane cannot be
+// shown. Latin letters and digits are proportional; ideographs and other
+// characters are one em wide, except half-width katakana in the Japanese
+// font.
+//
+// The font is taken to contain the characters of its character collection:
+// Latin letters, common symbols, ideographs and, depending on the font, kana,
+// bopomofo or Hangul. HasGlyph() and MissingGlyphs() report other characters,
+// such as Thai or Arabic, as missing, and fonts set with SetFontFallback()
+// supply them. The coverage of the ideograph blocks is not exact.
+//
+// familyStr is the name of the font family under which the font is selected
+// with SetFont(). The font names listed above can be passed to SetFont()
+// without calling this method.
+//
+// styleStr is "" for regular, or "B", "I" or "BI" for the bold and italic
+// forms that the viewer derives from the font.
+//
+// Fonts imported with this method can be used for vertical text with
+// CellFormatVertical() and MultiCellVertical(), in which case the vertical
+// forms of punctuation are selected by the viewer.
+func (f *Fpdf) AddCJKFont(familyStr, styleStr, fontStr string) {
+	if f.err != nil {
+		return
+	}
+	cjk, ok := cjkFonts[strings.ToLower(fontStr)]
+	if !ok {
+		f.err = fmt.Errorf("unknown CJK font: %s", fontStr)
+		return
+	}
+	familyStr = strings.ToLower(fontFamilyEscape(familyStr))
+	styleStr = strings.ToUpper(styleStr)
+	if styleStr == "IB" {
+		styleStr = "BI"
+	}
+	fontKey := getFontKey(familyStr, styleStr)
+	if _, ok = f.fonts[fontKey]; ok {
+		return
+	}
+	name := cjk.name
+	switch styleStr {
+	case "":
+	case "B":
+		name += ",Bold"
+	case "I":
+		name += ",Italic"
+	case "BI":
+		name += ",BoldItalic"
+	default:
+		f.err = fmt.Errorf("invalid style for CJK font: %s", styleStr)
+		return
+	}
+	cw := make([]int, 256*256)
+	for _, ranges := range [][][2]int{cjkCommonRanges, cjk.ranges} {
+		for _, rng := range ranges {
+			for r := rng[0]; r <= rng[1]; r++ {
+				cw[r] = 1000
+			}
+		}
+	}
+	copy(cw[0x20:], cjk.widths[:])
+	for _, rng := range cjk.halfWidth {
+		for r := rng[0]; r <= rng[1]; r++ {
+			cw[r] = 500
+		}
+	}
+	def := fontDefType{
+		Tp:        "CJK",
+		Name:      name,
+		Desc:      cjk.desc,
+		Up:        -100,
+		Ut:        50,
+		Cw:        cw,
+		Enc:       cjk.cmap,
+		usedRunes: make(map[int]int),
+	}
+	if strings.Contains(styleStr, "I") {
+		def.Desc.ItalicAngle = -11
+	}
+	def.i, _ = generateFontID(def)
+	f.fonts[fontKey] = def
+}
+
+// putCJKFont writes the Type0 font, the CIDFont and the font descriptor of a
+// font imported with AddCJKFont(). If the font is used for vertical text, a
+// second Type0 font with the vertical CMap is written; it shares the CIDFont.
+func (f *Fpdf) putCJKFont(font fontDefType) {
+	cjk := cjkFonts[strings.ToLower(strings.Split(font.Name, ",")[0])]
+	f.newobj()
+	f.outf("<</Type /Font\n/Subtype /Type0\n/BaseFont /%s-%s\n/Encoding /%s\n/DescendantFonts [%d 0 R]>>",
+		font.Name, font.Enc, font.Enc, f.n+1)
+	f.out("endobj")
+
+	var s fmtBuffer
+	s.printf("[1 [")
+	for _, w := range cjk.widths {
+		s.printf("%d ", w)
+	}
+	s.printf("]")
+	for _, rng := range cjk.halfWidth {
+		s.printf(" %d %d 500", rng[2], rng[2]+rng[1]-rng[0])
+	}
+	s.printf("]")
+	f.newobj()
+	f.outf("<</Type /Font\n/Subtype /CIDFontType0\n/BaseFont /%s\n"+
+		"/CIDSystemInfo <</Registry (Adobe) /Ordering (%s) /Supplement %d>>\n"+
+		"/FontDescriptor %d 0 R\n/DW %d\n/W %s>>", font.Name, cjk.ordering, cjk.supplement,
+		f.n+1, font.Desc.MissingWidth, s.String())
+	f.out("endobj")
+
+	d := font.Desc
+	f.newobj()
+	f.outf("<</Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle %d /Ascent %d /Descent %d /CapHeight %d /StemV %d>>", font.Name, d.Flags,
+		d.FontBBox.Xmin, d.FontBBox.Ymin, d.FontBBox.Xmax, d.FontBBox.Ymax, d.ItalicAngle,
+		d.Ascent, d.Descent, d.CapHeight, d.StemV)
+	f.out("endobj")
+
+	if _, vertical := f.verticalFonts[font.i]; vertical {
+		cmap := strings.TrimSuffix(font.Enc, "-H") + "-V"
+		f.newobj()
+		f.verticalFonts[font.i] = f.n
+		f.outf("<</Type /Font\n/Subtype /Type0\n/BaseFont /%s-%s\n/Encoding /%s\n/DescendantFonts [%d 0 R]>>",
+			font.Name, cmap, cmap, font.N+1)
+		f.out("endobj")
+	}
+}
@@ -0,0 +1,497 @@
+package gofpdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// colorGlyphType holds the color representation of a glyph of a UTF-8 font,
+// either as layers of outline glyphs (COLR table) or as a bitmap image (CBDT
+// or sbix table).
+type colorGlyphType struct {
+	gid     int
+	advance int              // advance width in thousandths of an em, as in Cw
+	layers  []colorLayerType // layers of a COLR glyph, bottom first
+	image   []byte           // PNG data of a bitmap glyph
+	// Position of the lower left corner and size of the bitmap in font units
+	x, y, w, h float64
+}
+
+// colorLayerType is one layer of a COLR glyph: the outline of glyph gid filled
+// with a palette color.
+type colorLayerType struct {
+	gid        int
+	r, g, b, a int  // color components from 0 to 255
+	foreground bool // layer is filled with the text color
+}
+
+// fontColorGlyph returns the color glyph of the UTF-8 font for r, or nil if
+// the font has none.
+func fontColorGlyph(font *fontDefType, r rune) *colorGlyphType {
+	if font.Tp != "UTF8" || font.utf8File == nil {
+		return nil
+	}
+	return font.utf8File.colorGlyph(r)
+}
+
+// fontRuneWidth returns the width of r in font, in thousandths of an em.
+func fontRuneWidth(font *fontDefType, r rune) int {
+	if glyph := fontColorGlyph(font, r); glyph != nil {
+		return glyph.advance
+	}
+	if r < 0 || int(r) >= len(font.Cw) || font.Cw[r] == 65535 {
+		// Marker for zero width symbols
+		return 0
+	}
+	return font.Cw[r]
+}
+
+// colorGlyph returns the color glyph for r, or nil if the font has none. The
+// color tables of the font are read when this method is first called.
+func (utf *utf8FontFile) colorGlyph(r rune) *colorGlyphType {
+	if utf.colorGlyphs == nil {
+		utf.colorGlyphs = utf.parseColorGlyphs()
+	}
+	return utf.colorGlyphs[r]
+}
+
+// hasColorGlyphs returns true if the font has color glyphs for any rune.
+func (utf *utf8FontFile) hasColorGlyphs() bool {
+	if utf.colorGlyphs == nil {
+		utf.colorGlyphs = utf.parseColorGlyphs()
+	}
+	return len(utf.colorGlyphs) > 0
+}
+
+// parseColorGlyphs reads the color glyphs of the COLR, CBDT and sbix tables
+// and returns them by the rune they are mapped to. If a glyph is present in
+// several tables, the vector representation of the COLR table is preferred,
+// then the CBDT bitmap. Damaged tables are ignored, so that their glyphs are
+// rendered with their outlines.
+func (utf *utf8FontFile) parseColorGlyphs() map[rune]*colorGlyphType {
+	runes := make(map[rune]*colorGlyphType)
+	glyphs := make(map[int]*colorGlyphType)
+	for _, parse := range []func(map[int]*colorGlyphType) error{utf.parseCOLRTable, utf.parseCBDTTable, utf.parseSBIXTable} {
+		found := make(map[int]*colorGlyphType)
+		if parse(found) == nil {
+			for gid, glyph := range found {
+				if _, ok := glyphs[gid]; !ok {
+					glyphs[gid] = glyph
+				}
+			}
+		}
+	}
+	if len(glyphs) == 0 {
+		return runes
+	}
+	hmtx := utf.getTableData("hmtx")
+	utf.SeekTable("hhea")
+	utf.skip(34)
+	metricsCount := utf.readUint16()
+	scale := 1000.0 / float64(utf.fontElementSize)
+	for r, gid := range utf.cmapRunes() {
+		glyph, ok := glyphs[gid]
+		if !ok {
+			continue
+		}
+		advance := 0
+		if utf.variation != nil {
+			advance, _ = utf.variation.advance(gid)
+		} else if pos := 4 * intIf(gid < metricsCount, gid, metricsCount-1); metricsCount > 0 && pos+2 <= len(hmtx) {
+			advance = int(binary.BigEndian.Uint16(hmtx[pos:]))
+		}
+		glyph.gid = gid
+		glyph.advance = int(math.Round(scale * float64(advance)))
+		runes[r] = glyph
+	}
+	return runes
+}
+
+// cmapRunes returns the glyph IDs of all runes mapped by the font, including
+// those outside the Basic Multilingual Plane, which are mapped by a format 12
+// cmap subtable.
+func (utf *utf8FontFile) cmapRunes() map[rune]int {
+	runes := make(map[rune]int)
+	if len(utf.charSymbolDictionary) == 0 {
+		utf.generateCMAP()
+	}
+	for char, gid := range utf.charSymbolDictionary {
+		if gid != 0 {
+			runes[rune(char)] = gid
+		}
+	}
+	data := utf.getTableData("cmap")
+	if len(data) < 4 {
+		return runes
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	for j := 0; j < count && 4+8*j+8 <= len(data); j++ {
+		platform := binary.BigEndian.Uint16(data[4+8*j:])
+		encoding := binary.BigEndian.Uint16(data[4+8*j+2:])
+		pos := int(binary.BigEndian.Uint32(data[4+8*j+4:]))
+		if !(platform == 3 && encoding == 10) && platform != 0 || pos+16 > len(data) ||
+			binary.BigEndian.Uint16(data[pos:]) != 12 {
+			continue
+		}
+		groups := int(binary.BigEndian.Uint32(data[pos+12:]))
+		for k := 0; k < groups && pos+16+12*k+12 <= len(data); k++ {
+			group := data[pos+16+12*k:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			gid := int(binary.BigEndian.Uint32(group[8:]))
+			for char := start; char <= end && end-start < 0x110000; char++ {
+				runes[rune(char)] = gid + int(char-start)
+			}
+		}
+		break
+	}
+	return runes
+}
+
+// parseCOLRTable reads the layered glyphs of version 0 of the COLR table, with
+// the colors of the first palette of the CPAL table.
+func (utf *utf8FontFile) parseCOLRTable(glyphs map[int]*colorGlyphType) error {
+	colr := utf.getTableData("COLR")
+	cpal := utf.getTableData("CPAL")
+	if colr == nil || cpal == nil {
+		return nil
+	}
+	if len(colr) < 14 || len(cpal) < 14 {
+		return fmt.Errorf("invalid COLR or CPAL table")
+	}
+	entries := int(binary.BigEndian.Uint16(cpal[2:]))
+	records := int(binary.BigEndian.Uint32(cpal[8:]))
+	first := int(binary.BigEndian.Uint16(cpal[12:]))
+	if records+4*(first+entries) > len(cpal) {
+		return fmt.Errorf("invalid CPAL table")
+	}
+	baseCount := int(binary.BigEndian.Uint16(colr[2:]))
+	basePos := int(binary.BigEndian.Uint32(colr[4:]))
+	layerPos := int(binary.BigEndian.Uint32(colr[8:]))
+	layerCount := int(binary.BigEndian.Uint16(colr[12:]))
+	if basePos+6*baseCount > len(colr) || layerPos+4*layerCount > len(colr) {
+		return fmt.Errorf("invalid COLR table")
+	}
+	for j := 0; j < baseCount; j++ {
+		rec := colr[basePos+6*j:]
+		gid := int(binary.BigEndian.Uint16(rec))
+		index := int(binary.BigEndian.Uint16(rec[2:]))
+		count := int(binary.BigEndian.Uint16(rec[4:]))
+		if index+count > layerCount {
+			return fmt.Errorf("invalid COLR table")
+		}
+		glyph := &colorGlyphType{}
+		for k := index; k < index+count; k++ {
+			layer := colorLayerType{gid: int(binary.BigEndian.Uint16(colr[layerPos+4*k:]))}
+			palette := int(binary.BigEndian.Uint16(colr[layerPos+4*k+2:]))
+			if palette == 0xFFFF || palette >= entries {
+				layer.foreground = true
+			} else {
+				c := cpal[records+4*(first+palette):]
+				layer.b, layer.g, layer.r, layer.a = int(c[0]), int(c[1]), int(c[2]), int(c[3])
+			}
+			glyph.layers = append(glyph.layers, layer)
+		}
+		glyphs[gid] = glyph
+	}
+	return nil
+}
+
+// parseCBDTTable reads the PNG glyphs of the largest strike of the CBLC and
+// CBDT tables.
+func (utf *utf8FontFile) parseCBDTTable(glyphs map[int]*colorGlyphType) error {
+	cblc := utf.getTableData("CBLC")
+	cbdt := utf.getTableData("CBDT")
+	if cblc == nil || cbdt == nil {
+		return nil
+	}
+	if len(cblc) < 8 {
+		return fmt.Errorf("invalid CBLC table")
+	}
+	numSizes := int(binary.BigEndian.Uint32(cblc[4:]))
+	if 8+48*numSizes > len(cblc) {
+		return fmt.Errorf("invalid CBLC table")
+	}
+	strike := -1
+	for j := 0; j < numSizes; j++ {
+		if strike < 0 || cblc[8+48*j+45] > cblc[8+48*strike+45] {
+			strike = j
+		}
+	}
+	if strike < 0 {
+		return nil
+	}
+	size := cblc[8+48*strike:]
+	arrayPos := int(binary.BigEndian.Uint32(size))
+	subtables := int(binary.BigEndian.Uint32(size[8:]))
+	ppem := float64(size[45])
+	if arrayPos+8*subtables > len(cblc) || ppem == 0 {
+		return fmt.Errorf("invalid CBLC table")
+	}
+	u := float64(utf.fontElementSize) / ppem
+	for j := 0; j < subtables; j++ {
+		entry := cblc[arrayPos+8*j:]
+		firstGid := int(binary.BigEndian.Uint16(entry))
+		lastGid := int(binary.BigEndian.Uint16(entry[2:]))
+		pos := arrayPos + int(binary.BigEndian.Uint32(entry[4:]))
+		if pos+8 > len(cblc) || lastGid < firstGid {
+			return fmt.Errorf("invalid CBLC table")
+		}
+		indexFormat := binary.BigEndian.Uint16(cblc[pos:])
+		imageFormat := binary.BigEndian.Uint16(cblc[pos+2:])
+		dataPos := int(binary.BigEndian.Uint32(cblc[pos+4:]))
+		body := cblc[pos+8:]
+		// Offsets of the glyph images in CBDT, relative to dataPos
+		locations := make(map[int][2]int)
+		var metrics []byte
+		count := lastGid - firstGid + 1
+		switch indexFormat {
+		case 1, 3:
+			n := intIf(indexFormat == 1, 4, 2)
+			if len(body) < n*(count+1) {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			offset := func(k int) int {
+				if n == 4 {
+					return int(binary.BigEndian.Uint32(body[4*k:]))
+				}
+				return int(binary.BigEndian.Uint16(body[2*k:]))
+			}
+			for k := 0; k < count; k++ {
+				locations[firstGid+k] = [2]int{offset(k), offset(k + 1)}
+			}
+		case 2:
+			if len(body) < 12 {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			imageSize := int(binary.BigEndian.Uint32(body))
+			metrics = body[4:12]
+			for k := 0; k < count; k++ {
+				locations[firstGid+k] = [2]int{k * imageSize, (k + 1) * imageSize}
+			}
+		case 4:
+			if len(body) < 4 {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			n := int(binary.BigEndian.Uint32(body))
+			if len(body) < 4+4*(n+1) {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			for k := 0; k < n; k++ {
+				pair := body[4+4*k:]
+				locations[int(binary.BigEndian.Uint16(pair))] = [2]int{
+					int(binary.BigEndian.Uint16(pair[2:])), int(binary.BigEndian.Uint16(pair[6:]))}
+			}
+		case 5:
+			if len(body) < 16 {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			imageSize := int(binary.BigEndian.Uint32(body))
+			metrics = body[4:12]
+			n := int(binary.BigEndian.Uint32(body[12:]))
+			if len(body) < 16+2*n {
+				return fmt.Errorf("invalid CBLC table")
+			}
+			for k := 0; k < n; k++ {
+				locations[int(binary.BigEndian.Uint16(body[16+2*k:]))] = [2]int{k * imageSize, (k + 1) * imageSize}
+			}
+		default:
+			continue
+		}
+		for gid, loc := range locations {
+			start, end := dataPos+loc[0], dataPos+loc[1]
+			if end <= start {
+				continue
+			}
+			if end > len(cbdt) {
+				return fmt.Errorf("invalid CBDT table")
+			}
+			glyph, err := cbdtGlyph(cbdt[start:end], imageFormat, metrics, u)
+			if err != nil {
+				return err
+			}
+			if glyph != nil {
+				glyphs[gid] = glyph
+			}
+		}
+	}
+	return nil
+}
+
+// cbdtGlyph returns the glyph of the CBDT image data in one of the PNG formats
+// 17, 18 and 19. metrics are the big glyph metrics of the index subtable,
+// which are used by format 19. u is the number of font units per pixel.
+func cbdtGlyph(data []byte, imageFormat uint16, metrics []byte, u float64) (*colorGlyphType, error) {
+	var pos int
+	switch imageFormat {
+	case 17:
+		metrics, pos = data, 5
+	case 18:
+		metrics, pos = data, 8
+	case 19:
+		pos = 0
+	default:
+		return nil, nil
+	}
+	if len(metrics) < 4 || pos+4 > len(data) {
+		return nil, fmt.Errorf("invalid CBDT table")
+	}
+	size := int(binary.BigEndian.Uint32(data[pos:]))
+	if pos+4+size > len(data) {
+		return nil, fmt.Errorf("invalid CBDT table")
+	}
+	height, width := float64(metrics[0]), float64(metrics[1])
+	bearingX, bearingY := float64(int8(metrics[2])), float64(int8(metrics[3]))
+	return &colorGlyphType{
+		image: data[pos+4 : pos+4+size],
+		x:     bearingX * u,
+		y:     (bearingY - height) * u,
+		w:     width * u,
+		h:     height * u,
+	}, nil
+}
+
+// parseSBIXTable reads the PNG glyphs of the largest strike of the sbix table.
+func (utf *utf8FontFile) parseSBIXTable(glyphs map[int]*colorGlyphType) error {
+	sbix := utf.getTableData("sbix")
+	if sbix == nil {
+		return nil
+	}
+	utf.SeekTable("maxp")
+	utf.skip(4)
+	numGlyphs := utf.readUint16()
+	if len(sbix) < 8 {
+		return fmt.Errorf("invalid sbix table")
+	}
+	numStrikes := int(binary.BigEndian.Uint32(sbix[4:]))
+	if 8+4*numStrikes > len(sbix) {
+		return fmt.Errorf("invalid sbix table")
+	}
+	strike, ppem := -1, 0
+	for j := 0; j < numStrikes; j++ {
+		pos := int(binary.BigEndian.Uint32(sbix[8+4*j:]))
+		if pos+4+4*(numGlyphs+1) > len(sbix) {
+			return fmt.Errorf("invalid sbix table")
+		}
+		if p := int(binary.BigEndian.Uint16(sbix[pos:])); strike < 0 || p > ppem {
+			strike, ppem = pos, p
+		}
+	}
+	if strike < 0 || ppem == 0 {
+		return nil
+	}
+	u := float64(utf.fontElementSize) / float64(ppem)
+	offset := func(gid int) int {
+		return strike + int(binary.BigEndian.Uint32(sbix[strike+4+4*gid:]))
+	}
+	var glyph func(gid, depth int) (*colorGlyphType, error)
+	glyph = func(gid, depth int) (*colorGlyphType, error) {
+		start, end := offset(gid), offset(gid+1)
+		if end-start < 8 {
+			return nil, nil
+		}
+		if end > len(sbix) {
+			return nil, fmt.Errorf("invalid sbix table")
+		}
+		data := sbix[start:end]
+		switch string(data[4:8]) {
+		case "png ":
+			width, height, err := pngSize(data[8:])
+			if err != nil {
+				return nil, err
+			}
+			return &colorGlyphType{
+				image: data[8:],
+				x:     float64(int16(binary.BigEndian.Uint16(data))) * u,
+				y:     float64(int16(binary.BigEndian.Uint16(data[2:]))) * u,
+				w:     float64(width) * u,
+				h:     float64(height) * u,
+			}, nil
+		case "dupe":
+			if len(data) < 10 || depth > 0 {
+				return nil, fmt.Errorf("invalid sbix table")
+			}
+			if dupe := int(binary.BigEndian.Uint16(data[8:])); dupe < numGlyphs {
+				return glyph(dupe, depth+1)
+			}
+		}
+		return nil, nil
+	}
+	for gid := 0; gid < numGlyphs; gid++ {
+		g, err := glyph(gid, 0)
+		if err != nil {
+			return err
+		}
+		if g != nil {
+			glyphs[gid] = g
+		}
+	}
+	return nil
+}
+
+// pngSize returns the dimensions, in pixels, of the PNG image data.
+func pngSize(data []byte) (width, height int, err error) {
+	if len(data) < 24 || !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) || string(data[12:16]) != "IHDR" {
+		return 0, 0, fmt.Errorf("invalid PNG glyph image")
+	}
+	return int(binary.BigEndian.Uint32(data[16:])), int(binary.BigEndian.Uint32(data[20:])), nil
+}
+
+// colorGlyphOps writes to s the operators that paint the color glyph of font
+// with its origin at x, y, which are specified in points. Each glyph is
+// painted in its own graphics state.
+func (f *Fpdf) colorGlyphOps(s *fmtBuffer, font *fontDefType, glyph *colorGlyphType, x, y float64) {
+	utf := font.utf8File
+	scale := f.fontSizePt / float64(utf.fontElementSize)
+	skew := 0.0
+	if strings.Contains(f.fontSynthetic, "I") {
+		skew = syntheticItalicSkew
+	}
+	s.printf(" q %.5f 0 %.5f %.5f %.2f %.2f cm", scale, skew*scale, scale, x, y)
+	if glyph.image != nil {
+		info := f.RegisterImageOptionsReader(sprintf("colorglyph-%s-%d", font.i, glyph.gid),
+			ImageOptions{ImageType: "PNG"}, bytes.NewReader(glyph.image))
+		if f.err != nil {
+			return
+		}
+		s.printf(" %.2f 0 0 %.2f %.2f %.2f cm /I%s Do", glyph.w, glyph.h, glyph.x, glyph.y, info.i)
+	}
+	for _, layer := range glyph.layers {
+		contours, err := utf.glyphOutline(layer.gid)
+		if err != nil {
+			f.err = err
+			return
+		}
+		if len(contours) == 0 {
+			continue
+		}
+		// The nonstroking color is the text color, which foreground layers use
+		s.printf(" q")
+		if !layer.foreground {
+			s.printf(" %.3f %.3f %.3f rg", float64(layer.r)/255, float64(layer.g)/255, float64(layer.b)/255)
+			if layer.a < 255 {
+				s.printf(" /GS%d gs", f.blendState(float64(layer.a)/255, "Normal"))
+			}
+		}
+		for _, contour := range contours {
+			for _, seg := range contour {
+				a := seg.Arg
+				switch seg.Cmd {
+				case 'M':
+					s.printf(" %.2f %.2f m", a[0], a[1])
+				case 'L':
+					s.printf(" %.2f %.2f l", a[0], a[1])
+				case 'C':
+					s.printf(" %.2f %.2f %.2f %.2f %.2f %.2f c", a[0], a[1], a[2], a[3], a[4], a[5])
+				case 'Z':
+					s.printf(" h")
+				}
+			}
+		}
+		s.printf(" f Q")
+	}
+	s.printf(" Q")
+}
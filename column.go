@@ -0,0 +1,475 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// ColumnLayoutType specifies the arrangement of text columns. It is passed to
+// ColumnsBegin() and ColumnsBalanced().
+//
+// Count is the number of columns; it must be at least one. Gutter is the
+// horizontal space between adjacent columns in the unit of measure specified
+// in New(). The columns share the space between the left and right margins
+// that are in effect when the column layout begins.
+//
+// If Rule is true, a vertical line is drawn in the middle of each gutter that
+// separates columns containing content. The line is drawn with the current
+// draw color and line width.
+type ColumnLayoutType struct {
+	Count  int
+	Gutter float64
+	Rule   bool
+}
+
+// columnStateType manages the column layout of a document.
+type columnStateType struct {
+	active       bool
+	layout       ColumnLayoutType
+	col          int     // zero-based index of current column
+	lMargin      float64 // page left margin
+	rMargin      float64 // page right margin
+	top          float64 // top of columns on current page
+	bottom       float64 // lowest position reached by content of previous columns on current page
+	limitPage    int     // page on which the column height is limited when balancing
+	limitBottom  float64 // bottom of columns on limitPage
+	savedTrigger float64 // page break threshold replaced by limitBottom
+}
+
+// ColumnsBegin starts a column layout at the current vertical position. Until
+// ColumnsEnd() is called, text written with Cell(), MultiCell(), Write() and
+// related methods, as well as images placed in flowing mode, continue at the
+// top of the next column when the bottom of a column is reached. After the
+// last column of a page, a new page is added and the flow continues in its
+// first column. See ColumnLayoutType for the arrangement of columns.
+//
+// The column layout is implemented by adjusting the left and right margins;
+// the page margins are restored by ColumnsEnd(). Calling AddPage() while a
+// column layout is active starts the first column of the new page.
+//
+// See ColumnsBalanced() for a variation that balances the heights of the
+// columns on the last page. The ColumnsBegin example demonstrates this method.
+func (f *Fpdf) ColumnsBegin(layout ColumnLayoutType) {
+	if f.err != nil {
+		return
+	}
+	if f.columns.active {
+		f.err = fmt.Errorf("column layout is already active")
+		return
+	}
+	if layout.Count < 1 {
+		f.err = fmt.Errorf("column count must be at least one")
+		return
+	}
+	if f.page == 0 {
+		f.AddPage()
+		if f.err != nil {
+			return
+		}
+	}
+	f.columns = columnStateType{
+		active:  true,
+		layout:  layout,
+		lMargin: f.lMargin,
+		rMargin: f.rMargin,
+		top:     f.y,
+		bottom:  f.y,
+	}
+	if f.columnWidth() <= 0 {
+		f.columns.active = false
+		f.err = fmt.Errorf("column gutter leaves no space for text")
+		return
+	}
+	f.columnSet(0)
+	f.x = f.lMargin
+}
+
+// ColumnsEnd terminates the column layout started with ColumnsBegin(). The
+// page margins are restored and the current position is set to the left
+// margin below the longest column on the current page.
+func (f *Fpdf) ColumnsEnd() {
+	if f.err != nil {
+		return
+	}
+	if !f.columns.active {
+		f.err = fmt.Errorf("column layout is not active")
+		return
+	}
+	f.columnEndPage()
+	f.columns.active = false
+	f.SetXY(f.lMargin, f.columns.bottom)
+}
+
+// ColumnsBalanced renders the content produced by fnc in the column layout
+// specified by layout, in the same way as calling ColumnsBegin(), fnc and
+// ColumnsEnd() in sequence, and then balances the heights of the columns on
+// the last page so that they end at approximately the same vertical position.
+//
+// Balancing requires the content to be rendered several times. All but the
+// final rendition are made in a copy of the document that is discarded, so
+// the pages, fonts, images, links and other state that they change are not
+// retained. Consequently, fnc must be repeatable: each call needs to produce
+// the same content without depending on state outside of the document that
+// it modifies itself. Page header and footer functions may also be called
+// more than once for the pages involved.
+func (f *Fpdf) ColumnsBalanced(layout ColumnLayoutType, fnc func()) {
+	if f.err != nil {
+		return
+	}
+	if f.page == 0 {
+		f.AddPage()
+		if f.err != nil {
+			return
+		}
+	}
+	render := func(limitPage int, limitBottom float64) {
+		f.ColumnsBegin(layout)
+		f.columns.limitPage = limitPage
+		f.columns.limitBottom = limitBottom
+		f.columnApplyLimit()
+		fnc()
+	}
+	doc := *f
+	measure := func(limitPage int, limitBottom float64) {
+		*f = doc.columnScratch()
+		render(limitPage, limitBottom)
+	}
+	discard := func() {
+		err := f.err
+		*f = doc
+		f.err = err
+	}
+	// Unconstrained rendition to determine the last page and the space that
+	// its columns occupy
+	measure(0, 0)
+	if f.err != nil {
+		discard()
+		return
+	}
+	lastPage := f.page
+	top := f.columns.top
+	// A column height that certainly fits is the full height of the page
+	// area; use a bisection search for the smallest height that keeps the
+	// content on lastPage.
+	lo, hi := 0.0, f.pageBreakLimit()-top
+	if f.columns.col == 0 && layout.Count > 1 {
+		// Only the first column is used; the balanced height is at least a
+		// fraction of its height
+		lo = (f.y - top) / float64(layout.Count)
+		hi = f.y - top
+	}
+	for j := 0; j < 16 && hi-lo > 0.1; j++ {
+		mid := (lo + hi) / 2
+		measure(lastPage, top+mid)
+		if f.err != nil {
+			discard()
+			return
+		}
+		if f.page == lastPage {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	discard()
+	render(lastPage, top+hi)
+	f.ColumnsEnd()
+}
+
+// ColumnNo returns the one-based index of the current column, or zero if no
+// column layout is active.
+func (f *Fpdf) ColumnNo() int {
+	if f.columns.active {
+		return f.columns.col + 1
+	}
+	return 0
+}
+
+// NextColumn terminates the current column and continues the flow at the top
+// of the next one, adding a page if the current column is the last one on
+// the page. It has no effect if no column layout is active.
+func (f *Fpdf) NextColumn() {
+	if f.err != nil || !f.columns.active {
+		return
+	}
+	x := f.x - f.lMargin
+	if f.columns.col < f.columns.layout.Count-1 {
+		f.columnAdvance()
+	} else {
+		f.columnNewPage()
+	}
+	f.x = f.lMargin + x
+}
+
+// columnWidth returns the width of each column in the active layout.
+func (f *Fpdf) columnWidth() float64 {
+	c := &f.columns
+	n := float64(c.layout.Count)
+	return (f.w - c.lMargin - c.rMargin - (n-1)*c.layout.Gutter) / n
+}
+
+// columnSet makes the column with index col the current one by adjusting the
+// margins.
+func (f *Fpdf) columnSet(col int) {
+	c := &f.columns
+	wd := f.columnWidth()
+	c.col = col
+	f.lMargin = c.lMargin + float64(col)*(wd+c.layout.Gutter)
+	f.rMargin = f.w - f.lMargin - wd
+}
+
+// columnAdvance moves the flow to the top of the next column on the current
+// page.
+func (f *Fpdf) columnAdvance() {
+	c := &f.columns
+	c.bottom = math.Max(c.bottom, f.y)
+	f.columnSet(c.col + 1)
+	f.y = c.top
+}
+
+// columnNewPage adds a page and moves the flow to its first column. Word
+// spacing that is in effect is carried over to the new page.
+func (f *Fpdf) columnNewPage() {
+	ws := f.ws
+	if ws > 0 {
+		f.ws = 0
+		f.out("0 Tw")
+	}
+	f.AddPageFormat(f.curOrientation, f.curPageSize)
+	if ws > 0 && f.err == nil {
+		f.ws = ws
+		f.outf("%.3f Tw", ws*f.k)
+	}
+}
+
+// columnAcceptPageBreak is called when content reaches the page break
+// threshold. If a column layout is active, the flow continues in the next
+// column or on a new page and false is returned, since the break has been
+// handled. Otherwise, the application's page break function is consulted.
+func (f *Fpdf) columnAcceptPageBreak() bool {
+	c := &f.columns
+	if !c.active {
+		return f.acceptPageBreak()
+	}
+	if c.col < c.layout.Count-1 {
+		x := f.x - f.lMargin
+		f.columnAdvance()
+		f.x = f.lMargin + x
+		return false
+	}
+	// A balanced rendition that overflows the page being balanced always
+	// gets a new page so that the overflow can be detected
+	if c.limitPage != f.page && !f.acceptPageBreak() {
+		return false
+	}
+	x := f.x - f.lMargin
+	f.columnNewPage()
+	f.x = f.lMargin + x
+	return false
+}
+
+// columnEndPage restores the page margins and draws the column rules of the
+// current page. It is called before the footer of a page is rendered.
+func (f *Fpdf) columnEndPage() {
+	c := &f.columns
+	if !c.active {
+		return
+	}
+	c.bottom = math.Max(c.bottom, f.y)
+	if c.limitPage == f.page && c.savedTrigger != 0 {
+		f.pageBreakTrigger = c.savedTrigger
+		c.savedTrigger = 0
+	}
+	if c.layout.Rule && c.bottom > c.top {
+		wd := f.columnWidth()
+		for j := 1; j <= c.col; j++ {
+			x := c.lMargin + float64(j)*(wd+c.layout.Gutter) - c.layout.Gutter/2
+			f.Line(x, c.top, x, c.bottom)
+		}
+	}
+	f.lMargin = c.lMargin
+	f.rMargin = c.rMargin
+}
+
+// columnBeginPage starts the first column of a newly added page. It is called
+// after the page header has been rendered.
+func (f *Fpdf) columnBeginPage() {
+	c := &f.columns
+	if !c.active {
+		return
+	}
+	c.top = f.y
+	c.bottom = f.y
+	f.columnSet(0)
+	f.x = f.lMargin
+	f.columnApplyLimit()
+}
+
+// columnApplyLimit lowers the page break threshold to the balanced column
+// height if the current page is the one being balanced.
+func (f *Fpdf) columnApplyLimit() {
+	c := &f.columns
+	if c.limitPage == f.page && c.limitBottom < f.pageBreakTrigger {
+		c.savedTrigger = f.pageBreakTrigger
+		f.pageBreakTrigger = c.limitBottom
+	}
+}
+
+// columnScratch returns a document in which content can be rendered to
+// measure it and then discarded without affecting f. Like the document of a
+// template, it is created with fpdfNew() and takes over the settings of f
+// that govern the layout of content: the current page, position, margins and
+// font, page breaking, headers and footers, sections, footnotes and the
+// graphics state. The resources that content refers to are copied so that it
+// can extend them. Page content is not copied since it is not read back.
+//
+// Fields that are not copied keep the values assigned by fpdfNew(), so new
+// fields of Fpdf are never shared with f; a field that affects the layout of
+// content needs to be added here.
+func (f *Fpdf) columnScratch() (c Fpdf) {
+	c = *fpdfNew(f.defOrientation, f.unitStr, "", f.fontDirStr, f.defPageSize)
+	// Page, position and margins
+	c.page, c.n, c.state = f.page, f.n, f.state
+	c.compress = f.compress
+	c.curOrientation, c.curPageSize = f.curOrientation, f.curPageSize
+	c.wPt, c.hPt, c.w, c.h = f.wPt, f.hPt, f.w, f.h
+	c.lMargin, c.tMargin, c.rMargin, c.bMargin, c.cMargin = f.lMargin, f.tMargin, f.rMargin, f.bMargin, f.cMargin
+	c.x, c.y, c.lasth, c.ws = f.x, f.y, f.lasth, f.ws
+	c.isRTL = f.isRTL
+	c.pages = make([]*bytes.Buffer, len(f.pages))
+	for j := range f.pages {
+		c.pages[j] = new(bytes.Buffer)
+	}
+	for n, sz := range f.pageSizes {
+		c.pageSizes[n] = sz
+	}
+	for key, pb := range f.defPageBoxes {
+		c.defPageBoxes[key] = pb
+	}
+	for n, boxes := range f.pageBoxes {
+		c.pageBoxes[n] = make(map[string]PageBox, len(boxes))
+		for key, pb := range boxes {
+			c.pageBoxes[n][key] = pb
+		}
+	}
+	// Page breaks, headers and footers
+	c.autoPageBreak, c.acceptPageBreak, c.pageBreakTrigger = f.autoPageBreak, f.acceptPageBreak, f.pageBreakTrigger
+	c.inHeader, c.headerFnc, c.headerHomeMode = f.inHeader, f.headerFnc, f.headerHomeMode
+	c.inFooter, c.footerFnc, c.footerFncLpi = f.inFooter, f.footerFnc, f.footerFncLpi
+	c.aliasNbPagesStr = f.aliasNbPagesStr
+	for key, str := range f.aliasMap {
+		c.aliasMap[key] = str
+	}
+	c.sections = make([]*sectionRecType, len(f.sections))
+	for j, sec := range f.sections {
+		cp := *sec
+		c.sections[j] = &cp
+		if sec == f.sectionPending {
+			c.sectionPending = &cp
+		}
+	}
+	c.footnote = f.footnote
+	c.footnote.page = append([]footnoteRecType(nil), f.footnote.page...)
+	c.footnote.carry = append([]footnoteRecType(nil), f.footnote.carry...)
+	c.footnote.endList = append([]footnoteRecType(nil), f.footnote.endList...)
+	c.columns = f.columns
+	c.exclusions = append([]exclusionType(nil), f.exclusions...)
+	// Fonts
+	c.fontpath, c.fontLoader = f.fontpath, f.fontLoader
+	c.isCurrentUTF8, c.fontFamily, c.fontStyle, c.fontSynthetic = f.isCurrentUTF8, f.fontFamily, f.fontStyle, f.fontSynthetic
+	c.underline, c.strikeout = f.underline, f.strikeout
+	c.fontSizePt, c.fontSize = f.fontSizePt, f.fontSize
+	c.userUnderlineThickness = f.userUnderlineThickness
+	c.currentFont = f.currentFont
+	for key, font := range f.fonts {
+		used := make(map[int]int, len(font.usedRunes))
+		for r, n := range font.usedRunes {
+			used[r] = n
+		}
+		font.usedRunes = used
+		c.fonts[key] = font
+		if font.i == f.currentFont.i {
+			c.currentFont.usedRunes = used
+		}
+	}
+	for key, ff := range f.fontFiles {
+		c.fontFiles[key] = ff
+	}
+	c.diffs = append(c.diffs, f.diffs...)
+	c.fontFallbacks = make(map[string][]string, len(f.fontFallbacks))
+	for key, list := range f.fontFallbacks {
+		c.fontFallbacks[key] = list
+	}
+	c.syntheticStyles = make(map[string]bool, len(f.syntheticStyles))
+	for key, ok := range f.syntheticStyles {
+		c.syntheticStyles[key] = ok
+	}
+	for key, n := range f.verticalFonts {
+		c.verticalFonts[key] = n
+	}
+	// Graphics state
+	c.lineWidth, c.capStyle, c.joinStyle = f.lineWidth, f.capStyle, f.joinStyle
+	c.dashArray, c.dashPhase = append([]float64(nil), f.dashArray...), f.dashPhase
+	c.colorFlag, c.color = f.colorFlag, f.color
+	c.blendMode, c.alpha = f.blendMode, f.alpha
+	c.clipNest, c.transformNest = f.clipNest, f.transformNest
+	c.layer.currentLayer, c.layer.openLayerPane = f.layer.currentLayer, f.layer.openLayerPane
+	c.layer.list = append(c.layer.list, f.layer.list...)
+	c.pdfVersion = f.pdfVersion
+	c.imagePolicy = f.imagePolicy
+	// Resources that content refers to and extends
+	for key, t := range f.templates {
+		c.templates[key] = t
+	}
+	for key, n := range f.templateObjects {
+		c.templateObjects[key] = n
+	}
+	for key, b := range f.importedObjs {
+		c.importedObjs[key] = b
+	}
+	for key, pos := range f.importedObjPos {
+		c.importedObjPos[key] = make(map[int]string, len(pos))
+		for n, str := range pos {
+			c.importedObjPos[key][n] = str
+		}
+	}
+	for key, str := range f.importedTplObjs {
+		c.importedTplObjs[key] = str
+	}
+	for key, n := range f.importedTplIDs {
+		c.importedTplIDs[key] = n
+	}
+	// Images that are registered under several names stay shared
+	images := make(map[*ImageInfoType]*ImageInfoType)
+	for key, info := range f.images {
+		cp, ok := images[info]
+		if !ok {
+			img := *info
+			cp = &img
+			images[info] = cp
+		}
+		c.images[key] = cp
+	}
+	c.pageLinks = make([][]linkType, len(f.pageLinks))
+	for j, links := range f.pageLinks {
+		c.pageLinks[j] = append([]linkType(nil), links...)
+	}
+	c.links = append([]intLinkType(nil), f.links...)
+	c.attachments = append([]Attachment(nil), f.attachments...)
+	c.pageAttachments = make([][]annotationAttach, len(f.pageAttachments))
+	for j, list := range f.pageAttachments {
+		c.pageAttachments[j] = append([]annotationAttach(nil), list...)
+	}
+	c.outlines = append([]outlineType(nil), f.outlines...)
+	c.blendList = append([]blendModeType(nil), f.blendList...)
+	for key, n := range f.blendMap {
+		c.blendMap[key] = n
+	}
+	c.gradientList = append([]gradientType(nil), f.gradientList...)
+	c.patternList = append([]patternType(nil), f.patternList...)
+	for key, clr := range f.spotColorMap {
+		c.spotColorMap[key] = clr
+	}
+	return
+}
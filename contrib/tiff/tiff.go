@@ -16,13 +16,15 @@
 
 // Package tiff allows standard (LZW-compressed) TIFF images to be used in
 // documents generated with gofpdf.
+//
+// gofpdf now reads TIFF images itself when the image type is "tiff" or
+// "tif", embedding CCITT-compressed images without decoding them. This
+// package remains for compatibility.
 package tiff
 
 import (
-	"bytes"
 	"fmt"
 	"image"
-	"image/png"
 	"io"
 	"os"
 
@@ -39,16 +41,11 @@ import (
 func RegisterReader(fpdf *gofpdf.Fpdf, imgName string, options gofpdf.ImageOptions, r io.Reader) (info *gofpdf.ImageInfoType) {
 	var err error
 	var img image.Image
-	var buf bytes.Buffer
 	if fpdf.Ok() {
 		if options.ImageType == "tiff" || options.ImageType == "tif" {
 			img, err = tiff.Decode(r)
 			if err == nil {
-				err = png.Encode(&buf, img)
-				if err == nil {
-					options.ImageType = "png"
-					info = fpdf.RegisterImageOptionsReader(imgName, options, &buf)
-				}
+				info = fpdf.RegisterImageFromImage(imgName, img, options)
 			}
 		} else {
 			err = fmt.Errorf("expecting \"tiff\" or \"tif\" as image type, got \"%s\"", options.ImageType)
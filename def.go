@@ -122,6 +122,7 @@ const (
 	colorModeRGB colorMode = iota
 	colorModeSpot
 	colorModeCMYK
+	colorModePattern
 )
 
 type colorType struct {
@@ -166,7 +167,7 @@ func (p PointType) XY() (float64, float64) {
 // methods.
 type ImageInfoType struct {
 	data  []byte  // Raw image data
-	smask []byte  // Soft Mask, an 8bit per-pixel transparency mask
+	smask []byte  // Soft Mask, a per-pixel transparency mask with the bit depth of the image
 	n     int     // Image object number
 	w     float64 // Width
 	h     float64 // Height
@@ -175,10 +176,32 @@ type ImageInfoType struct {
 	bpc   int     // Bits Per Component
 	f     string  // Image filter
 	dp    string  // DecodeParms
-	trns  []int   // Transparency mask
+	trns  []int   // Transparency mask, a color key from a PNG image
 	scale float64 // Document scale factor
-	dpi   float64 // Dots-per-inch found from image file (png only)
-	i     string  // SHA-1 checksum of the above values.
+	dpi   float64 // Dots-per-inch found from image file (png, jpeg, tiff and jbig2 only)
+	dpiY  float64 // Vertical dots-per-inch if it differs from dpi (jpeg, tiff and jbig2 only)
+	// Images that are drawn one below the other in place of data (tiff only)
+	strips []*ImageInfoType
+	// Alpha channel of a JPEG 2000 image: 1 if straight, 2 if premultiplied
+	smaskInData int
+	globals     []byte         // Global segments of a JBIG2 image
+	decode      string         // Decode array, such as that of inverted CMYK data
+	orientation int            // EXIF orientation of a JPEG image, or zero
+	stencil     bool           // Stencil mask painted in the current fill color
+	colorKey    []int          // Color key mask, a range of values for each component
+	smaskImage  *ImageInfoType // Gray image used as soft mask in place of smask
+	dpiRead     bool           // Set when dpi and dpiY were read from the image file
+	i           string         // SHA-1 checksum of the above values.
+	placed      bool           // Set when the image is first placed; not part of the checksum
+}
+
+// smaskImages returns the soft mask image of info, if any, as a slice that can
+// be gob-encoded even when it is empty.
+func (info *ImageInfoType) smaskImages() []*ImageInfoType {
+	if info.smaskImage == nil {
+		return nil
+	}
+	return []*ImageInfoType{info.smaskImage}
 }
 
 func generateImageID(info *ImageInfoType) (string, error) {
@@ -189,7 +212,9 @@ func generateImageID(info *ImageInfoType) (string, error) {
 // GobEncode encodes the receiving image to a byte slice.
 func (info *ImageInfoType) GobEncode() (buf []byte, err error) {
 	fields := []interface{}{info.data, info.smask, info.n, info.w, info.h, info.cs,
-		info.pal, info.bpc, info.f, info.dp, info.trns, info.scale, info.dpi}
+		info.pal, info.bpc, info.f, info.dp, info.trns, info.scale, info.dpi, info.dpiY, info.strips,
+		info.smaskInData, info.globals, info.decode, info.orientation, info.stencil, info.colorKey,
+		info.smaskImages(), info.dpiRead}
 	w := new(bytes.Buffer)
 	encoder := gob.NewEncoder(w)
 	for j := 0; j < len(fields) && err == nil; j++ {
@@ -204,13 +229,20 @@ func (info *ImageInfoType) GobEncode() (buf []byte, err error) {
 // GobDecode decodes the specified byte buffer (generated by GobEncode) into
 // the receiving image.
 func (info *ImageInfoType) GobDecode(buf []byte) (err error) {
+	var smaskImages []*ImageInfoType
 	fields := []interface{}{&info.data, &info.smask, &info.n, &info.w, &info.h,
-		&info.cs, &info.pal, &info.bpc, &info.f, &info.dp, &info.trns, &info.scale, &info.dpi}
+		&info.cs, &info.pal, &info.bpc, &info.f, &info.dp, &info.trns, &info.scale, &info.dpi,
+		&info.dpiY, &info.strips, &info.smaskInData, &info.globals,
+		&info.decode, &info.orientation, &info.stencil, &info.colorKey, &smaskImages,
+		&info.dpiRead}
 	r := bytes.NewBuffer(buf)
 	decoder := gob.NewDecoder(r)
 	for j := 0; j < len(fields) && err == nil; j++ {
 		err = decoder.Decode(fields[j])
 	}
+	if len(smaskImages) > 0 {
+		info.smaskImage = smaskImages[0]
+	}
 
 	info.i, err = generateImageID(info)
 	return
@@ -243,22 +275,48 @@ func (info *ImageInfoType) Extent() (wd, ht float64) {
 	return info.Width(), info.Height()
 }
 
-// Width returns the width of the image in the units of the Fpdf object.
+// Width returns the width of the image in the units of the Fpdf object. The
+// width of a JPEG image that is turned by a quarter turn according to its
+// EXIF orientation is its height in pixels.
 func (info *ImageInfoType) Width() float64 {
-	return info.w / (info.scale * info.dpi / 72)
+	w, _, dpi, _ := info.orientedSize()
+	return w / (info.scale * dpi / 72)
 }
 
 // Height returns the height of the image in the units of the Fpdf object.
 func (info *ImageInfoType) Height() float64 {
-	return info.h / (info.scale * info.dpi / 72)
+	_, h, _, dpiY := info.orientedSize()
+	return h / (info.scale * dpiY / 72)
+}
+
+// orientedSize returns the size of the image in pixels and its horizontal and
+// vertical dots per inch as the image appears on the page, which is turned by
+// a quarter turn if its orientation is 5 to 8.
+func (info *ImageInfoType) orientedSize() (w, h, dpi, dpiY float64) {
+	w, h, dpi, dpiY = info.w, info.h, info.dpi, info.verticalDpi()
+	if info.orientation >= 5 {
+		w, h, dpi, dpiY = h, w, dpiY, dpi
+	}
+	return
+}
+
+// verticalDpi returns the dots per inch of the image in the vertical
+// direction.
+func (info *ImageInfoType) verticalDpi() float64 {
+	if info.dpiY > 0 {
+		return info.dpiY
+	}
+	return info.dpi
 }
 
-// SetDpi sets the dots per inch for an image. PNG images MAY have their dpi
-// set automatically, if the image specifies it. DPI information is not
-// currently available automatically for JPG and GIF images, so if it's
+// SetDpi sets the dots per inch for an image. PNG, JPEG, TIFF and JBIG2 images
+// MAY have their dpi set automatically, if the image specifies it. DPI
+// information is not available automatically for GIF images, so if it's
 // important to you, you can set it here. It defaults to 72 dpi.
 func (info *ImageInfoType) SetDpi(dpi float64) {
 	info.dpi = dpi
+	info.dpiY = 0
+	info.dpiRead = false
 }
 
 type fontFileType struct {
@@ -544,6 +602,10 @@ type Fpdf struct {
 	underline        bool                       // underlining flag
 	strikeout        bool                       // strike out flag
 	currentFont      fontDefType                // current font info
+	fontFallbacks    map[string][]string        // fallback font families keyed by family
+	syntheticStyles  map[string]bool            // pseudo-styles keyed by font key
+	verticalFonts    map[string]int             // Identity-V font objects of fonts used for vertical text, keyed by font index
+	fontSynthetic    string                     // synthetic style of current font: "", "B", "I" or "BI"
 	fontSizePt       float64                    // current font size in points
 	fontSize         float64                    // current font size in user unit
 	ws               float64                    // word spacing
@@ -576,6 +638,12 @@ type Fpdf struct {
 	creationDate     time.Time                  // override for document CreationDate value
 	modDate          time.Time                  // override for document ModDate value
 	aliasNbPagesStr  string                     // alias for total number of pages
+	sections         []*sectionRecType          // sections started with AddSection()
+	sectionPending   *sectionRecType            // section to begin with next page
+	footnote         footnoteStateType          // footnotes and endnotes
+	columns          columnStateType            // multi-column text flow
+	exclusions       []exclusionType            // regions that flowing text avoids
+	imagePolicy      ImagePolicy                // reduction of images as they are placed
 	pdfVersion       string                     // PDF version number
 	fontDirStr       string                     // location of font definition files
 	capStyle         int                        // line cap style: butt 0, round 1, square 2
@@ -587,6 +655,7 @@ type Fpdf struct {
 	blendMode        string                     // current blend mode
 	alpha            float64                    // current transpacency
 	gradientList     []gradientType             // slice[idx] of gradient records
+	patternList      []patternType              // slice[idx] of tiling patterns, 1-based
 	clipNest         int                        // Number of active clipping contexts
 	transformNest    int                        // Number of active transformation contexts
 	err              error                      // Set if error occurs during life cycle of instance
@@ -0,0 +1,173 @@
+package gofpdf
+
+import (
+	"math"
+	"sort"
+)
+
+// exclusionType describes a region of a page that flowing text avoids.
+type exclusionType struct {
+	page   int
+	tp     byte // 'R' rectangle, 'C' circle, 'P' polygon
+	x, y   float64
+	w, h   float64
+	r      float64
+	points []PointType
+	margin float64
+}
+
+// AddExclusionRect adds a rectangular exclusion region to the current page.
+// Text written with MultiCell(), Write() and HTMLBasicType.Write() flows
+// around exclusion regions: the width available to each line is reduced to
+// the widest part of the line that is not covered by a region. x and y
+// specify the upper left corner of the rectangle and w and h its size.
+// margin is the minimum distance kept between the region and text. All values
+// are in the unit of measure specified in New().
+//
+// A typical use is to reserve the area of an image that is placed at the
+// left or right side of a paragraph. Exclusion regions remain in effect for
+// the page on which they are added until ClearExclusions() is called.
+//
+// The AddExclusionRect example demonstrates this method.
+func (f *Fpdf) AddExclusionRect(x, y, w, h, margin float64) {
+	f.exclusions = append(f.exclusions, exclusionType{page: f.page, tp: 'R',
+		x: x, y: y, w: w, h: h, margin: margin})
+}
+
+// AddExclusionCircle adds a circular exclusion region centered on (x, y) with
+// radius r to the current page. See AddExclusionRect() for details.
+func (f *Fpdf) AddExclusionCircle(x, y, r, margin float64) {
+	f.exclusions = append(f.exclusions, exclusionType{page: f.page, tp: 'C',
+		x: x, y: y, r: r, margin: margin})
+}
+
+// AddExclusionPolygon adds a polygonal exclusion region with the specified
+// vertices to the current page. For each line, the horizontal extent of the
+// polygon within the line is excluded, so text does not flow into the
+// concavities of a non-convex polygon. See AddExclusionRect() for details.
+func (f *Fpdf) AddExclusionPolygon(points []PointType, margin float64) {
+	f.exclusions = append(f.exclusions, exclusionType{page: f.page, tp: 'P',
+		points: append([]PointType(nil), points...), margin: margin})
+}
+
+// ClearExclusions removes all exclusion regions from the document.
+func (f *Fpdf) ClearExclusions() {
+	f.exclusions = nil
+}
+
+// exclusionActive returns true if the current page has exclusion regions.
+func (f *Fpdf) exclusionActive() bool {
+	for _, ex := range f.exclusions {
+		if ex.page == f.page {
+			return true
+		}
+	}
+	return false
+}
+
+// interval returns the horizontal extent of the region within the band
+// between y0 and y1. ok is false if the region does not intersect the band.
+func (ex exclusionType) interval(y0, y1 float64) (x0, x1 float64, ok bool) {
+	m := ex.margin
+	switch ex.tp {
+	case 'R':
+		if y1 > ex.y-m && y0 < ex.y+ex.h+m {
+			return ex.x - m, ex.x + ex.w + m, true
+		}
+	case 'C':
+		r := ex.r + m
+		var dy float64
+		if ex.y < y0 {
+			dy = y0 - ex.y
+		} else if ex.y > y1 {
+			dy = ex.y - y1
+		}
+		if dy < r {
+			half := math.Sqrt(r*r - dy*dy)
+			return ex.x - half, ex.x + half, true
+		}
+	case 'P':
+		y0 -= m
+		y1 += m
+		x0, x1 = math.Inf(1), math.Inf(-1)
+		add := func(x float64) {
+			x0 = math.Min(x0, x)
+			x1 = math.Max(x1, x)
+		}
+		n := len(ex.points)
+		for j, pt := range ex.points {
+			if pt.Y >= y0 && pt.Y <= y1 {
+				add(pt.X)
+			}
+			nx := ex.points[(j+1)%n]
+			for _, yb := range []float64{y0, y1} {
+				if (pt.Y < yb && nx.Y > yb) || (pt.Y > yb && nx.Y < yb) {
+					add(pt.X + (yb-pt.Y)*(nx.X-pt.X)/(nx.Y-pt.Y))
+				}
+			}
+		}
+		if x0 <= x1 {
+			return x0 - m, x1 + m, true
+		}
+	}
+	return 0, 0, false
+}
+
+// exclusionSpan returns the horizontal position and width available for a
+// line of height h at the current vertical position, within the range from
+// left to right. If x is greater than left, the line continues a partially
+// written line at x and the free segment at or after x is used; otherwise the
+// widest free segment is used. If no segment is wide enough for text, the
+// current vertical position is advanced by h until one is found or the page
+// break threshold is reached.
+func (f *Fpdf) exclusionSpan(h, left, right, x float64) (spanX, spanW float64) {
+	minWd := 2*f.cMargin + 2*f.fontSize
+	for {
+		type segType struct{ x0, x1 float64 }
+		var blocked []segType
+		for _, ex := range f.exclusions {
+			if ex.page != f.page {
+				continue
+			}
+			if x0, x1, ok := ex.interval(f.y, f.y+h); ok {
+				blocked = append(blocked, segType{x0, x1})
+			}
+		}
+		if len(blocked) == 0 {
+			return math.Max(x, left), right - math.Max(x, left)
+		}
+		sort.Slice(blocked, func(a, b int) bool { return blocked[a].x0 < blocked[b].x0 })
+		// Free segments between the blocked intervals
+		var free []segType
+		pos := left
+		for _, b := range blocked {
+			if b.x0 > pos {
+				free = append(free, segType{pos, math.Min(b.x0, right)})
+			}
+			pos = math.Max(pos, b.x1)
+		}
+		if pos < right {
+			free = append(free, segType{pos, right})
+		}
+		best := segType{}
+		for _, s := range free {
+			if x > left {
+				if s.x1 > x && s.x1-math.Max(s.x0, x) >= minWd {
+					best = segType{math.Max(s.x0, x), s.x1}
+					break
+				}
+			} else if s.x1-s.x0 > best.x1-best.x0 {
+				best = s
+			}
+		}
+		if best.x1-best.x0 >= minWd {
+			return best.x0, best.x1 - best.x0
+		}
+		if f.y+h > f.pageBreakLimit() {
+			// The page break that follows leaves the exclusion regions behind
+			return math.Max(x, left), right - math.Max(x, left)
+		}
+		f.y += h
+		x = left
+	}
+}
@@ -0,0 +1,172 @@
+package gofpdf
+
+import (
+	"strings"
+)
+
+// fontRunType is a run of text that is rendered with a single font.
+type fontRunType struct {
+	font  fontDefType
+	str   string
+	color bool // characters are color glyphs of the font
+}
+
+// SetFontFallback specifies the font families that supply glyphs missing from
+// the UTF-8 font family familyStr. When text is rendered or measured with
+// familyStr, each character that the current font does not contain is taken
+// from the first family in fallbackStrs that contains it. The fallback font
+// of the same style as the current font is used if it has been added;
+// otherwise the regular style of the fallback family is used. Characters that
+// no font contains are rendered with the current font.
+//
+// Fallback fonts must be UTF-8 fonts added with AddUTF8Font() or
+// AddUTF8FontFromBytes(). Text drawn with CellFormat(), MultiCell(), Write()
+// and the methods based on them is split into runs of characters that share a
+// font, and GetStringWidth(), SplitText() and the line breaking of these
+// methods take the width of each character in its font into account.
+//
+// Passing an empty list removes the fallback families of familyStr.
+//
+// The SetFontFallback example demonstrates this method.
+func (f *Fpdf) SetFontFallback(familyStr string, fallbackStrs []string) {
+	familyStr = strings.ToLower(fontFamilyEscape(familyStr))
+	if len(fallbackStrs) == 0 {
+		delete(f.fontFallbacks, familyStr)
+		return
+	}
+	list := make([]string, len(fallbackStrs))
+	for j, str := range fallbackStrs {
+		list[j] = strings.ToLower(fontFamilyEscape(str))
+	}
+	if f.fontFallbacks == nil {
+		f.fontFallbacks = make(map[string][]string)
+	}
+	f.fontFallbacks[familyStr] = list
+}
+
+// fontHasRune returns true if the UTF-8 font contains a glyph for r.
+func fontHasRune(font *fontDefType, r rune) bool {
+	return (font.Tp == "UTF8" || font.Tp == "CJK") && r > 0 && (int(r) < len(font.Cw) && font.Cw[r] != 0 || fontColorGlyph(font, r) != nil)
+}
+
+// fallbackFont returns the fallback font that supplies the glyph for r. ok is
+// false if the current font contains r or no fallback font does.
+func (f *Fpdf) fallbackFont(r rune) (font fontDefType, ok bool) {
+	if !f.isCurrentUTF8 || fontHasRune(&f.currentFont, r) {
+		return
+	}
+	for _, familyStr := range f.fontFallbacks[f.fontFamily] {
+		fb, found := f.fonts[familyStr+f.fontStyle]
+		if !found {
+			fb, found = f.fonts[familyStr]
+		}
+		if found && fontHasRune(&fb, r) {
+			return fb, true
+		}
+	}
+	return
+}
+
+// runeWidth returns the width, in glyph units, with which r is rendered in the
+// current font: that of its color glyph, its outline glyph or the glyph of the
+// fallback font that supplies it, in this order. ok is false if none of these
+// fonts contains r. All methods that measure text use this width.
+func (f *Fpdf) runeWidth(r rune) (w int, ok bool) {
+	cw := f.currentFont.Cw
+	if !f.isCurrentUTF8 {
+		if r >= 0 && int(r) < len(cw) && cw[r] != 0 {
+			return cw[r], true
+		}
+		return
+	}
+	if fontHasRune(&f.currentFont, r) {
+		return fontRuneWidth(&f.currentFont, r), true
+	}
+	var font fontDefType
+	if font, ok = f.fallbackFont(r); ok {
+		w = fontRuneWidth(&font, r)
+	}
+	return
+}
+
+// fallbackRuns splits txtStr into runs of characters that are rendered with
+// the same font, separating the color glyphs of each font from its outline
+// glyphs. It returns nil if all characters are rendered with the outline
+// glyphs of the current font.
+func (f *Fpdf) fallbackRuns(txtStr string) (runs []fontRunType) {
+	if !f.isCurrentUTF8 || len(f.fontFallbacks[f.fontFamily]) == 0 &&
+		(f.currentFont.utf8File == nil || !f.currentFont.utf8File.hasColorGlyphs()) {
+		return nil
+	}
+	fallback := false
+	var b strings.Builder
+	cur := fontRunType{font: f.currentFont}
+	for _, r := range txtStr {
+		font, ok := f.fallbackFont(r)
+		if !ok {
+			font = f.currentFont
+		}
+		color := fontColorGlyph(&font, r) != nil
+		fallback = fallback || ok || color
+		if (font.i != cur.font.i || color != cur.color) && b.Len() > 0 {
+			cur.str = b.String()
+			runs = append(runs, cur)
+			b.Reset()
+		}
+		cur = fontRunType{font: font, color: color}
+		b.WriteRune(r)
+	}
+	if !fallback {
+		return nil
+	}
+	if b.Len() > 0 {
+		cur.str = b.String()
+		runs = append(runs, cur)
+	}
+	return
+}
+
+// fallbackText returns the text operators that show runs, each with its
+// font, followed by the operator that reselects the current font. If
+// justified is true, spaces are widened by shift thousandths of a text space
+// unit. Color glyphs are skipped in the text; glyphStr holds the operators
+// that paint them after the text object, which begins at x, y in points.
+func (f *Fpdf) fallbackText(runs []fontRunType, justified bool, shift, x, y float64) (textStr, glyphStr string) {
+	var s, g fmtBuffer
+	space := f.escape(utf8toutf16(" ", false))
+	// Position in thousandths of a text space unit
+	pos := 0.0
+	for _, run := range runs {
+		if run.color {
+			for _, r := range run.str {
+				glyph := fontColorGlyph(&run.font, r)
+				f.colorGlyphOps(&g, &run.font, glyph, x+pos*f.fontSizePt/1000, y)
+				s.printf("[%d] TJ ", -glyph.advance)
+				pos += float64(glyph.advance)
+			}
+			continue
+		}
+		for _, r := range run.str {
+			run.font.usedRunes[int(r)] = int(r)
+			pos += float64(fontRuneWidth(&run.font, r))
+			if justified && r == ' ' {
+				pos += shift
+			}
+		}
+		s.printf("/F%s %.2f Tf ", run.font.i, f.fontSizePt)
+		if justified {
+			s.printf("[")
+			for j, word := range strings.Split(run.str, " ") {
+				if j > 0 {
+					s.printf("%.3f(%s) ", -shift, space)
+				}
+				s.printf("(%s) ", f.escape(utf8toutf16(word, false)))
+			}
+			s.printf("] TJ ")
+		} else {
+			s.printf("(%s)Tj ", f.escape(utf8toutf16(run.str, false)))
+		}
+	}
+	s.printf("/F%s %.2f Tf", f.currentFont.i, f.fontSizePt)
+	return s.String(), g.String()
+}
@@ -0,0 +1,179 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// FontFaceType describes one face of a TrueType or OpenType font file. It is
+// returned by FontCollectionFaces().
+type FontFaceType struct {
+	Index          int    // zero-based position of the face in the collection
+	PostScriptName string // name ID 6, for example "NotoSansCJKjp-Bold"
+	FamilyName     string // name ID 1, for example "Noto Sans CJK JP"
+	SubfamilyName  string // name ID 2, for example "Bold"
+	FullName       string // name ID 4, for example "Noto Sans CJK JP Bold"
+}
+
+// utf8FontFaceOffset returns the position of the table directory of the
+// specified face in data. A font that is not a collection has a single face.
+func utf8FontFaceOffset(data []byte, face int) (int, error) {
+	if len(data) >= 4 && string(data[0:4]) == "ttcf" && len(data) < 12 {
+		return 0, fmt.Errorf("invalid font collection header")
+	}
+	if len(data) < 12 || string(data[0:4]) != "ttcf" {
+		if face != 0 {
+			return 0, fmt.Errorf("font face %d not found: font is not a collection", face)
+		}
+		return 0, nil
+	}
+	count := int(binary.BigEndian.Uint32(data[8:]))
+	if face < 0 || face >= count {
+		return 0, fmt.Errorf("font face %d not found: collection has %d faces", face, count)
+	}
+	if 12+4*count > len(data) {
+		return 0, fmt.Errorf("invalid font collection header")
+	}
+	offset := int(binary.BigEndian.Uint32(data[12+4*face:]))
+	if offset < 12 || offset+12 > len(data) {
+		return 0, fmt.Errorf("invalid offset of font face %d", face)
+	}
+	return offset, nil
+}
+
+// fontFaceSelectType identifies the face of a font file that is imported and,
+// for variable fonts, its instance. The zero value selects the default instance
+// of the first face.
+type fontFaceSelectType struct {
+	index    int                // position of the face in a collection
+	name     string             // name of the face; overrides index
+	instance string             // name of the instance of a variable font
+	coords   map[string]float64 // axis coordinates of the instance, if not named
+	strict   bool               // set by the methods that select a face
+}
+
+// explicit reports whether sel selects anything but the default or comes
+// from a method that selects a face. Errors in such fonts are recorded in the
+// Fpdf rather than printed.
+func (sel fontFaceSelectType) explicit() bool {
+	return sel.strict || sel.index != 0 || sel.name != "" || sel.instance != "" || sel.coords != nil
+}
+
+// utf8FontFace parses the face of utf8Bytes that is selected by sel.
+func utf8FontFace(utf8Bytes []byte, sel fontFaceSelectType) (utf *utf8FontFile, err error) {
+	faceIndex, faceNameStr := sel.index, sel.name
+	if faceNameStr != "" {
+		var faces []FontFaceType
+		faces, err = FontCollectionFacesFromBytes(utf8Bytes)
+		if err != nil {
+			return
+		}
+		faceIndex = -1
+		for _, face := range faces {
+			if face.PostScriptName == faceNameStr || strings.EqualFold(face.FullName, faceNameStr) {
+				faceIndex = face.Index
+				break
+			}
+		}
+		if faceIndex < 0 {
+			// A family name selects the first face of that family
+			for _, face := range faces {
+				if strings.EqualFold(face.FamilyName, faceNameStr) {
+					faceIndex = face.Index
+					break
+				}
+			}
+		}
+		if faceIndex < 0 {
+			return nil, fmt.Errorf("font face \"%s\" not found", faceNameStr)
+		}
+	}
+	var offset int
+	offset, err = utf8FontFaceOffset(utf8Bytes, faceIndex)
+	if err != nil {
+		return
+	}
+	utf = newUTF8Font(&fileReader{readerPosition: 0, array: utf8Bytes})
+	utf.faceOffset = offset
+	err = utf.parseFile()
+	if err == nil && (sel.instance != "" || sel.coords != nil) {
+		err = utf.setVariation(sel.instance, sel.coords)
+	}
+	return
+}
+
+// FontCollectionFaces returns the faces contained in the TrueType or OpenType
+// font file specified by fileStr. A collection file (usually with the ".ttc"
+// or ".otc" extension) contains several faces; other font files contain one.
+// The Index field of each face can be passed to AddUTF8FontFace().
+func FontCollectionFaces(fileStr string) (faces []FontFaceType, err error) {
+	var data []byte
+	data, err = ioutil.ReadFile(fileStr)
+	if err == nil {
+		faces, err = FontCollectionFacesFromBytes(data)
+	}
+	return
+}
+
+// FontCollectionFacesFromBytes returns the faces contained in the TrueType or
+// OpenType font data specified by utf8Bytes. See FontCollectionFaces() for
+// more details.
+func FontCollectionFacesFromBytes(utf8Bytes []byte) (faces []FontFaceType, err error) {
+	count := 1
+	if len(utf8Bytes) >= 12 && string(utf8Bytes[0:4]) == "ttcf" {
+		count = int(binary.BigEndian.Uint32(utf8Bytes[8:]))
+	}
+	for j := 0; j < count; j++ {
+		var offset int
+		offset, err = utf8FontFaceOffset(utf8Bytes, j)
+		if err != nil {
+			return nil, err
+		}
+		utf := newUTF8Font(&fileReader{readerPosition: 0, array: utf8Bytes})
+		utf.faceOffset = offset
+		utf.fileReader.readerPosition = int64(offset) + 4
+		utf.generateTableDescriptions()
+		if _, ok := utf.tableDescriptions["name"]; !ok {
+			return nil, fmt.Errorf("font face %d has no name table", j)
+		}
+		utf.parseNAMETable()
+		faces = append(faces, FontFaceType{
+			Index:          j,
+			PostScriptName: utf.names[6],
+			FamilyName:     utf.names[1],
+			SubfamilyName:  utf.names[2],
+			FullName:       utf.names[4],
+		})
+	}
+	return
+}
+
+// AddUTF8FontFace imports one face of a TrueType or OpenType font collection,
+// such as the ".ttc" files in which many CJK and platform fonts are
+// distributed, and makes it available in the same way as AddUTF8Font(). face
+// is the zero-based index of the face in the collection; FontCollectionFaces()
+// lists the available faces. AddUTF8Font() imports the first face of a
+// collection.
+//
+// The AddUTF8FontFace example demonstrates this method.
+func (f *Fpdf) AddUTF8FontFace(familyStr, styleStr, fileStr string, face int) {
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true, fontFaceSelectType{index: face, strict: true})
+}
+
+// AddUTF8FontFaceByName imports the face of a TrueType or OpenType font
+// collection that is identified by faceNameStr. faceNameStr is compared with
+// the PostScript name of each face and, ignoring case, with its full name. If
+// no face matches, the first face whose family name matches is selected. See
+// AddUTF8FontFace() for more details.
+func (f *Fpdf) AddUTF8FontFaceByName(familyStr, styleStr, fileStr, faceNameStr string) {
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true, fontFaceSelectType{name: faceNameStr, strict: true})
+}
+
+// AddUTF8FontFaceFromBytes imports one face of a TrueType or OpenType font
+// collection from static bytes within the executable. See AddUTF8FontFace()
+// for more details.
+func (f *Fpdf) AddUTF8FontFaceFromBytes(familyStr, styleStr string, utf8Bytes []byte, face int) {
+	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, nil, nil, utf8Bytes, fontFaceSelectType{index: face, strict: true})
+}
@@ -0,0 +1,411 @@
+package gofpdf
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// FontMetricsType holds the vertical metrics of a font at a particular size.
+// It is returned by GetFontMetrics(). Values are expressed in the unit of
+// measure specified in New() and are measured from the baseline, with
+// positive values above it.
+type FontMetricsType struct {
+	Ascender  float64 // height of the ascenders, for example of "h"
+	Descender float64 // depth of the descenders, for example of "p"; negative
+	LineGap   float64 // additional leading recommended by the font designer
+	CapHeight float64 // height of flat capital letters, for example of "H"
+	XHeight   float64 // height of flat lowercase letters, for example of "x"
+}
+
+// TextBoundsType describes the area covered by the glyphs of a character
+// or string. It is returned by GetGlyphBounds() and GetTextBounds(). The
+// coordinates are expressed in the unit of measure specified in New() and are
+// relative to the origin of the text on the baseline, with y increasing
+// upward as in the font descriptor. A string of blanks has an empty area, in
+// which all coordinates are zero.
+type TextBoundsType struct {
+	XMin, YMin float64 // lower left corner of the area covered by the glyphs
+	XMax, YMax float64 // upper right corner of the area covered by the glyphs
+	Width      float64 // advance width, as returned by GetStringWidth()
+}
+
+// coreFontMetrics holds the ascender, descender, cap height and x-height of
+// the core fonts, which are not included in their font definitions. The
+// values are taken from the Adobe font metrics files.
+var coreFontMetrics = map[string][4]int{
+	"Courier":               {629, -157, 562, 426},
+	"Courier-Bold":          {629, -157, 562, 439},
+	"Courier-Oblique":       {629, -157, 562, 426},
+	"Courier-BoldOblique":   {629, -157, 562, 439},
+	"Helvetica":             {718, -207, 718, 523},
+	"Helvetica-Bold":        {718, -207, 718, 532},
+	"Helvetica-Oblique":     {718, -207, 718, 523},
+	"Helvetica-BoldOblique": {718, -207, 718, 532},
+	"Times-Roman":           {683, -217, 662, 450},
+	"Times-Bold":            {683, -217, 676, 461},
+	"Times-Italic":          {683, -217, 653, 441},
+	"Times-BoldItalic":      {683, -217, 669, 462},
+	"ZapfDingbats":          {820, -143, 0, 0},
+}
+
+// metricsFont returns the font that is used for familyStr and styleStr and
+// the part of its style that is simulated. An empty familyStr selects the
+// current font family. The font is loaded if it is a core font that has not
+// been used before; it does not become the current font. ok is false if the
+// font is not defined, which is not an error.
+func (f *Fpdf) metricsFont(familyStr, styleStr string) (font fontDefType, synthStr string, ok bool) {
+	font, _, _, synthStr, ok = f.metricsFontFamily(familyStr, styleStr)
+	return
+}
+
+// metricsFontFamily is like metricsFont but also returns the family and style
+// in the form in which the font is selected with SetFont().
+func (f *Fpdf) metricsFontFamily(familyStr, styleStr string) (font fontDefType, family, style, synthStr string, ok bool) {
+	if f.err != nil {
+		return
+	}
+	familyStr = fontFamilyEscape(familyStr)
+	if familyStr == "" {
+		familyStr = f.fontFamily
+	} else {
+		familyStr = strings.ToLower(familyStr)
+	}
+	styleStr = strings.ToUpper(styleStr)
+	styleStr = strings.Replace(styleStr, "U", "", -1)
+	styleStr = strings.Replace(styleStr, "S", "", -1)
+	if styleStr == "IB" {
+		styleStr = "BI"
+	}
+	var fontKey string
+	family, style, fontKey, synthStr, ok = f.findFont(familyStr, styleStr)
+	if ok {
+		font = f.fonts[fontKey]
+	}
+	return
+}
+
+// fontUnitSize returns the size in the unit of measure specified in New() of
+// a font of size points, or of the current font if size is zero.
+func (f *Fpdf) fontUnitSize(size float64) float64 {
+	if size == 0 {
+		return f.fontSize
+	}
+	return size / f.k
+}
+
+// GetFontMetrics returns the ascender, descender, line gap, cap height and
+// x-height of a font of size points. familyStr and styleStr identify the font
+// as with SetFont(), but the current font is left unchanged; an empty
+// familyStr selects the current font family and a size of zero the current
+// size. The font does not need to have been used.
+//
+// The values of fonts added with AddUTF8Font() and its variants are read
+// from the hhea and OS/2 tables of the font file. If the font does not
+// specify the cap height or x-height, it is measured from the glyphs "H" and
+// "x". For the core fonts, the values of the Adobe font metrics are used. For
+// other fonts, the values of the font descriptor are used, and the line gap
+// and x-height are zero.
+//
+// The FontMetrics example demonstrates this method.
+func (f *Fpdf) GetFontMetrics(familyStr, styleStr string, size float64) (m FontMetricsType) {
+	font, _, ok := f.metricsFont(familyStr, styleStr)
+	if !ok {
+		return
+	}
+	sz := f.fontUnitSize(size)
+	if font.utf8File != nil {
+		utf := font.utf8File
+		ascender, descender, lineGap, capHeight, xHeight := utf.verticalMetrics()
+		scale := sz / float64(utf.fontElementSize)
+		m.Ascender = float64(ascender) * scale
+		m.Descender = float64(descender) * scale
+		m.LineGap = float64(lineGap) * scale
+		m.CapHeight = float64(capHeight) * scale
+		m.XHeight = float64(xHeight) * scale
+		return
+	}
+	scale := sz / 1000
+	if v, ok := coreFontMetrics[font.Name]; ok && font.Tp == "Core" {
+		m.Ascender = float64(v[0]) * scale
+		m.Descender = float64(v[1]) * scale
+		m.CapHeight = float64(v[2]) * scale
+		m.XHeight = float64(v[3]) * scale
+		return
+	}
+	m.Ascender = float64(font.Desc.Ascent) * scale
+	m.Descender = float64(font.Desc.Descent) * scale
+	m.CapHeight = float64(font.Desc.CapHeight) * scale
+	return
+}
+
+// HasGlyph returns true if the font identified by familyStr and styleStr, as
+// with SetFont(), contains a glyph for r. Fallback fonts specified with
+// SetFontFallback() are not considered. For fonts that are not added with
+// AddUTF8Font(), one of its variants or AddCJKFont(), r is a single-byte
+// character code of the font's encoding.
+func (f *Fpdf) HasGlyph(familyStr, styleStr string, r rune) bool {
+	font, _, ok := f.metricsFont(familyStr, styleStr)
+	return ok && glyphCovered(&font, r)
+}
+
+// glyphCovered returns true if font contains a glyph for r.
+func glyphCovered(font *fontDefType, r rune) bool {
+	if font.Tp == "UTF8" || font.Tp == "CJK" {
+		return fontHasRune(font, r)
+	}
+	return r >= 0 && int(r) < len(font.Cw) && font.Cw[r] != 0
+}
+
+// MissingGlyphs returns the characters of txtStr for which the font identified
+// by familyStr and styleStr, as with SetFont(), contains no glyph. Each
+// character is listed once, in the order of its first occurrence; control
+// characters such as line breaks are ignored. See HasGlyph() for more details.
+//
+// This method can be used to check in advance whether text can be rendered
+// with a font, or to choose the fonts to pass to SetFontFallback().
+func (f *Fpdf) MissingGlyphs(familyStr, styleStr, txtStr string) (missing []rune) {
+	font, _, ok := f.metricsFont(familyStr, styleStr)
+	if !ok {
+		return
+	}
+	var chars []rune
+	if font.Tp == "UTF8" || font.Tp == "CJK" {
+		chars = []rune(txtStr)
+	} else {
+		for _, ch := range []byte(txtStr) {
+			chars = append(chars, rune(ch))
+		}
+	}
+	seen := make(map[rune]bool)
+	for _, r := range chars {
+		if seen[r] || unicode.IsControl(r) {
+			continue
+		}
+		seen[r] = true
+		if !glyphCovered(&font, r) {
+			missing = append(missing, r)
+		}
+	}
+	return
+}
+
+// GetGlyphBounds returns the area covered by the glyph for r in the font
+// identified by familyStr and styleStr at size points. See GetTextBounds()
+// for more details. ok is false if the font contains no glyph for r.
+func (f *Fpdf) GetGlyphBounds(familyStr, styleStr string, size float64, r rune) (b TextBoundsType, ok bool) {
+	var font fontDefType
+	font, _, ok = f.metricsFont(familyStr, styleStr)
+	if ok && glyphCovered(&font, r) {
+		txtStr := string(r)
+		if font.Tp != "UTF8" && font.Tp != "CJK" {
+			txtStr = string([]byte{byte(r)})
+		}
+		return f.GetTextBounds(familyStr, styleStr, size, txtStr), true
+	}
+	return b, false
+}
+
+// GetTextBounds returns the area covered by the glyphs of txtStr when it is
+// rendered in the font identified by familyStr and styleStr at size points.
+// familyStr, styleStr and size are interpreted as with GetFontMetrics(). The
+// current font is left unchanged.
+//
+// For fonts added with AddUTF8Font() and its variants, the bounds are those
+// of the glyph outlines, including the extreme points of curves, so they can
+// be used to align text precisely, for example to center a label vertically
+// in a box regardless of whether it has ascenders or descenders. The slant
+// and stroke of synthetic styles are taken into account. Characters that the
+// font does not contain contribute only their advance width, which, as with
+// GetStringWidth(), is that of the fallback font that supplies them or the
+// missing width of the font. Other fonts do
+// not retain glyph outlines; for them the area spans the advance width of the
+// text and the ascender and descender of the font.
+//
+// The FontMetrics example demonstrates this method.
+func (f *Fpdf) GetTextBounds(familyStr, styleStr string, size float64, txtStr string) (b TextBoundsType) {
+	font, family, style, synthStr, ok := f.metricsFontFamily(familyStr, styleStr)
+	if !ok {
+		return
+	}
+	sz := f.fontUnitSize(size)
+	b.Width = f.metricsStringWidth(font, family, style, sz, txtStr)
+	if font.utf8File == nil {
+		if b.Width > 0 {
+			m := f.GetFontMetrics(familyStr, styleStr, size)
+			b.XMax, b.YMin, b.YMax = b.Width, m.Descender, m.Ascender
+		}
+		return
+	}
+	utf := font.utf8File
+	scale := sz / float64(utf.fontElementSize)
+	skew := 0.0
+	if strings.Contains(synthStr, "I") {
+		skew = syntheticItalicSkew
+	}
+	first, x := true, 0.0
+	for _, r := range txtStr {
+		if gid, found := utf.glyphIndex(r); found {
+			contours, err := utf.glyphOutline(gid)
+			if err != nil {
+				f.err = err
+				return TextBoundsType{}
+			}
+			if xMin, yMin, xMax, yMax, ok := outlineBounds(contours, skew); ok {
+				xMin, xMax = x+xMin*scale, x+xMax*scale
+				yMin, yMax = yMin*scale, yMax*scale
+				if first {
+					b.XMin, b.YMin, b.XMax, b.YMax = xMin, yMin, xMax, yMax
+					first = false
+				} else {
+					b.XMin, b.YMin = math.Min(b.XMin, xMin), math.Min(b.YMin, yMin)
+					b.XMax, b.YMax = math.Max(b.XMax, xMax), math.Max(b.YMax, yMax)
+				}
+			}
+		}
+		x += f.metricsStringWidth(font, family, style, sz, string(r))
+	}
+	if !first && strings.Contains(synthStr, "B") {
+		// Half of the stroke lies outside the outlines
+		d := syntheticBoldStroke * sz / 2
+		b.XMin, b.YMin, b.XMax, b.YMax = b.XMin-d, b.YMin-d, b.XMax+d, b.YMax+d
+	}
+	return
+}
+
+// metricsStringWidth returns the width of txtStr as GetStringWidth() returns
+// it when font, selected as family and style, is the current font at size sz,
+// in the unit of measure specified in New(). The current font is restored.
+func (f *Fpdf) metricsStringWidth(font fontDefType, family, style string, sz float64, txtStr string) float64 {
+	curFont, curFamily, curStyle, curUTF8, curSize := f.currentFont, f.fontFamily, f.fontStyle, f.isCurrentUTF8, f.fontSize
+	f.currentFont, f.fontFamily, f.fontStyle, f.fontSize = font, family, style, sz
+	f.isCurrentUTF8 = font.Tp == "UTF8" || font.Tp == "CJK"
+	w := f.GetStringWidth(txtStr)
+	f.currentFont, f.fontFamily, f.fontStyle, f.isCurrentUTF8, f.fontSize = curFont, curFamily, curStyle, curUTF8, curSize
+	return w
+}
+
+// outlineBounds returns the bounding box of contours, which are expressed in
+// font units with the y axis pointing upward, after slanting them by skew. ok
+// is false if the contours contain no points.
+func outlineBounds(contours [][]SVGBasicSegmentType, skew float64) (xMin, yMin, xMax, yMax float64, ok bool) {
+	xMin, yMin = math.Inf(1), math.Inf(1)
+	xMax, yMax = math.Inf(-1), math.Inf(-1)
+	add := func(x, y float64) {
+		xMin, xMax = math.Min(xMin, x), math.Max(xMax, x)
+		yMin, yMax = math.Min(yMin, y), math.Max(yMax, y)
+		ok = true
+	}
+	for _, contour := range contours {
+		var x0, y0 float64
+		for _, seg := range contour {
+			var p [8]float64
+			n := segmentPointCount(seg.Cmd)
+			p[0], p[1] = x0+skew*y0, y0
+			for j := 0; j < n; j++ {
+				p[2*j+2], p[2*j+3] = seg.Arg[2*j]+skew*seg.Arg[2*j+1], seg.Arg[2*j+1]
+			}
+			switch seg.Cmd {
+			case 'M', 'L':
+				add(p[2], p[3])
+			case 'Q':
+				add(p[4], p[5])
+				for _, t := range bezierExtrema(p[:6]) {
+					u := 1 - t
+					add(u*u*p[0]+2*u*t*p[2]+t*t*p[4], u*u*p[1]+2*u*t*p[3]+t*t*p[5])
+				}
+			case 'C':
+				add(p[6], p[7])
+				for _, t := range bezierExtrema(p[:8]) {
+					u := 1 - t
+					add(u*u*u*p[0]+3*u*u*t*p[2]+3*u*t*t*p[4]+t*t*t*p[6],
+						u*u*u*p[1]+3*u*u*t*p[3]+3*u*t*t*p[5]+t*t*t*p[7])
+				}
+			}
+			if n > 0 {
+				x0, y0 = seg.Arg[2*n-2], seg.Arg[2*n-1]
+			}
+		}
+	}
+	return
+}
+
+// bezierExtrema returns the parameters in the open interval (0, 1) at which
+// the quadratic or cubic Bézier curve with control points p (x0, y0, x1, y1,
+// ...) has a horizontal or vertical tangent.
+func bezierExtrema(p []float64) (ts []float64) {
+	add := func(t float64) {
+		if t > 0 && t < 1 {
+			ts = append(ts, t)
+		}
+	}
+	for axis := 0; axis < 2; axis++ {
+		if len(p) == 6 {
+			// Derivative: 2(1-t)(p1-p0) + 2t(p2-p1)
+			a, b := p[2+axis]-p[axis], p[4+axis]-p[2+axis]
+			if a != b {
+				add(a / (a - b))
+			}
+			continue
+		}
+		// Derivative: 3(at² + bt + c)
+		p0, p1, p2, p3 := p[axis], p[2+axis], p[4+axis], p[6+axis]
+		a := -p0 + 3*p1 - 3*p2 + p3
+		b := 2 * (p0 - 2*p1 + p2)
+		c := p1 - p0
+		if math.Abs(a) < 1e-12 {
+			if b != 0 {
+				add(-c / b)
+			}
+			continue
+		}
+		d := b*b - 4*a*c
+		if d >= 0 {
+			d = math.Sqrt(d)
+			add((-b + d) / (2 * a))
+			add((-b - d) / (2 * a))
+		}
+	}
+	return
+}
+
+// verticalMetrics returns the ascender, descender and line gap of the font and
+// the height of its capital and lowercase letters, in font units.
+func (utf *utf8FontFile) verticalMetrics() (ascender, descender, lineGap, capHeight, xHeight int) {
+	if desc, ok := utf.tableDescriptions["hhea"]; ok && desc.size >= 10 {
+		utf.seekTable("hhea", 4)
+		ascender = int(utf.readInt16())
+		descender = int(utf.readInt16())
+		lineGap = int(utf.readInt16())
+	}
+	if desc, ok := utf.tableDescriptions["OS/2"]; ok && desc.size >= 74 {
+		if ascender == 0 && descender == 0 {
+			utf.seekTable("OS/2", 68)
+			ascender = int(utf.readInt16())
+			descender = int(utf.readInt16())
+			lineGap = int(utf.readInt16())
+		}
+		if utf.getUint16(desc.position) >= 2 && desc.size >= 90 {
+			utf.seekTable("OS/2", 86)
+			xHeight = int(utf.readInt16())
+			capHeight = int(utf.readInt16())
+		}
+	}
+	measure := func(r rune) int {
+		if gid, ok := utf.glyphIndex(r); ok {
+			if contours, err := utf.glyphOutline(gid); err == nil {
+				if _, _, _, yMax, ok := outlineBounds(contours, 0); ok {
+					return int(math.Round(yMax))
+				}
+			}
+		}
+		return 0
+	}
+	if capHeight == 0 {
+		capHeight = measure('H')
+	}
+	if xHeight == 0 {
+		xHeight = measure('x')
+	}
+	return
+}
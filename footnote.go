@@ -0,0 +1,332 @@
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+)
+
+// FootnoteFormatType specifies the appearance of footnotes. It is passed to
+// SetFootnoteFormat().
+//
+// FontFamilyStr and FontStyleStr specify the font used for the note text. An
+// empty FontFamilyStr selects the font family that is current when the note
+// is added. FontSize is the size of the note font in points; zero selects 8
+// points. LineHt is the height of each note line in the unit of measure
+// specified in New(); zero selects a height proportional to FontSize.
+//
+// SeparatorWd is the length of the rule that separates the notes from the
+// body text; zero selects one third of the width between the margins.
+// SeparatorGap is the vertical space reserved for the rule; zero selects the
+// note line height.
+//
+// MarkSize is the size, in points, of the superscript marker written by
+// WriteFootnote(); zero selects 60 percent of the current font size.
+type FootnoteFormatType struct {
+	FontFamilyStr string
+	FontStyleStr  string
+	FontSize      float64
+	LineHt        float64
+	SeparatorWd   float64
+	SeparatorGap  float64
+	MarkSize      float64
+}
+
+// footnoteRecType holds the lines of a note that are to be rendered on a
+// single page. markStr is empty for the continuation of a note that was
+// started on a previous page.
+type footnoteRecType struct {
+	markStr string
+	lines   []string
+}
+
+// footnoteStateType manages the notes of a document.
+type footnoteStateType struct {
+	format    FootnoteFormatType
+	endnotes  bool              // collect notes for WriteEndnotes() rather than place them on the page
+	count     int               // number of footnotes added
+	endCount  int               // number of endnotes added
+	page      []footnoteRecType // note lines to be rendered on the current page
+	carry     []footnoteRecType // note lines continued on the next page
+	reserved  float64           // space reserved at bottom of current page
+	endList   []footnoteRecType // endnotes waiting for WriteEndnotes()
+	lineHt    float64           // line height of the notes on the current page
+	indentWd  float64           // width of the marker column on the current page
+	separator bool              // separator rule has been reserved on the current page
+}
+
+// SetFootnoteFormat sets the appearance of footnotes that are subsequently
+// added with AddFootnote() or WriteFootnote(). See FootnoteFormatType for the
+// meaning of each field.
+func (f *Fpdf) SetFootnoteFormat(format FootnoteFormatType) {
+	f.footnote.format = format
+}
+
+// SetEndnoteMode specifies whether notes added with AddFootnote() and
+// WriteFootnote() are placed at the bottom of the current page (false, the
+// default) or collected as endnotes (true). Collected endnotes are rendered
+// with WriteEndnotes(), typically in a later section of the document.
+func (f *Fpdf) SetEndnoteMode(endnotes bool) {
+	f.footnote.endnotes = endnotes
+}
+
+// footnoteLineHt returns the line height and font size of note text.
+func (f *Fpdf) footnoteLineHt() (lineHt, sizePt float64) {
+	sizePt = f.footnote.format.FontSize
+	if sizePt == 0 {
+		sizePt = 8
+	}
+	lineHt = f.footnote.format.LineHt
+	if lineHt == 0 {
+		lineHt = 1.25 * sizePt / f.k
+	}
+	return
+}
+
+// footnoteFont selects the note font. It returns a function that restores the
+// font that was current before the call.
+func (f *Fpdf) footnoteFont() (restore func()) {
+	familyStr := f.fontFamily
+	styleStr := f.fontStyle
+	if f.underline {
+		styleStr += "U"
+	}
+	if f.strikeout {
+		styleStr += "S"
+	}
+	sizePt := f.fontSizePt
+	noteFamilyStr := f.footnote.format.FontFamilyStr
+	if noteFamilyStr == "" {
+		noteFamilyStr = familyStr
+	}
+	_, noteSizePt := f.footnoteLineHt()
+	f.SetFont(noteFamilyStr, f.footnote.format.FontStyleStr, noteSizePt)
+	return func() {
+		if familyStr != "" {
+			f.SetFont(familyStr, styleStr, sizePt)
+		}
+	}
+}
+
+// footnoteSplit breaks txtStr into lines that fit in width w using the current
+// font.
+func (f *Fpdf) footnoteSplit(txtStr string, w float64) (lines []string) {
+	if f.isCurrentUTF8 {
+		return f.SplitText(txtStr, w)
+	}
+	for _, ln := range f.SplitLines([]byte(txtStr), w) {
+		lines = append(lines, string(ln))
+	}
+	return
+}
+
+// AddFootnote registers a note with the text specified by txtStr and returns
+// the marker that identifies it, for example "3". The marker is not written
+// to the document; this allows it to be included in text rendered with
+// MultiCell(), Cell() or similar methods. See WriteFootnote() for a method
+// that writes the marker as a superscript at the current position.
+//
+// Unless endnote mode has been enabled with SetEndnoteMode(), the note is
+// placed at the bottom of the current page, above the bottom margin and
+// below a separator rule. The space required by the note is reserved by
+// moving the automatic page break threshold upward, so body text that follows
+// wraps to the next page sooner. If the note does not fit on the current page,
+// the remainder is continued at the bottom of the following page.
+//
+// The FootnoteFormatType structure passed to SetFootnoteFormat() controls the
+// appearance of the notes.
+func (f *Fpdf) AddFootnote(txtStr string) (markStr string) {
+	if f.err != nil {
+		return
+	}
+	fn := &f.footnote
+	if fn.endnotes {
+		fn.endCount++
+		markStr = sprintf("%d", fn.endCount)
+		fn.endList = append(fn.endList, footnoteRecType{markStr: markStr, lines: []string{txtStr}})
+		return
+	}
+	if f.page == 0 {
+		f.err = fmt.Errorf("footnote requires an open page")
+		return
+	}
+	fn.count++
+	markStr = sprintf("%d", fn.count)
+	restore := f.footnoteFont()
+	lineHt, _ := f.footnoteLineHt()
+	indentWd := f.GetStringWidth("00") + 2*f.cMargin
+	lines := f.footnoteSplit(txtStr, f.w-f.lMargin-f.rMargin-indentWd)
+	restore()
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	fn.lineHt = lineHt
+	fn.indentWd = indentWd
+	// Space available between the line that contains the marker and the
+	// current page break threshold
+	lineBottom := f.y + math.Max(f.lasth, f.fontSize)
+	avail := f.pageBreakLimit() - lineBottom
+	sepHt := 0.0
+	if !fn.separator {
+		sepHt = f.footnoteSeparatorGap()
+	}
+	count := int(math.Floor((avail - sepHt) / lineHt))
+	if count > len(lines) {
+		count = len(lines)
+	}
+	if count < 0 || len(fn.carry) > 0 {
+		// Notes continued on the next page keep their order
+		count = 0
+	}
+	if count > 0 {
+		f.footnoteReserve(sepHt + float64(count)*lineHt)
+		fn.separator = true
+		fn.page = append(fn.page, footnoteRecType{markStr: markStr, lines: lines[:count]})
+		lines = lines[count:]
+	}
+	if len(lines) > 0 {
+		rec := footnoteRecType{lines: lines}
+		if count == 0 {
+			rec.markStr = markStr
+		}
+		fn.carry = append(fn.carry, rec)
+	}
+	return
+}
+
+// WriteFootnote writes the marker of a new note as a superscript at the
+// current position, in the same way as Write(), and registers the note text
+// specified by txtStr. h is the line height of the text that contains the
+// marker. See AddFootnote() for details about the placement of the note.
+//
+// The WriteFootnote example demonstrates this method.
+func (f *Fpdf) WriteFootnote(h float64, txtStr string) {
+	if f.err != nil {
+		return
+	}
+	sizePt := f.footnote.format.MarkSize
+	if sizePt == 0 {
+		sizePt = 0.6 * f.fontSizePt
+	}
+	// The marker is written before the note is registered so that the note
+	// is placed on the page where the marker ends up.
+	markStr := sprintf("%d", f.footnote.count+1)
+	if f.footnote.endnotes {
+		markStr = sprintf("%d", f.footnote.endCount+1)
+	}
+	f.SubWrite(h, markStr, sizePt, 0.4*f.fontSizePt, 0, "")
+	f.AddFootnote(txtStr)
+}
+
+// footnoteSeparatorGap returns the vertical space used by the separator rule.
+func (f *Fpdf) footnoteSeparatorGap() float64 {
+	if f.footnote.format.SeparatorGap > 0 {
+		return f.footnote.format.SeparatorGap
+	}
+	lineHt, _ := f.footnoteLineHt()
+	return lineHt
+}
+
+// footnoteReserve reserves ht above the bottom margin of the current page for
+// notes.
+func (f *Fpdf) footnoteReserve(ht float64) {
+	f.footnote.reserved += ht
+}
+
+// pageBreakLimit returns the vertical position beyond which content triggers
+// an automatic page break: the page break threshold, moved upward by the
+// space reserved for the notes of the current page.
+func (f *Fpdf) pageBreakLimit() float64 {
+	return f.pageBreakTrigger - f.footnote.reserved
+}
+
+// footnoteBeginPage reserves space on a newly started page for the notes that
+// are continued from the previous page. At least one line is placed on each
+// page; beyond that, continued notes occupy at most half of the page.
+func (f *Fpdf) footnoteBeginPage() {
+	fn := &f.footnote
+	if len(fn.carry) == 0 {
+		return
+	}
+	lineHt, _ := f.footnoteLineHt()
+	sepHt := f.footnoteSeparatorGap()
+	count := int(math.Floor(((f.pageBreakLimit()-f.y)/2 - sepHt) / lineHt))
+	if count < 1 {
+		count = 1
+	}
+	fn.lineHt = lineHt
+	ht := sepHt
+	var carry []footnoteRecType
+	for _, rec := range fn.carry {
+		switch {
+		case count == 0:
+			carry = append(carry, rec)
+		case count >= len(rec.lines):
+			fn.page = append(fn.page, rec)
+			count -= len(rec.lines)
+			ht += float64(len(rec.lines)) * lineHt
+		default:
+			fn.page = append(fn.page, footnoteRecType{markStr: rec.markStr, lines: rec.lines[:count]})
+			carry = append(carry, footnoteRecType{lines: rec.lines[count:]})
+			ht += float64(count) * lineHt
+			count = 0
+		}
+	}
+	fn.carry = carry
+	fn.separator = true
+	f.footnoteReserve(ht)
+}
+
+// footnotePut renders the notes of the current page in the reserved space
+// above the bottom margin and releases the space. It is called before the
+// page footer.
+func (f *Fpdf) footnotePut() {
+	fn := &f.footnote
+	if len(fn.page) > 0 {
+		x, y := f.x, f.y
+		lasth := f.lasth
+		restore := f.footnoteFont()
+		top := f.h - f.bMargin - fn.reserved
+		sepWd := fn.format.SeparatorWd
+		if sepWd == 0 {
+			sepWd = (f.w - f.lMargin - f.rMargin) / 3
+		}
+		sepHt := f.footnoteSeparatorGap()
+		f.Line(f.lMargin, top+sepHt/2, f.lMargin+sepWd, top+sepHt/2)
+		f.SetY(top + sepHt)
+		for _, rec := range fn.page {
+			for j, ln := range rec.lines {
+				f.SetX(f.lMargin)
+				markStr := ""
+				if j == 0 {
+					markStr = rec.markStr
+				}
+				f.CellFormat(fn.indentWd, fn.lineHt, markStr, "", 0, "R", false, 0, "")
+				f.CellFormat(0, fn.lineHt, ln, "", 1, "L", false, 0, "")
+			}
+		}
+		restore()
+		f.x, f.y = x, y
+		f.lasth = lasth
+	}
+	fn.reserved = 0
+	fn.page = fn.page[:0]
+	fn.separator = false
+}
+
+// WriteEndnotes writes the notes that have been collected in endnote mode
+// (see SetEndnoteMode()) starting at the current position, and then discards
+// them. Each note is rendered with MultiCell() using the current font and the
+// line height specified by h, preceded by its marker.
+func (f *Fpdf) WriteEndnotes(h float64) {
+	if f.err != nil {
+		return
+	}
+	indentWd := f.GetStringWidth("00.") + 2*f.cMargin
+	for _, rec := range f.footnote.endList {
+		x := f.GetX()
+		f.CellFormat(indentWd, h, rec.markStr+".", "", 0, "R", false, 0, "")
+		f.MultiCell(0, h, rec.lines[0], "", "L", false)
+		f.SetX(x)
+	}
+	f.footnote.endList = nil
+}
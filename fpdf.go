@@ -82,6 +82,7 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	f.defPageBoxes = make(map[string]PageBox)
 	f.state = 0
 	f.fonts = make(map[string]fontDefType)
+	f.verticalFonts = make(map[string]int)
 	f.fontFiles = make(map[string]fontFileType)
 	f.diffs = make([]string, 0, 8)
 	f.templates = make(map[string]Template)
@@ -200,6 +201,7 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	f.alpha = 1
 	f.gradientList = make([]gradientType, 0, 8)
 	f.gradientList = append(f.gradientList, gradientType{}) // gradientList[0] is unused
+	f.patternList = make([]patternType, 1, 8) // patternList[0] is unused
 	// Set default PDF version number
 	f.pdfVersion = "1.3"
 	f.SetProducer("FPDF "+cnFpdfVersion, true)
@@ -677,12 +679,23 @@ func (f *Fpdf) Close() {
 			return
 		}
 	}
+	// Add pages for footnotes that have been continued
+	for len(f.footnote.carry) > 0 && f.err == nil {
+		f.AddPage()
+	}
+	if f.err != nil {
+		return
+	}
+	f.columnEndPage()
 	// Page footer
 	f.inFooter = true
-	if f.footerFnc != nil {
-		f.footerFnc()
-	} else if f.footerFncLpi != nil {
-		f.footerFncLpi(true)
+	f.footnotePut()
+	if !f.sectionFooter() {
+		if f.footerFnc != nil {
+			f.footerFnc()
+		} else if f.footerFncLpi != nil {
+			f.footerFncLpi(true)
+		}
 	}
 	f.inFooter = false
 
@@ -742,18 +755,25 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	cf := f.colorFlag
 
 	if f.page > 0 {
+		// Restore page margins and draw column rules
+		f.columnEndPage()
 		f.inFooter = true
+		// Footnotes
+		f.footnotePut()
 		// Page footer avoid double call on footer.
-		if f.footerFnc != nil {
-			f.footerFnc()
-
-		} else if f.footerFncLpi != nil {
-			f.footerFncLpi(false) // not last page.
+		if !f.sectionFooter() {
+			if f.footerFnc != nil {
+				f.footerFnc()
+			} else if f.footerFncLpi != nil {
+				f.footerFncLpi(false) // not last page.
+			}
 		}
 		f.inFooter = false
 		// Close page
 		f.endpage()
 	}
+	// Activate section requested by AddSection()
+	f.sectionBegin()
 	// Start new page
 	f.beginpage(orientationStr, size)
 	// 	Set line cap style to current value
@@ -787,7 +807,14 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	f.color.text = tc
 	f.colorFlag = cf
 	// 	Page header
-	if f.headerFnc != nil {
+	if f.sectionCurrent() != nil {
+		f.inHeader = true
+		f.sectionHeader()
+		f.inHeader = false
+		if f.headerHomeMode {
+			f.SetHomeXY()
+		}
+	} else if f.headerFnc != nil {
 		f.inHeader = true
 		f.headerFnc()
 		f.inHeader = false
@@ -818,6 +845,10 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	}
 	f.color.text = tc
 	f.colorFlag = cf
+	// Reserve space for footnotes continued from previous page
+	f.footnoteBeginPage()
+	// Start first column if a column layout is active
+	f.columnBeginPage()
 	return
 }
 
@@ -840,6 +871,10 @@ func (f *Fpdf) AddPage() {
 		return
 	}
 	// dbg("AddPage")
+	if sec := f.sectionCurrent(); sec != nil {
+		f.AddPageFormat(sec.OrientationStr, sec.Size)
+		return
+	}
 	f.AddPageFormat(f.defOrientation, f.defPageSize)
 	return
 }
@@ -962,11 +997,8 @@ func (f *Fpdf) GetStringSymbolWidth(s string) int {
 	if f.isCurrentUTF8 {
 		unicode := []rune(s)
 		for _, char := range unicode {
-			intChar := int(char)
-			if len(f.currentFont.Cw) >= intChar && f.currentFont.Cw[intChar] > 0 {
-				if f.currentFont.Cw[intChar] != 65535 {
-					w += f.currentFont.Cw[intChar]
-				}
+			if fw, ok := f.runeWidth(char); ok {
+				w += fw
 			} else if f.currentFont.Desc.MissingWidth != 0 {
 				w += f.currentFont.Desc.MissingWidth
 			} else {
@@ -1363,6 +1395,12 @@ func (f *Fpdf) SetAlpha(alpha float64, blendModeStr string) {
 	}
 	f.alpha = alpha
 	f.blendMode = blendModeStr
+	f.outf("/GS%d gs", f.blendState(alpha, blendModeStr))
+}
+
+// blendState returns the number of the graphics state that sets the alpha
+// value and blend mode, registering the state if it has not been used before.
+func (f *Fpdf) blendState(alpha float64, blendModeStr string) int {
 	alphaStr := sprintf("%.3f", alpha)
 	keyStr := sprintf("%s %s", alphaStr, blendModeStr)
 	pos, ok := f.blendMap[keyStr]
@@ -1371,7 +1409,7 @@ func (f *Fpdf) SetAlpha(alpha float64, blendModeStr string) {
 		f.blendList = append(f.blendList, blendModeType{alphaStr, alphaStr, blendModeStr, 0})
 		f.blendMap[keyStr] = pos
 	}
-	f.outf("/GS%d gs", pos)
+	return pos
 }
 
 func (f *Fpdf) gradientClipStart(x, y, w, h float64) {
@@ -1411,6 +1449,9 @@ func (f *Fpdf) gradient(tp, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2, r float6
 // anchored on the rectangle edge. Color 1 is used up to the origin of the
 // vector and color 2 is used beyond the vector's end point. Between the points
 // the colors are gradually blended.
+//
+// CreateLinearGradient() creates gradients with more than two colors that can
+// paint any path.
 func (f *Fpdf) LinearGradient(x, y, w, h float64, r1, g1, b1, r2, g2, b2 int, x1, y1, x2, y2 float64) {
 	f.gradientClipStart(x, y, w, h)
 	f.gradient(2, r1, g1, b1, r2, g2, b2, x1, y1, x2, y2, 0)
@@ -1604,8 +1645,8 @@ func (f *Fpdf) ClipPolygon(points []PointType, outline bool) {
 }
 
 // ClipEnd ends a clipping operation that was started with a call to
-// ClipRect(), ClipRoundedRect(), ClipText(), ClipEllipse(), ClipCircle() or
-// ClipPolygon(). Clipping operations can be nested. The document cannot be
+// ClipRect(), ClipRoundedRect(), ClipText(), ClipEllipse(), ClipCircle(),
+// ClipPolygon() or ClipPath(). Clipping operations can be nested. The document cannot be
 // successfully output while a clipping operation is active.
 //
 // The ClipText() example demonstrates this method.
@@ -1641,7 +1682,7 @@ func (f *Fpdf) ClipEnd() {
 // definition file to be added. The file will be loaded from the font directory
 // specified in the call to New() or SetFontLocation().
 func (f *Fpdf) AddFont(familyStr, styleStr, fileStr string) {
-	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, false)
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, false, fontFaceSelectType{})
 }
 
 // AddUTF8Font imports a TrueType font with utf-8 symbols and makes it available.
@@ -1664,11 +1705,25 @@ func (f *Fpdf) AddFont(familyStr, styleStr, fileStr string) {
 // fileStr specifies the base name with ".json" extension of the font
 // definition file to be added. The file will be loaded from the font directory
 // specified in the call to New() or SetFontLocation().
+//
+// OpenType fonts with PostScript outlines (CFF or CFF2, usually with the
+// ".otf" extension) are supported as well as TrueType fonts. Their glyphs are
+// subset and embedded as a CID-keyed CFF font program, which requires PDF
+// version 1.6; the document version is raised accordingly.
+//
+// Color fonts, such as emoji fonts, are supported for text drawn with Text(),
+// CellFormat() and the methods based on it. Glyphs with layers in the COLR
+// table are painted as filled outlines in the colors of the first palette of
+// the CPAL table, and glyphs with PNG images in the CBDT or sbix table are
+// painted as images from the largest strike. Characters outside the Basic
+// Multilingual Plane, which includes most emoji, are available only as color
+// glyphs. A color font is typically specified as a fallback font of a text
+// font with SetFontFallback().
 func (f *Fpdf) AddUTF8Font(familyStr, styleStr, fileStr string) {
-	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true)
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true, fontFaceSelectType{})
 }
 
-func (f *Fpdf) addFont(familyStr, styleStr, fileStr string, isUTF8 bool) {
+func (f *Fpdf) addFont(familyStr, styleStr, fileStr string, isUTF8 bool, sel fontFaceSelectType) {
 	if fileStr == "" {
 		if isUTF8 {
 			fileStr = strings.Replace(familyStr, " ", "", -1) + strings.ToLower(styleStr) + ".ttf"
@@ -1698,9 +1753,8 @@ func (f *Fpdf) addFont(familyStr, styleStr, fileStr string, isUTF8 bool) {
 			f.SetError(err)
 			return
 		}
-		reader := fileReader{readerPosition: 0, array: utf8Bytes}
-		utf8File := newUTF8Font(&reader)
-		err = utf8File.parseFile()
+		var utf8File *utf8FontFile
+		utf8File, err = utf8FontFace(utf8Bytes, sel)
 		if err != nil {
 			f.SetError(err)
 			return
@@ -1791,13 +1845,16 @@ func makeSubsetRange(end int) map[int]int {
 //
 // zFileBytes contain all bytes of Z file.
 func (f *Fpdf) AddFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFileBytes []byte) {
-	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, jsonFileBytes, zFileBytes, nil)
+	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, jsonFileBytes, zFileBytes, nil, fontFaceSelectType{})
 }
 
 // AddUTF8FontFromBytes  imports a TrueType font with utf-8 symbols from static
 // bytes within the executable and makes it available for use in the generated
 // document.
 //
+// OpenType fonts with PostScript outlines are supported as described for
+// AddUTF8Font().
+//
 // family specifies the font family. The name can be chosen arbitrarily. If it
 // is a standard family name, it will override the corresponding font. This
 // string is used to subsequently set the font with the SetFont method.
@@ -1810,10 +1867,10 @@ func (f *Fpdf) AddFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFile
 //
 // zFileBytes contain all bytes of Z file.
 func (f *Fpdf) AddUTF8FontFromBytes(familyStr, styleStr string, utf8Bytes []byte) {
-	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, nil, nil, utf8Bytes)
+	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, nil, nil, utf8Bytes, fontFaceSelectType{})
 }
 
-func (f *Fpdf) addFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFileBytes, utf8Bytes []byte) {
+func (f *Fpdf) addFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFileBytes, utf8Bytes []byte, sel fontFaceSelectType) {
 	if f.err != nil {
 		return
 	}
@@ -1834,12 +1891,12 @@ func (f *Fpdf) addFontFromBytes(familyStr, styleStr string, jsonFileBytes, zFile
 		// }
 
 		Type := "UTF8"
-		reader := fileReader{readerPosition: 0, array: utf8Bytes}
-
-		utf8File := newUTF8Font(&reader)
-
-		err := utf8File.parseFile()
+		utf8File, err := utf8FontFace(utf8Bytes, sel)
 		if err != nil {
+			if sel.explicit() {
+				f.err = err
+				return
+			}
 			fmt.Printf("get metrics Error: %e\n", err)
 			return
 		}
@@ -2019,7 +2076,9 @@ func (f *Fpdf) GetFontDesc(familyStr, styleStr string) FontDescType {
 // familyStr specifies the font family. It can be either a name defined by
 // AddFont(), AddFontFromReader() or one of the standard families (case
 // insensitive): "Courier" for fixed-width, "Helvetica" or "Arial" for sans
-// serif, "Times" for serif, "Symbol" or "ZapfDingbats" for symbolic.
+// serif, "Times" for serif, "Symbol" or "ZapfDingbats" for symbolic. The
+// non-embedded CJK fonts "STSong-Light", "MSung-Light", "KozMinPro-Regular"
+// and "HYSMyeongJo-Medium" can also be selected by name; see AddCJKFont().
 //
 // styleStr can be "B" (bold), "I" (italic), "U" (underscore), "S" (strike-out)
 // or any combination. The default value (specified with an empty string) is
@@ -2036,7 +2095,6 @@ func (f *Fpdf) SetFont(familyStr, styleStr string, size float64) {
 	}
 	// dbg("SetFont")
 	familyStr = fontFamilyEscape(familyStr)
-	var ok bool
 	if familyStr == "" {
 		familyStr = f.fontFamily
 	} else {
@@ -2058,9 +2116,43 @@ func (f *Fpdf) SetFont(familyStr, styleStr string, size float64) {
 		size = f.fontSizePt
 	}
 
-	// Test if font is already loaded
-	fontKey := familyStr + styleStr
+	family, style, fontKey, synthStr, ok := f.findFont(familyStr, styleStr)
+	if !ok {
+		if f.err == nil {
+			f.err = fmt.Errorf("undefined font: %s %s", familyStr, styleStr)
+		}
+		return
+	}
+	familyStr, styleStr = family, style
+	// Select it
+	f.fontFamily = familyStr
+	f.fontStyle = styleStr
+	f.fontSizePt = size
+	f.fontSize = size / f.k
+	f.currentFont = f.fonts[fontKey]
+	f.fontSynthetic = synthStr
+	if f.currentFont.Tp == "UTF8" || f.currentFont.Tp == "CJK" {
+		f.isCurrentUTF8 = true
+	} else {
+		f.isCurrentUTF8 = false
+	}
+	if f.page > 0 {
+		f.outf("BT /F%s %.2f Tf ET", f.currentFont.i, f.fontSizePt)
+	}
+	return
+}
+
+// findFont returns the key of the font that is used for familyStr and
+// styleStr, loading a core font if it has not been used before, and the part
+// of the style that is simulated. family and style are familyStr and styleStr
+// in the form in which the font is selected. ok is false if the font is not
+// defined; f.err is set only if a core font cannot be loaded.
+func (f *Fpdf) findFont(familyStr, styleStr string) (family, style, fontKey, synthStr string, ok bool) {
+	fontKey = familyStr + styleStr
 	_, ok = f.fonts[fontKey]
+	if !ok && f.syntheticStyles[fontKey] {
+		fontKey, synthStr, ok = f.syntheticFont(familyStr, styleStr)
+	}
 	if !ok {
 		// Test if one of the core fonts
 		if familyStr == "arial" {
@@ -2082,29 +2174,20 @@ func (f *Fpdf) SetFont(familyStr, styleStr string, size float64) {
 					f.AddFontFromReader(familyStr, styleStr, rdr)
 				}
 				if f.err != nil {
-					return
+					return "", "", "", "", false
 				}
 			}
+		} else if _, ok = cjkFonts[familyStr]; ok {
+			fontKey = familyStr + styleStr
+			f.AddCJKFont(familyStr, styleStr, familyStr)
+			if f.err != nil {
+				return "", "", "", "", false
+			}
 		} else {
-			f.err = fmt.Errorf("undefined font: %s %s", familyStr, styleStr)
-			return
+			return "", "", "", "", false
 		}
 	}
-	// Select it
-	f.fontFamily = familyStr
-	f.fontStyle = styleStr
-	f.fontSizePt = size
-	f.fontSize = size / f.k
-	f.currentFont = f.fonts[fontKey]
-	if f.currentFont.Tp == "UTF8" {
-		f.isCurrentUTF8 = true
-	} else {
-		f.isCurrentUTF8 = false
-	}
-	if f.page > 0 {
-		f.outf("BT /F%s %.2f Tf ET", f.currentFont.i, f.fontSizePt)
-	}
-	return
+	return familyStr, styleStr, fontKey, synthStr, true
 }
 
 // SetFontStyle sets the style of the current font. See also SetFont()
@@ -2206,20 +2289,25 @@ func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 // precisely on the page, but it is usually easier to use Cell(), MultiCell()
 // or Write() which are the standard methods to print text.
 func (f *Fpdf) Text(x, y float64, txtStr string) {
-	var txt2 string
+	var txt2, glyphStr string
 	if f.isCurrentUTF8 {
 		if f.isRTL {
 			txtStr = reverseText(txtStr)
 			x -= f.GetStringWidth(txtStr)
 		}
-		txt2 = f.escape(utf8toutf16(txtStr, false))
-		for _, uni := range []rune(txtStr) {
-			f.currentFont.usedRunes[int(uni)] = int(uni)
+		if runs := f.fallbackRuns(txtStr); runs != nil {
+			txt2, glyphStr = f.fallbackText(runs, false, 0, x*f.k, (f.h-y)*f.k)
+		} else {
+			txt2 = "(" + f.escape(utf8toutf16(txtStr, false)) + ") Tj"
+			for _, uni := range []rune(txtStr) {
+				f.currentFont.usedRunes[int(uni)] = int(uni)
+			}
 		}
 	} else {
-		txt2 = f.escape(txtStr)
+		txt2 = "(" + f.escape(txtStr) + ") Tj"
 	}
-	s := sprintf("BT %.2f %.2f Td (%s) Tj ET", x*f.k, (f.h-y)*f.k, txt2)
+	s := sprintf("%sBT %s %s ET%s%s", f.syntheticBoldBegin(), f.textOrigin(x*f.k, (f.h-y)*f.k), txt2,
+		glyphStr, f.syntheticBoldEnd())
 	if f.underline && txtStr != "" {
 		s += " " + f.dounderline(x, y, txtStr)
 	}
@@ -2323,7 +2411,7 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 
 	borderStr = strings.ToUpper(borderStr)
 	k := f.k
-	if f.y+h > f.pageBreakTrigger && !f.inHeader && !f.inFooter && f.acceptPageBreak() {
+	if f.y+h > f.pageBreakLimit() && !f.inHeader && !f.inFooter && f.columnAcceptPageBreak() {
 		// Automatic page break
 		x := f.x
 		ws := f.ws
@@ -2418,30 +2506,48 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 		if f.colorFlag {
 			s.printf("q %s ", f.color.text.str)
 		}
+		s.printf("%s", f.syntheticBoldBegin())
 		//If multibyte, Tw has no effect - do word spacing using an adjustment before each space
 		if (f.ws != 0 || alignStr == "J") && f.isCurrentUTF8 { // && f.ws != 0
 			if f.isRTL {
 				txtStr = reverseText(txtStr)
 			}
 			wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
-			for _, uni := range []rune(txtStr) {
-				f.currentFont.usedRunes[int(uni)] = int(uni)
+			runs := f.fallbackRuns(txtStr)
+			if runs == nil {
+				for _, uni := range []rune(txtStr) {
+					f.currentFont.usedRunes[int(uni)] = int(uni)
+				}
 			}
 			space := f.escape(utf8toutf16(" ", false))
 			strSize := f.GetStringSymbolWidth(txtStr)
-			s.printf("BT 0 Tw %.2f %.2f Td [", (f.x+dx)*k, (f.h-(f.y+.5*h+.3*f.fontSize))*k)
 			t := strings.Split(txtStr, " ")
 			shift := float64((wmax - strSize)) / float64(len(t)-1)
-			numt := len(t)
-			for i := 0; i < numt; i++ {
-				tx := t[i]
-				tx = "(" + f.escape(utf8toutf16(tx, false)) + ")"
-				s.printf("%s ", tx)
-				if (i + 1) < numt {
-					s.printf("%.3f(%s) ", -shift, space)
+			if runs != nil {
+				bt, td := (f.x+dx)*k, (f.h-(f.y+.5*h+.3*f.fontSize))*k
+				textStr, glyphStr := f.fallbackText(runs, true, shift, bt, td)
+				s.printf("BT 0 Tw %s %s ET%s", f.textOrigin(bt, td), textStr, glyphStr)
+			} else {
+				s.printf("BT 0 Tw %s [", f.textOrigin((f.x+dx)*k, (f.h-(f.y+.5*h+.3*f.fontSize))*k))
+				numt := len(t)
+				for i := 0; i < numt; i++ {
+					tx := t[i]
+					tx = "(" + f.escape(utf8toutf16(tx, false)) + ")"
+					s.printf("%s ", tx)
+					if (i + 1) < numt {
+						s.printf("%.3f(%s) ", -shift, space)
+					}
 				}
+				s.printf("] TJ ET")
 			}
-			s.printf("] TJ ET")
+		} else if runs := f.fallbackRuns(txtStr); runs != nil {
+			if f.isRTL {
+				runs = f.fallbackRuns(reverseText(txtStr))
+			}
+			bt := (f.x + dx) * k
+			td := (f.h - (f.y + dy + .5*h + .3*f.fontSize)) * k
+			textStr, glyphStr := f.fallbackText(runs, false, 0, bt, td)
+			s.printf("BT %s %s ET%s", f.textOrigin(bt, td), textStr, glyphStr)
 		} else {
 			var txt2 string
 			if f.isCurrentUTF8 {
@@ -2460,9 +2566,10 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr, borderStr string, ln int,
 			}
 			bt := (f.x + dx) * k
 			td := (f.h - (f.y + dy + .5*h + .3*f.fontSize)) * k
-			s.printf("BT %.2f %.2f Td (%s)Tj ET", bt, td, txt2)
+			s.printf("BT %s (%s)Tj ET", f.textOrigin(bt, td), txt2)
 			//BT %.2F %.2F Td (%s) Tj ET',(f.x+dx)*k,(f.h-(f.y+.5*h+.3*f.FontSize))*k,txt2);
 		}
+		s.printf("%s", f.syntheticBoldEnd())
 
 		if f.underline {
 			s.printf(" %s", f.dounderline(f.x+dx, f.y+dy+.5*h+.3*f.fontSize, txtStr))
@@ -2608,6 +2715,16 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 		w = f.w - f.rMargin - f.x
 	}
 	wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
+	// Narrow each line to avoid exclusion regions
+	exclusion := f.exclusionActive()
+	left, right := f.x, f.x+w
+	lineSpan := func() {
+		if exclusion {
+			f.x, w = f.exclusionSpan(h, left, right, left)
+			wmax = int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
+		}
+	}
+	lineSpan()
 	s := strings.Replace(txtStr, "\r", "", -1)
 	srune := []rune(s)
 
@@ -2702,6 +2819,7 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			if len(borderStr) > 0 && nl == 2 {
 				b = b2
 			}
+			lineSpan()
 			continue
 		}
 		if c == ' ' || isChinese(c) {
@@ -2709,14 +2827,13 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			ls = l
 			ns++
 		}
-		if int(c) >= len(cw) {
+		if fw, ok := f.runeWidth(c); ok {
+			l += fw
+		} else if int(c) >= len(cw) {
 			f.err = fmt.Errorf("character outside the supported range: %s", string(c))
 			return
-		}
-		if cw[int(c)] == 0 { //Marker width 0 used for missing symbols
+		} else {
 			l += f.currentFont.Desc.MissingWidth
-		} else if cw[int(c)] != 65535 { //Marker width 65535 used for zero width symbols
-			l += cw[int(c)]
 		}
 		if l > wmax {
 			// Automatic line break
@@ -2757,6 +2874,7 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			if len(borderStr) > 0 && nl == 2 {
 				b = b2
 			}
+			lineSpan()
 		} else {
 			i++
 		}
@@ -2787,9 +2905,17 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 // write outputs text in flowing mode
 func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 	// dbg("Write")
-	cw := f.currentFont.Cw
 	w := f.w - f.rMargin - f.x
 	wmax := (w - 2*f.cMargin) * 1000 / f.fontSize
+	// Narrow each line to avoid exclusion regions
+	exclusion := f.exclusionActive()
+	lineSpan := func() {
+		if exclusion {
+			f.x, w = f.exclusionSpan(h, f.lMargin, f.w-f.rMargin, f.x)
+			wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
+		}
+	}
+	lineSpan()
 	s := strings.Replace(txtStr, "\r", "", -1)
 	var nb int
 	if f.isCurrentUTF8 {
@@ -2825,10 +2951,11 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 			sep = -1
 			j = i
 			l = 0.0
-			if nl == 1 {
+			if nl == 1 || exclusion {
 				f.x = f.lMargin
 				w = f.w - f.rMargin - f.x
 				wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
+				lineSpan()
 			}
 			nl++
 			continue
@@ -2836,7 +2963,9 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 		if c == ' ' {
 			sep = i
 		}
-		l += float64(cw[int(c)])
+		if fw, ok := f.runeWidth(c); ok {
+			l += float64(fw)
+		}
 		if l > wmax {
 			// Automatic line break
 			if sep == -1 {
@@ -2846,6 +2975,7 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 					f.y += h
 					w = f.w - f.rMargin - f.x
 					wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
+					lineSpan()
 					i++
 					nl++
 					continue
@@ -2869,10 +2999,11 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 			sep = -1
 			j = i
 			l = 0.0
-			if nl == 1 {
+			if nl == 1 || exclusion {
 				f.x = f.lMargin
 				w = f.w - f.rMargin - f.x
 				wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
+				lineSpan()
 			}
 			nl++
 		} else {
@@ -2999,44 +3130,102 @@ func (f *Fpdf) ImageTypeFromMime(mimeStr string) (tp string) {
 		tp = "jpg"
 	case "image/gif":
 		tp = "gif"
+	case "image/tiff":
+		tp = "tiff"
+	case "image/bmp":
+		tp = "bmp"
+	case "image/webp":
+		tp = "webp"
+	case "image/jp2":
+		tp = "jp2"
+	case "image/jpx":
+		tp = "jpx"
+	case "image/jbig2":
+		tp = "jbig2"
 	default:
 		f.SetErrorf("unsupported image type: %s", mimeStr)
 	}
 	return
 }
 
-func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, allowNegativeX, flow bool, link int, linkStr string) {
-	// Automatic width and height calculation if needed
+// imageSize returns the width and height, in user units, of an image that is
+// placed with the width and height arguments of ImageOptions().
+func (f *Fpdf) imageSize(info *ImageInfoType, w, h float64) (float64, float64) {
+	// Width and height are those of the image as it appears, which may be
+	// turned according to its orientation. The height relative to the
+	// width differs from that in pixels if the horizontal and vertical dpi
+	// differ.
+	pw, ph, dpi, dpiY := info.orientedSize()
+	ratio := ph / pw * dpi / dpiY
 	if w == 0 && h == 0 {
-		// Put image at 96 dpi
+		// Put image at the resolution read from the image file, if any, or
+		// at 96 dpi
 		w = -96
 		h = -96
+		if info.dpiRead {
+			w = -1
+			h = -1
+		} else if dpiY != dpi {
+			h = 0
+		}
 	}
 	if w == -1 {
 		// Set image width to whatever value for dpi we read
 		// from the image or that was set manually
-		w = -info.dpi
+		w = -dpi
 	}
 	if h == -1 {
 		// Set image height to whatever value for dpi we read
 		// from the image or that was set manually
-		h = -info.dpi
+		h = -dpiY
 	}
 	if w < 0 {
-		w = -info.w * 72.0 / w / f.k
+		w = -pw * 72.0 / w / f.k
 	}
 	if h < 0 {
-		h = -info.h * 72.0 / h / f.k
+		h = -ph * 72.0 / h / f.k
 	}
 	if w == 0 {
-		w = h * info.w / info.h
+		w = h / ratio
 	}
 	if h == 0 {
-		h = w * info.h / info.w
+		h = w * ratio
+	}
+	return w, h
+}
+
+func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, flow bool, options ImageOptions, link int, linkStr string) {
+	// With a fit mode, w and h specify the box in which the image is placed
+	fit := strings.ToLower(options.Fit)
+	boxW, boxH := w, h
+	switch fit {
+	case "":
+		// Automatic width and height calculation if needed
+		w, h = f.imageSize(info, w, h)
+		boxW, boxH = w, h
+	case "contain", "cover", "stretch", "none":
+		if w <= 0 || h <= 0 {
+			f.err = fmt.Errorf("image fit mode %s requires a box of positive width and height", options.Fit)
+			return
+		}
+		w, h = f.imageSize(info, 0, 0)
+		switch fit {
+		case "contain":
+			scale := math.Min(boxW/w, boxH/h)
+			w, h = w*scale, h*scale
+		case "cover":
+			scale := math.Max(boxW/w, boxH/h)
+			w, h = w*scale, h*scale
+		case "stretch":
+			w, h = boxW, boxH
+		}
+	default:
+		f.err = fmt.Errorf("unsupported image fit mode: %s", options.Fit)
+		return
 	}
 	// Flowing mode
 	if flow {
-		if f.y+h > f.pageBreakTrigger && !f.inHeader && !f.inFooter && f.acceptPageBreak() {
+		if f.y+boxH > f.pageBreakLimit() && !f.inHeader && !f.inFooter && f.columnAcceptPageBreak() {
 			// Automatic page break
 			x2 := f.x
 			f.AddPageFormat(f.curOrientation, f.curPageSize)
@@ -3046,18 +3235,58 @@ func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, allowNegativeX,
 			f.x = x2
 		}
 		y = f.y
-		f.y += h
+		f.y += boxH
 	}
-	if !allowNegativeX {
+	if !options.AllowNegativePosition {
 		if x < 0 {
 			x = f.x
 		}
 	}
+	info = f.applyImagePolicy(info, w, h)
+	if f.err != nil {
+		return
+	}
+	// Position of the image in its box
+	boxX, boxY := x, y
+	alignStr := strings.ToUpper(options.Align)
+	switch {
+	case strings.Contains(alignStr, "L"):
+	case strings.Contains(alignStr, "R"):
+		x += boxW - w
+	default:
+		x += (boxW - w) / 2
+	}
+	switch {
+	case strings.Contains(alignStr, "T"):
+	case strings.Contains(alignStr, "B"):
+		y += boxH - h
+	default:
+		y += (boxH - h) / 2
+	}
+	clip := true
+	switch {
+	case options.Circle:
+		f.ClipEllipse(boxX+boxW/2, boxY+boxH/2, boxW/2, boxH/2, false)
+	case options.CornerRadius > 0:
+		f.ClipRoundedRect(boxX, boxY, boxW, boxH, options.CornerRadius, false)
+	case w > boxW || h > boxH:
+		f.ClipRect(boxX, boxY, boxW, boxH, false)
+	default:
+		clip = false
+	}
 	// dbg("h %.2f", h)
 	// q 85.04 0 0 NaN 28.35 NaN cm /I2 Do Q
-	f.outf("q %.5f 0 0 %.5f %.5f %.5f cm /I%s Do Q", w*f.k, h*f.k, x*f.k, (f.h-(y+h))*f.k, info.i)
+	if info.orientation > 1 {
+		m := orientationMatrix(info.orientation, x*f.k, (f.h-(y+h))*f.k, w*f.k, h*f.k)
+		f.outf("q %.5f %.5f %.5f %.5f %.5f %.5f cm /I%s Do Q", m[0], m[1], m[2], m[3], m[4], m[5], info.i)
+	} else {
+		f.outf("q %.5f 0 0 %.5f %.5f %.5f cm /I%s Do Q", w*f.k, h*f.k, x*f.k, (f.h-(y+h))*f.k, info.i)
+	}
+	if clip {
+		f.ClipEnd()
+	}
 	if link > 0 || len(linkStr) > 0 {
-		f.newLink(x, y, w, h, link, linkStr)
+		f.newLink(boxX, boxY, boxW, boxH, link, linkStr)
 	}
 }
 
@@ -3073,12 +3302,16 @@ func (f *Fpdf) Image(imageNameStr string, x, y, w, h float64, flow bool, tp stri
 	f.ImageOptions(imageNameStr, x, y, w, h, flow, options, link, linkStr)
 }
 
-// ImageOptions puts a JPEG, PNG or GIF image in the current page. The size it
-// will take on the page can be specified in different ways. If both w and h
-// are 0, the image is rendered at 96 dpi. If either w or h is zero, it will be
-// calculated from the other dimension so that the aspect ratio is maintained.
-// If w and/or h are -1, the dpi for that dimension will be read from the
-// ImageInfoType object. PNG files can contain dpi information, and if present,
+// ImageOptions puts a JPEG, PNG, GIF, TIFF, BMP, WebP, JPEG 2000 or JBIG2
+// image in the current page. The size it will take on the page can be specified in different ways.
+// If both w and h are 0, a JPEG, TIFF or JBIG2 image is rendered at the
+// resolution that was read from the image file if options.ReadDpi is set and
+// the file specifies it; other images, including PNG images with a
+// resolution, are rendered at 96 dpi. If either w or h is
+// zero, it will be calculated from the other dimension so that the aspect
+// ratio is maintained. If w and/or h are -1, the dpi for that dimension will
+// be read from the ImageInfoType object. PNG, JPEG, TIFF and JBIG2 files can
+// contain dpi information, and if present,
 // this information will be populated in the ImageInfoType object and used in
 // Width, Height, and Extent calculations. Otherwise, the SetDpi function can
 // be used to change the dpi from the default of 72.
@@ -3086,8 +3319,22 @@ func (f *Fpdf) Image(imageNameStr string, x, y, w, h float64, flow bool, tp stri
 // If w and h are any other negative value, their absolute values
 // indicate their dpi extents.
 //
-// Supported JPEG formats are 24 bit, 32 bit and gray scale. Supported PNG
-// formats are 24 bit, indexed color, and 8 bit indexed gray scale. If a GIF
+// If options.Fit is set, w and h instead specify a box of which (x, y) is the
+// upper left corner. The image is scaled to fit or cover the box, positioned
+// in it as specified by options.Align and clipped to it. The image can also be
+// clipped to a box with rounded corners or to an ellipse; see ImageOptions.
+//
+// Supported JPEG formats are 24 bit, 32 bit and gray scale. JPEG images are
+// turned or mirrored as specified by their EXIF orientation, in which case
+// w and h are the dimensions of the image as it appears. CMYK JPEG images
+// with an Adobe marker, which hold inverted values, are inverted. PNG images of
+// all color types and bit depths are supported, including interlaced images;
+// images with 16 bits per sample are embedded at full precision unless the
+// Reduce16Bit option is set. Bilevel TIFF images compressed with CCITT
+// Group 3 or Group 4 encoding are embedded without being decoded; other TIFF
+// images, and BMP and WebP images, are decoded and compressed again. JPEG 2000
+// images, either JP2 files or bare codestreams, and JBIG2 images are embedded
+// without being decoded; only their headers are read. If a GIF
 // image is animated, only the first frame is rendered. Transparency is
 // supported. It is possible to put a link on the image.
 //
@@ -3117,7 +3364,7 @@ func (f *Fpdf) ImageOptions(imageNameStr string, x, y, w, h float64, flow bool,
 	if f.err != nil {
 		return
 	}
-	f.imageOut(info, x, y, w, h, options.AllowNegativePosition, flow, link, linkStr)
+	f.imageOut(info, x, y, w, h, flow, options, link, linkStr)
 	return
 }
 
@@ -3137,21 +3384,90 @@ func (f *Fpdf) RegisterImageReader(imgName, tp string, r io.Reader) (info *Image
 // parsing an image.
 //
 // ImageType's possible values are (case insensitive):
-// "JPG", "JPEG", "PNG" and "GIF". If empty, the type is inferred from
+// "JPG", "JPEG", "PNG", "GIF", "TIFF", "TIF", "BMP", "WEBP", "JP2", "JPX",
+// "JPF", "J2K", "J2C", "JBIG2" and "JB2". If empty, the type is inferred from
 // the file extension.
 //
 // ReadDpi defines whether to attempt to automatically read the image
-// dpi information from the image file. Normally, this should be set
+// dpi information from the image file. PNG, JPEG, TIFF and JBIG2 images may
+// contain it; the horizontal and vertical resolutions of a JPEG, TIFF or JBIG2
+// image, such as a fax page, may differ. Normally, this should be set
 // to true (understanding that not all images will have this info
 // available). However, for backwards compatibility with previous
-// versions of the API, it defaults to false.
+// versions of the API, it defaults to false. The resolution that is read
+// from a JPEG, TIFF or JBIG2 image also sets its size when it is placed with
+// a width and height of zero; see ImageOptions().
 //
 // AllowNegativePosition can be set to true in order to prevent the default
 // coercion of negative x values to the current x position.
+//
+// Reduce16Bit converts PNG images with 16 bits per sample to 8 bits. By
+// default such images are embedded with their full precision, which requires
+// PDF version 1.5.
+//
+// JPEGQuality, if greater than zero, makes RegisterImageFromImage() encode
+// gray and RGB images with the DCT (JPEG) filter at this quality, from 1 to
+// 100, rather than losslessly with the Flate filter. TIFF, BMP and WebP images
+// that are decoded when registered are encoded the same way.
+//
+// Page selects the page of a multi-page TIFF or JBIG2 image, starting with 1. Zero
+// selects the first page. When an image file is registered by name, pages
+// other than the first are registered as the file name followed by "#" and
+// the page number, for example "scan.tif#2".
+//
+// Fit, if not empty, makes the w and h arguments of ImageOptions() specify a
+// box in which the image is placed, rather than the size of the image. Both
+// must be positive. Its possible values are (case insensitive): "contain",
+// which scales the image to the largest size that fits in the box; "cover",
+// which scales the image to the smallest size that covers the box, cropping
+// what extends beyond it; "stretch", which fills the box without keeping the
+// aspect ratio; and "none", which keeps the size the image would have if w
+// and h were zero, cropping it if it is larger than the box.
+//
+// Align specifies the position of the image in its box when the two differ
+// in size. It combines "L", "C" or "R" for the horizontal position with "T",
+// "M" or "B" for the vertical one, as in "LT" or "RB"; the image is centered
+// in either direction that is not specified.
+//
+// CornerRadius, if greater than zero, clips the image to its box with corners
+// rounded by this radius. Circle clips the image to the ellipse inscribed in
+// its box, which is a circle if the box is square. Without a fit mode, the
+// box is the area of the image itself.
+//
+// The following masks apply when the image is registered, so an image that is
+// used both with and without them needs to be registered under two names, for
+// example with RegisterImageOptionsReader().
+//
+// SoftMask names a registered image, or an image file that is then registered,
+// whose gray levels specify the opacity of the image: black is transparent
+// and white opaque. It may differ in size from the image, and replaces any
+// alpha channel or color key mask the image has, including a transparent
+// color of a GIF or PNG image. A color mask image is converted to gray.
+//
+// StencilMask turns the image into a stencil mask, which is painted in the
+// fill color current when it is placed, like a glyph of a font. Bilevel gray
+// images paint where their pixels are black; other images are converted, so
+// that their dark pixels that are not transparent are painted.
+//
+// ColorKeyMin and ColorKeyMax, if either is not nil, specify a range of
+// colors that are masked: pixels whose components all lie between those of
+// the two colors are not painted. If only one of them is set, pixels of that
+// color are masked. Color key masks apply to gray, RGB and CMYK images.
 type ImageOptions struct {
 	ImageType             string
 	ReadDpi               bool
 	AllowNegativePosition bool
+	Reduce16Bit           bool
+	JPEGQuality           int
+	Page                  int
+	Fit                   string
+	Align                 string
+	CornerRadius          float64
+	Circle                bool
+	SoftMask              string
+	StencilMask           bool
+	ColorKeyMin           color.Color
+	ColorKeyMax           color.Color
 }
 
 // RegisterImageOptionsReader registers an image, reading it from Reader r, adding it
@@ -3181,17 +3497,31 @@ func (f *Fpdf) RegisterImageOptionsReader(imgName string, options ImageOptions,
 	}
 	switch options.ImageType {
 	case "jpg":
-		info = f.parsejpg(r)
+		info = f.parsejpg(r, options)
 	case "png":
-		info = f.parsepng(r, options.ReadDpi)
+		info = f.parsepng(r, options)
 	case "gif":
 		info = f.parsegif(r)
+	case "tiff", "tif":
+		info = f.parsetiff(r, options)
+	case "bmp":
+		info = f.parsebmp(r, options)
+	case "webp":
+		info = f.parsewebp(r, options)
+	case "jp2", "jpx", "jpf", "j2k", "j2c":
+		info = f.parsejpx(r)
+	case "jbig2", "jb2":
+		info = f.parsejbig2(r, nil, options)
 	default:
 		f.err = fmt.Errorf("unsupported image type: %s", options.ImageType)
 	}
 	if f.err != nil {
 		return
 	}
+	f.applyImageMasks(info, options)
+	if f.err != nil {
+		return
+	}
 
 	if info.i, f.err = generateImageID(info); f.err != nil {
 		return
@@ -3222,7 +3552,13 @@ func (f *Fpdf) RegisterImage(fileStr, tp string) (info *ImageInfoType) {
 // necessary if you need information about the image before placing it. See
 // Image() for restrictions on the image and the "tp" parameters.
 func (f *Fpdf) RegisterImageOptions(fileStr string, options ImageOptions) (info *ImageInfoType) {
-	info, ok := f.images[fileStr]
+	// Pages of a multi-page image other than the first are registered under
+	// a name of their own
+	nameStr := fileStr
+	if options.Page > 1 {
+		nameStr = sprintf("%s#%d", fileStr, options.Page)
+	}
+	info, ok := f.images[nameStr]
 	if ok {
 		return
 	}
@@ -3244,7 +3580,7 @@ func (f *Fpdf) RegisterImageOptions(fileStr string, options ImageOptions) (info
 		options.ImageType = fileStr[pos+1:]
 	}
 
-	return f.RegisterImageOptionsReader(fileStr, options, file)
+	return f.RegisterImageOptionsReader(nameStr, options, file)
 }
 
 // GetImageInfo returns information about the registered image specified by
@@ -3645,9 +3981,10 @@ func (f *Fpdf) newImageInfo() *ImageInfoType {
 	return &ImageInfoType{scale: f.k, dpi: 72}
 }
 
-// parsejpg extracts info from io.Reader with JPEG data
+// parsejpg extracts info from io.Reader with JPEG data, including the
+// orientation, Adobe marker and resolution found in its application segments.
 // Thank you, Bruno Michel, for providing this code.
-func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
+func (f *Fpdf) parsejpg(r io.Reader, options ImageOptions) (info *ImageInfoType) {
 	info = f.newImageInfo()
 	var (
 		data bytes.Buffer
@@ -3680,17 +4017,28 @@ func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
 		f.err = fmt.Errorf("image JPEG buffer has unsupported color space (%v)", config.ColorModel)
 		return
 	}
+	meta := jpegMetadata(info.data)
+	if meta.adobe && info.cs == "DeviceCMYK" {
+		// Adobe applications write CMYK data inverted
+		info.decode = "1 0 1 0 1 0 1 0"
+	}
+	info.orientation = meta.orientation
+	if options.ReadDpi && meta.dpi > 0 {
+		info.dpi = meta.dpi
+		info.dpiY = meta.dpiY
+		info.dpiRead = true
+	}
 	return
 }
 
 // parsepng extracts info from a PNG data
-func (f *Fpdf) parsepng(r io.Reader, readdpi bool) (info *ImageInfoType) {
+func (f *Fpdf) parsepng(r io.Reader, options ImageOptions) (info *ImageInfoType) {
 	buf, err := bufferFromReader(r)
 	if err != nil {
 		f.err = err
 		return
 	}
-	return f.parsepngstream(buf, readdpi)
+	return f.parsepngstream(buf, options)
 }
 
 func (f *Fpdf) readBeInt32(r io.Reader) (val int32) {
@@ -3728,7 +4076,7 @@ func (f *Fpdf) parsegif(r io.Reader) (info *ImageInfoType) {
 		f.err = err
 		return
 	}
-	return f.parsepngstream(pngBuf, false)
+	return f.parsepngstream(pngBuf, ImageOptions{})
 }
 
 // newobj begins a new object
@@ -3883,6 +4231,7 @@ func (f *Fpdf) putpages() {
 		// Replace number of pages
 		f.RegisterAlias(f.aliasNbPagesStr, sprintf("%d", nb))
 	}
+	f.sectionReplaceAliases()
 	f.replaceAliases()
 	if f.defOrientation == "P" {
 		wPt = f.defPageSize.Wd * f.k
@@ -4108,33 +4457,60 @@ func (f *Fpdf) putfonts() {
 				fontName := "utf8" + font.Name
 				usedRunes := font.usedRunes
 				delete(usedRunes, 0)
-				utf8FontStream := font.utf8File.GenerateCutFont(usedRunes)
+				utf8FontStream, err := font.utf8File.GenerateCutFont(usedRunes)
+				if err != nil {
+					f.err = fmt.Errorf("unable to generate subset of font %s: %v", font.Name, err)
+					return
+				}
+				isCFF := font.utf8File.isCFF
 				utf8FontSize := len(utf8FontStream)
 				compressedFontStream := sliceCompress(utf8FontStream)
 				CodeSignDictionary := font.utf8File.CodeSymbolDictionary
 				delete(CodeSignDictionary, 0)
+				_, vertical := f.verticalFonts[font.i]
+				cmapStr := toUnicode
+				if vertical {
+					cmapStr = font.utf8File.vertical.toUnicode()
+				}
 
 				f.newobj()
 				f.out(fmt.Sprintf("<</Type /Font\n/Subtype /Type0\n/BaseFont /%s\n/Encoding /Identity-H\n/DescendantFonts [%d 0 R]\n/ToUnicode %d 0 R>>\n"+"endobj", fontName, f.n+1, f.n+2))
 
+				// Fonts with PostScript outlines are CID-keyed, so CIDs are mapped
+				// to glyphs by the font program rather than by a CIDToGIDMap
+				cidFontType := "CIDFontType2"
+				if isCFF {
+					cidFontType = "CIDFontType0"
+				}
 				f.newobj()
-				f.out("<</Type /Font\n/Subtype /CIDFontType2\n/BaseFont /" + fontName + "\n" +
+				f.out("<</Type /Font\n/Subtype /" + cidFontType + "\n/BaseFont /" + fontName + "\n" +
 					"/CIDSystemInfo " + strconv.Itoa(f.n+2) + " 0 R\n/FontDescriptor " + strconv.Itoa(f.n+3) + " 0 R")
 				if font.Desc.MissingWidth != 0 {
 					f.out("/DW " + strconv.Itoa(font.Desc.MissingWidth) + "")
 				}
 				f.generateCIDFontMap(&font, font.utf8File.LastRune)
-				f.out("/CIDToGIDMap " + strconv.Itoa(f.n+4) + " 0 R>>")
+				if vertical {
+					f.out(verticalWidths(&font))
+				}
+				if isCFF {
+					f.out(">>")
+				} else {
+					f.out("/CIDToGIDMap " + strconv.Itoa(f.n+4) + " 0 R>>")
+				}
 				f.out("endobj")
 
 				f.newobj()
-				f.out("<</Length " + strconv.Itoa(len(toUnicode)) + ">>")
-				f.putstream([]byte(toUnicode))
+				f.out("<</Length " + strconv.Itoa(len(cmapStr)) + ">>")
+				f.putstream([]byte(cmapStr))
 				f.out("endobj")
 
 				// CIDInfo
 				f.newobj()
-				f.out("<</Registry (Adobe)\n/Ordering (UCS)\n/Supplement 0>>")
+				if isCFF {
+					f.out("<</Registry (Adobe)\n/Ordering (Identity)\n/Supplement 0>>")
+				} else {
+					f.out("<</Registry (Adobe)\n/Ordering (UCS)\n/Supplement 0>>")
+				}
 				f.out("endobj")
 
 				// Font descriptor
@@ -4152,33 +4528,52 @@ func (f *Fpdf) putfonts() {
 				s.printf(" /ItalicAngle %d", font.Desc.ItalicAngle)
 				s.printf(" /StemV %d", font.Desc.StemV)
 				s.printf(" /MissingWidth %d", font.Desc.MissingWidth)
-				s.printf("/FontFile2 %d 0 R", f.n+2)
+				if isCFF {
+					s.printf("/FontFile3 %d 0 R", f.n+1)
+				} else {
+					s.printf("/FontFile2 %d 0 R", f.n+2)
+				}
 				s.printf(">>")
 				f.out(s.String())
 				f.out("endobj")
 
-				// Embed CIDToGIDMap
-				cidToGidMap := make([]byte, 256*256*2)
+				if !isCFF {
+					// Embed CIDToGIDMap
+					cidToGidMap := make([]byte, 256*256*2)
 
-				for cc, glyph := range CodeSignDictionary {
-					cidToGidMap[cc*2] = byte(glyph >> 8)
-					cidToGidMap[cc*2+1] = byte(glyph & 0xFF)
-				}
+					for cc, glyph := range CodeSignDictionary {
+						cidToGidMap[cc*2] = byte(glyph >> 8)
+						cidToGidMap[cc*2+1] = byte(glyph & 0xFF)
+					}
 
-				cidToGidMap = sliceCompress(cidToGidMap)
-				f.newobj()
-				f.out("<</Length " + strconv.Itoa(len(cidToGidMap)) + "/Filter /FlateDecode>>")
-				f.putstream(cidToGidMap)
-				f.out("endobj")
+					cidToGidMap = sliceCompress(cidToGidMap)
+					f.newobj()
+					f.out("<</Length " + strconv.Itoa(len(cidToGidMap)) + "/Filter /FlateDecode>>")
+					f.putstream(cidToGidMap)
+					f.out("endobj")
+				}
 
 				//Font file
 				f.newobj()
 				f.out("<</Length " + strconv.Itoa(len(compressedFontStream)))
 				f.out("/Filter /FlateDecode")
-				f.out("/Length1 " + strconv.Itoa(utf8FontSize))
+				if isCFF {
+					f.out("/Subtype /OpenType")
+				} else {
+					f.out("/Length1 " + strconv.Itoa(utf8FontSize))
+				}
 				f.out(">>")
 				f.putstream(compressedFontStream)
 				f.out("endobj")
+
+				if vertical {
+					// Font for vertical writing, sharing the CIDFont
+					f.newobj()
+					f.verticalFonts[font.i] = f.n
+					f.out(fmt.Sprintf("<</Type /Font\n/Subtype /Type0\n/BaseFont /%s\n/Encoding /Identity-V\n/DescendantFonts [%d 0 R]\n/ToUnicode %d 0 R>>\n"+"endobj", fontName, font.N+1, font.N+2))
+				}
+			case "CJK":
+				f.putCJKFont(font)
 			default:
 				f.err = fmt.Errorf("unsupported font type: %s", tp)
 				return
@@ -4375,36 +4770,69 @@ func (f *Fpdf) putimages() {
 }
 
 func (f *Fpdf) putimage(info *ImageInfoType) {
+	if len(info.strips) > 0 {
+		f.putimageStrips(info)
+		return
+	}
 	f.newobj()
 	info.n = f.n
+	// The soft mask, the JBIG2 global segments and the palette are written
+	// after the image, in that order
+	smaskN := f.n + 1
+	globalsN := smaskN
+	if len(info.smask) > 0 {
+		globalsN++
+	} else if info.smaskImage != nil {
+		globalsN += imageObjectCount(info.smaskImage)
+	}
+	palN := globalsN
+	if len(info.globals) > 0 {
+		palN++
+	}
 	f.out("<</Type /XObject")
 	f.out("/Subtype /Image")
 	f.outf("/Width %d", int(info.w))
 	f.outf("/Height %d", int(info.h))
-	if info.cs == "Indexed" {
-		f.outf("/ColorSpace [/Indexed /DeviceRGB %d %d 0 R]", len(info.pal)/3-1, f.n+1)
-	} else {
+	if info.stencil {
+		f.out("/ImageMask true")
+	} else if info.cs == "Indexed" {
+		f.outf("/ColorSpace [/Indexed /DeviceRGB %d %d 0 R]", len(info.pal)/3-1, palN)
+	} else if info.cs != "" {
+		// A JPEG 2000 image without a color space uses the one it specifies
 		f.outf("/ColorSpace /%s", info.cs)
-		if info.cs == "DeviceCMYK" {
-			f.out("/Decode [1 0 1 0 1 0 1 0]")
-		}
 	}
-	f.outf("/BitsPerComponent %d", info.bpc)
+	if len(info.decode) > 0 {
+		f.outf("/Decode [%s]", info.decode)
+	}
+	if info.f != "JPXDecode" {
+		f.outf("/BitsPerComponent %d", info.bpc)
+	}
 	if len(info.f) > 0 {
 		f.outf("/Filter /%s", info.f)
 	}
 	if len(info.dp) > 0 {
 		f.outf("/DecodeParms <<%s>>", info.dp)
+	} else if len(info.globals) > 0 {
+		f.outf("/DecodeParms <</JBIG2Globals %d 0 R>>", globalsN)
 	}
-	if len(info.trns) > 0 {
+	if info.smaskInData > 0 {
+		f.outf("/SMaskInData %d", info.smaskInData)
+	}
+	if len(info.colorKey) > 0 {
+		var key fmtBuffer
+		for _, v := range info.colorKey {
+			key.printf("%d ", v)
+		}
+		f.outf("/Mask [%s]", key.String())
+	} else if len(info.trns) > 0 {
 		var trns fmtBuffer
 		for _, v := range info.trns {
 			trns.printf("%d %d ", v, v)
 		}
 		f.outf("/Mask [%s]", trns.String())
 	}
-	if info.smask != nil {
-		f.outf("/SMask %d 0 R", f.n+1)
+	if len(info.smask) > 0 || info.smaskImage != nil {
+		f.outf("/SMask %d 0 R", smaskN)
 	}
 	f.outf("/Length %d>>", len(info.data))
 	f.putstream(info.data)
@@ -4415,13 +4843,25 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 			w:     info.w,
 			h:     info.h,
 			cs:    "DeviceGray",
-			bpc:   8,
-			f:     info.f,
-			dp:    sprintf("/Predictor 15 /Colors 1 /BitsPerComponent 8 /Columns %d", int(info.w)),
+			bpc:   info.bpc,
+			f:     "FlateDecode",
+			dp:    sprintf("/Predictor 15 /Colors 1 /BitsPerComponent %d /Columns %d", info.bpc, int(info.w)),
 			data:  info.smask,
 			scale: f.k,
 		}
 		f.putimage(smask)
+	} else if info.smaskImage != nil {
+		// A copy, so that the object number of the registered mask image is
+		// not changed
+		smask := *info.smaskImage
+		f.putimage(&smask)
+	}
+	// 	JBIG2 global segments
+	if len(info.globals) > 0 {
+		f.newobj()
+		f.outf("<</Length %d>>", len(info.globals))
+		f.putstream(info.globals)
+		f.out("endobj")
 	}
 	// 	Palette
 	if info.cs == "Indexed" {
@@ -4438,6 +4878,24 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 	}
 }
 
+// imageObjectCount returns the number of objects that putimage() writes for
+// an image that is not divided into strips.
+func imageObjectCount(info *ImageInfoType) (count int) {
+	count = 1
+	if len(info.smask) > 0 {
+		count++
+	} else if info.smaskImage != nil {
+		count += imageObjectCount(info.smaskImage)
+	}
+	if len(info.globals) > 0 {
+		count++
+	}
+	if info.cs == "Indexed" {
+		count++
+	}
+	return
+}
+
 func (f *Fpdf) putxobjectdict() {
 	{
 		var image *ImageInfoType
@@ -4492,6 +4950,9 @@ func (f *Fpdf) putresourcedict() {
 		for _, key = range keyList {
 			font = f.fonts[key]
 			f.outf("/F%s %d 0 R", font.i, font.N)
+			if n := f.verticalFonts[font.i]; n > 0 {
+				f.outf("/F%sV %d 0 R", font.i, n)
+			}
 		}
 	}
 	f.out(">>")
@@ -4514,6 +4975,14 @@ func (f *Fpdf) putresourcedict() {
 		}
 		f.out(">>")
 	}
+	count = len(f.patternList)
+	if count > 1 {
+		f.out("/Pattern <<")
+		for j := 1; j < count; j++ {
+			f.outf("/P%d %d 0 R", j, f.patternList[j].objNum)
+		}
+		f.out(">>")
+	}
 	// Layers
 	f.layerPutResourceDict()
 	f.spotColorPutResourceDict()
@@ -4589,6 +5058,7 @@ func (f *Fpdf) putresources() {
 	}
 	f.putimages()
 	f.putTemplates()
+	f.putPatterns()
 	f.putImportedTemplates() // gofpdi
 	// 	Resource dictionary
 	f.offsets[2] = f.buffer.Len()
@@ -4680,6 +5150,8 @@ func (f *Fpdf) putcatalog() {
 		f.outf("/Outlines %d 0 R", f.outlineRoot)
 		f.out("/PageMode /UseOutlines")
 	}
+	// Page labels
+	f.sectionPutCatalog()
 	// Layers
 	f.layerPutCatalog()
 	// Name dictionary :
@@ -4699,6 +5171,12 @@ func (f *Fpdf) putheader() {
 	if len(f.blendMap) > 0 && f.pdfVersion < "1.4" {
 		f.pdfVersion = "1.4"
 	}
+	// OpenType font programs are embedded as of PDF 1.6
+	for _, font := range f.fonts {
+		if font.utf8File != nil && font.utf8File.isCFF && f.pdfVersion < "1.6" {
+			f.pdfVersion = "1.6"
+		}
+	}
 	f.outf("%%PDF-%s", f.pdfVersion)
 }
 
@@ -20,6 +20,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"image"
+	"image/color"
 	"io"
 	"io/ioutil"
 	"math"
@@ -2922,3 +2924,977 @@ func ExampleFpdf_SetModificationDate() {
 	// Output:
 	// Successfully generated pdf/Fpdf_SetModificationDate.pdf
 }
+
+// ExampleFpdf_AddSection demonstrates sections with independent headers,
+// footers, page numbering and page labels. Viewers that support page labels
+// display the front matter pages as "i" and "ii" and the chapter pages as
+// "A-1" through "A-3".
+func ExampleFpdf_AddSection() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Arial", "", 12)
+	footer := func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %s of {snb}", pdf.SectionPageLabel()),
+			"", 0, "C", false, 0, "")
+	}
+	pdf.AddSection(gofpdf.SectionType{
+		LabelStyle:      "r",
+		FooterFnc:       footer,
+		AliasNbPagesStr: "{snb}",
+	})
+	pdf.Cell(40, 10, "Preface")
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Contents")
+	pdf.AddSection(gofpdf.SectionType{
+		OrientationStr: "L",
+		TopMargin:      25,
+		LabelStyle:     "D",
+		LabelPrefix:    "A-",
+		HeaderFnc: func() {
+			pdf.SetFont("Arial", "B", 10)
+			pdf.CellFormat(0, 10, "Appendix A (odd page)", "B", 1, "R", false, 0, "")
+		},
+		HeaderFirstFnc: func() {},
+		HeaderEvenFnc: func() {
+			pdf.SetFont("Arial", "B", 10)
+			pdf.CellFormat(0, 10, "Appendix A (even page)", "B", 1, "L", false, 0, "")
+		},
+		FooterFnc:       footer,
+		AliasNbPagesStr: "{snb}",
+	})
+	for j := 0; j < 3; j++ {
+		if j > 0 {
+			pdf.AddPage()
+		}
+		pdf.Cell(40, 10, fmt.Sprintf("Section %d, page %d", pdf.SectionNo(), pdf.SectionPageNo()))
+	}
+	fileStr := example.Filename("Fpdf_AddSection")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddSection.pdf
+}
+
+// ExampleFpdf_WriteFootnote demonstrates footnotes written inline with
+// Write(), embedded in basic HTML and referenced from MultiCell() text. Space
+// for the notes is reserved at the bottom of each page and a long note is
+// continued on the following page. Notes added in endnote mode are collected
+// and written at the end of the document.
+func ExampleFpdf_WriteFootnote() {
+	pdf := gofpdf.New("P", "mm", "A5", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.SetFootnoteFormat(gofpdf.FootnoteFormatType{FontSize: 9})
+	pdf.AddPage()
+	pdf.Write(6, "Footnotes are numbered in the order in which they appear")
+	pdf.WriteFootnote(6, "This note is placed at the bottom of the page.")
+	pdf.Write(6, ". ")
+	html := pdf.HTMLBasicNew()
+	html.Write(6, "Notes can also be added to <b>rich text</b><footnote>This note "+
+		"is embedded in basic HTML.</footnote>. ")
+	markStr := pdf.AddFootnote("This note is referenced from text rendered with MultiCell().")
+	pdf.Ln(8)
+	pdf.MultiCell(0, 6, "A marker can be included in any text ["+markStr+"].", "", "L", false)
+	for j := 0; j < 18; j++ {
+		pdf.MultiCell(0, 6, lorem(), "", "J", false)
+		if j == 2 {
+			pdf.WriteFootnote(6, strings.Repeat(lorem()+" ", 4))
+			pdf.Ln(6)
+		}
+	}
+	pdf.SetEndnoteMode(true)
+	pdf.Write(6, "Endnotes are collected")
+	pdf.WriteFootnote(6, "This is the first endnote.")
+	pdf.Write(6, " and written later")
+	pdf.WriteFootnote(6, "This is the second endnote.")
+	pdf.Ln(12)
+	pdf.SetFont("Times", "B", 12)
+	pdf.Cell(0, 6, "Notes")
+	pdf.Ln(8)
+	pdf.SetFont("Times", "", 10)
+	pdf.WriteEndnotes(5)
+	fileStr := example.Filename("Fpdf_WriteFootnote")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_WriteFootnote.pdf
+}
+
+// ExampleFpdf_ColumnsBegin demonstrates multi-column text flow. Text written
+// with MultiCell() and images placed in flowing mode continue in the next
+// column when the bottom of a column is reached. The final block of text is
+// rendered with ColumnsBalanced() so that its columns end at the same height.
+func ExampleFpdf_ColumnsBegin() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 11)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.CellFormat(0, 10, "Newsletter", "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Times", "", 11)
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.ColumnsBegin(gofpdf.ColumnLayoutType{Count: 3, Gutter: 6, Rule: true})
+	for j := 0; j < 10; j++ {
+		pdf.SetFont("Arial", "B", 11)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Column %d, story %d", pdf.ColumnNo(), j+1), "", 1, "L", false, 0, "")
+		pdf.SetFont("Times", "", 11)
+		pdf.MultiCell(0, 5, lorem(), "", "J", false)
+		if j == 4 {
+			pdf.ImageOptions(example.ImageFile("logo.png"), -1, -1, 30, 0, true,
+				gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+		}
+		pdf.Ln(2)
+	}
+	pdf.ColumnsEnd()
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Balanced columns", "", 1, "L", false, 0, "")
+	pdf.SetFont("Times", "", 11)
+	pdf.ColumnsBalanced(gofpdf.ColumnLayoutType{Count: 2, Gutter: 8}, func() {
+		for j := 0; j < 3; j++ {
+			pdf.MultiCell(0, 5, lorem(), "", "J", false)
+		}
+	})
+	pdf.CellFormat(0, 8, "Text after the balanced columns", "T", 1, "C", false, 0, "")
+	fileStr := example.Filename("Fpdf_ColumnsBegin")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ColumnsBegin.pdf
+}
+
+// ExampleFpdf_TextFrameNew demonstrates text that flows through a chain of
+// rectangles located on different pages. Plain text and basic HTML are
+// supported, and text that does not fit in the last rectangle is returned to
+// the caller.
+func ExampleFpdf_TextFrameNew() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 11)
+	for j := 0; j < 3; j++ {
+		pdf.AddPage()
+	}
+	boxes := []gofpdf.TextFrameBoxType{
+		{Page: 1, X: 20, Y: 40, Wd: 80, Ht: 60},
+		{Page: 1, X: 110, Y: 120, Wd: 80, Ht: 40},
+		{Page: 3, X: 60, Y: 30, Wd: 90, Ht: 50},
+	}
+	pdf.SetDrawColor(200, 200, 200)
+	for _, b := range boxes {
+		pdf.SetPage(b.Page)
+		pdf.Rect(b.X, b.Y, b.Wd, b.Ht, "D")
+	}
+	pdf.SetPage(3)
+	tf := pdf.TextFrameNew(boxes...)
+	overflowStr := tf.Write(5, lorem()+"\n"+lorem(), "J")
+	if overflowStr != "" {
+		pdf.SetError(fmt.Errorf("unexpected overflow"))
+	}
+	overflowStr = tf.WriteHTML(5, strings.Repeat("Text frames accept <b>bold</b>, <i>italic</i> "+
+		"and <u>underscored</u> text as well as <a href=\"https://github.com/jung-kurt/gofpdf\">links</a>. ", 12), "L")
+	pdf.SetXY(20, 100)
+	pdf.SetFont("Arial", "", 9)
+	pdf.MultiCell(0, 5, fmt.Sprintf("Frame full: %v, overflow: %d bytes", tf.Full(), len(overflowStr)), "", "L", false)
+	fileStr := example.Filename("Fpdf_TextFrameNew")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_TextFrameNew.pdf
+}
+
+// ExampleFpdf_AddExclusionRect demonstrates text that flows around images
+// and shapes. An exclusion region is added for each floated object, and
+// MultiCell(), Write() and basic HTML narrow their lines to avoid it.
+func ExampleFpdf_AddExclusionRect() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.AddPage()
+	// Image floated to the right of the first paragraphs
+	pdf.ImageOptions(example.ImageFile("logo.png"), 140, 15, 50, 0, false,
+		gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.AddExclusionRect(140, 15, 50, 50, 3)
+	for j := 0; j < 2; j++ {
+		pdf.MultiCell(0, 5, lorem(), "", "J", false)
+		pdf.Ln(2)
+	}
+	// Circle floated to the left of text written with Write()
+	y := pdf.GetY() + 5
+	pdf.SetFillColor(200, 220, 255)
+	pdf.Circle(35, y+20, 20, "F")
+	pdf.AddExclusionCircle(35, y+20, 20, 3)
+	pdf.Write(5, lorem()+" "+lorem())
+	pdf.Ln(10)
+	// Triangle in the middle of basic HTML; text uses the wider side
+	y = pdf.GetY()
+	points := []gofpdf.PointType{{X: 80, Y: y}, {X: 120, Y: y + 40}, {X: 60, Y: y + 40}}
+	pdf.Polygon(points, "F")
+	pdf.AddExclusionPolygon(points, 2)
+	html := pdf.HTMLBasicNew()
+	html.Write(5, strings.Repeat("Exclusion regions are respected by <b>rich text</b> "+
+		"written with <i>HTMLBasicType</i>. ", 8))
+	fileStr := example.Filename("Fpdf_AddExclusionRect")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddExclusionRect.pdf
+}
+
+// ExampleFpdf_AddUTF8Font_openType demonstrates the use of an OpenType font
+// with PostScript (CFF) outlines. Only the glyphs of the runes that are used
+// in the document are embedded.
+func ExampleFpdf_AddUTF8Font_openType() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("cfftest", "", example.FontFile("CFFTest.otf"))
+	pdf.AddPage()
+	pdf.SetFont("cfftest", "", 48)
+	pdf.Cell(0, 20, "0110Q中")
+	pdf.Ln(20)
+	pdf.SetFont("cfftest", "", 24)
+	pdf.Cell(0, 12, "10Q01")
+	fileStr := example.Filename("Fpdf_AddUTF8Font_openType")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddUTF8Font_openType.pdf
+}
+
+// ExampleFpdf_AddUTF8FontFace demonstrates the selection of faces from a font
+// collection, first by index and then by PostScript name.
+func ExampleFpdf_AddUTF8FontFace() {
+	fontFileStr := example.FontFile("FontCollection.ttc")
+	faces, err := gofpdf.FontCollectionFaces(fontFileStr)
+	if err == nil {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddUTF8FontFace("calligra", "", fontFileStr, 0)
+		pdf.AddUTF8FontFaceByName("cfftest", "", fontFileStr, faces[1].PostScriptName)
+		pdf.AddPage()
+		for _, face := range faces {
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.CellFormat(0, 8, fmt.Sprintf("Face %d: %s (%s %s)", face.Index,
+				face.PostScriptName, face.FamilyName, face.SubfamilyName), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+		pdf.SetFont("calligra", "", 24)
+		pdf.CellFormat(0, 12, "Face selected by index", "", 1, "L", false, 0, "")
+		pdf.SetFont("cfftest", "", 24)
+		pdf.CellFormat(0, 12, "0110Q", "", 1, "L", false, 0, "")
+		fileStr := example.Filename("Fpdf_AddUTF8FontFace")
+		err = pdf.OutputFileAndClose(fileStr)
+		example.Summary(err, fileStr)
+	} else {
+		fmt.Println(err)
+	}
+	// Output:
+	// Successfully generated pdf/Fpdf_AddUTF8FontFace.pdf
+}
+
+// ExampleFpdf_SetFontFallback demonstrates text that contains characters
+// missing from the current font. They are drawn with the first fallback font
+// that contains them.
+func ExampleFpdf_SetFontFallback() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("calligra", "", example.FontFile("calligra.ttf"))
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("cfftest", "", example.FontFile("CFFTest.otf"))
+	pdf.SetFontFallback("calligra", []string{"dejavu", "cfftest"})
+	pdf.AddPage()
+	pdf.SetFont("calligra", "", 16)
+	txtStr := "Product names like Αλφα-中 or Ωmega mix scripts."
+	pdf.CellFormat(0, 10, txtStr, "1", 1, "C", false, 0, "")
+	pdf.Ln(4)
+	pdf.MultiCell(90, 8, strings.Repeat(txtStr+" ", 4), "1", "J", false)
+	pdf.Ln(4)
+	pdf.Write(8, strings.Repeat("Write() wraps Ελληνικά text too. ", 5))
+	pdf.Ln(12)
+	wd := pdf.GetStringWidth(txtStr)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Width of mixed text: %.1f mm", wd), "", 1, "L", false, 0, "")
+	fileStr := example.Filename("Fpdf_SetFontFallback")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFontFallback.pdf
+}
+
+// ExampleFpdf_AddSyntheticFontStyle demonstrates bold and italic pseudo-styles
+// for a font family of which only the regular style is available.
+func ExampleFpdf_AddSyntheticFontStyle() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("calligra", "", example.FontFile("calligra.ttf"))
+	pdf.AddSyntheticFontStyle("calligra", "B")
+	pdf.AddSyntheticFontStyle("calligra", "I")
+	pdf.AddSyntheticFontStyle("calligra", "BI")
+	pdf.AddPage()
+	pdf.SetTextColor(0, 0, 128)
+	for _, styleStr := range []string{"", "B", "I", "BI"} {
+		pdf.SetFont("calligra", styleStr, 20)
+		pdf.CellFormat(0, 12, fmt.Sprintf("Calligrapher in style \"%s\"", styleStr), "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont("calligra", "", 14)
+	pdf.SetTextColor(0, 0, 0)
+	html := pdf.HTMLBasicNew()
+	html.Write(7, "Pseudo-styles also work with <b>basic HTML</b>, <i>including "+
+		"italic</i> and <b><i>bold italic</i></b> runs.")
+	pdf.Ln(12)
+	pdf.SetFontStyle("BI")
+	pdf.Text(pdf.GetX(), pdf.GetY(), "Text() honors synthetic styles too")
+	fileStr := example.Filename("Fpdf_AddSyntheticFontStyle")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddSyntheticFontStyle.pdf
+}
+
+// ExampleFpdf_AddUTF8FontInstance demonstrates several designs taken from one
+// variable font file, selected by axis coordinates and by instance name.
+func ExampleFpdf_AddUTF8FontInstance() {
+	fontFileStr := example.FontFile("VariableTest.ttf")
+	axes, instances, err := gofpdf.FontVariations(fontFileStr)
+	if err == nil {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "", 12)
+		for _, axis := range axes {
+			pdf.CellFormat(0, 7, fmt.Sprintf("Axis %s (%s): %g to %g, default %g",
+				axis.Tag, axis.Name, axis.Min, axis.Max, axis.Default), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+		for j, wght := range []float64{300, 400, 550, 700} {
+			familyStr := fmt.Sprintf("weight%d", j)
+			pdf.AddUTF8FontInstance(familyStr, "", fontFileStr, map[string]float64{"wght": wght})
+			pdf.SetFont(familyStr, "", 24)
+			pdf.CellFormat(0, 12, fmt.Sprintf("Weight %g", wght), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+		for _, instance := range instances {
+			pdf.AddUTF8FontNamedInstance(instance.Name, "", fontFileStr, instance.Name)
+			pdf.SetFont(instance.Name, "", 24)
+			pdf.CellFormat(0, 12, fmt.Sprintf("%s (wght %g, wdth %g)", instance.Name,
+				instance.Coords["wght"], instance.Coords["wdth"]), "", 1, "L", false, 0, "")
+		}
+		fileStr := example.Filename("Fpdf_AddUTF8FontInstance")
+		err = pdf.OutputFileAndClose(fileStr)
+		example.Summary(err, fileStr)
+	} else {
+		fmt.Println(err)
+	}
+	// Output:
+	// Successfully generated pdf/Fpdf_AddUTF8FontInstance.pdf
+}
+
+// ExampleFpdf_TextPath demonstrates text drawn as vector outlines: filled,
+// stroked, rotated with a transformation and used as a clipping path.
+func ExampleFpdf_TextPath() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("calligra", "", example.FontFile("calligra.ttf"))
+	pdf.AddPage()
+	pdf.SetFont("dejavu", "", 48)
+	pdf.SetFillColor(40, 80, 160)
+	pdf.TextPath(20, 40, "Filled path", "F")
+	pdf.SetDrawColor(200, 30, 30)
+	pdf.SetLineWidth(0.4)
+	pdf.TextPath(20, 70, "Stroked path", "D")
+	pdf.SetFont("calligra", "", 40)
+	pdf.SetFillColor(240, 200, 60)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.2)
+	pdf.TextPath(20, 100, "Quadratic outlines", "FD")
+	pdf.TransformBegin()
+	pdf.TransformRotate(20, 30, 160)
+	pdf.SetFont("dejavu", "", 28)
+	pdf.SetFillColor(60, 140, 60)
+	pdf.TextPath(30, 160, "Rotated", "F")
+	pdf.TransformEnd()
+	pdf.SetFont("dejavu", "", 72)
+	paths := pdf.TextPathSegments(90, 200, "CLIP")
+	pdf.ClipPath(paths, true)
+	pdf.LinearGradient(90, 170, 110, 40, 255, 80, 0, 0, 60, 255, 0, 0, 1, 0)
+	pdf.ClipEnd()
+	fileStr := example.Filename("Fpdf_TextPath")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_TextPath.pdf
+}
+
+// ExampleFpdf_GetFontMetrics demonstrates the inspection of font metrics,
+// glyph coverage and the bounds of text without selecting the font.
+func ExampleFpdf_GetFontMetrics() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("calligra", "", example.FontFile("calligra.ttf"))
+	pdf.AddPage()
+	pdf.SetFont("helvetica", "", 10)
+	// Guide lines at the vertical metrics of the font
+	x, y := 20.0, 60.0
+	m := pdf.GetFontMetrics("dejavu", "", 72)
+	pdf.SetFont("dejavu", "", 72)
+	pdf.Text(x, y, "Hxgy")
+	w := pdf.GetStringWidth("Hxgy")
+	pdf.SetFont("helvetica", "", 8)
+	pdf.SetLineWidth(0.2)
+	for _, line := range []struct {
+		nameStr string
+		v       float64
+	}{
+		{"Ascender", m.Ascender},
+		{"Cap height", m.CapHeight},
+		{"x-height", m.XHeight},
+		{"Baseline", 0},
+		{"Descender", m.Descender},
+	} {
+		pdf.SetDrawColor(200, 30, 30)
+		pdf.Line(x, y-line.v, x+w+5, y-line.v)
+		pdf.Text(x+w+7, y-line.v+1, fmt.Sprintf("%s %.2f mm", line.nameStr, line.v))
+	}
+	// Labels centered vertically in boxes by their ink bounds
+	y = 90
+	for _, txtStr := range []string{"ace", "Hill", "jump", "gypsy"} {
+		b := pdf.GetTextBounds("calligra", "", 28, txtStr)
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.Rect(x, y, 40, 20, "D")
+		pdf.SetDrawColor(120, 160, 220)
+		baseline := y + 10 + (b.YMax+b.YMin)/2
+		left := x + 20 - (b.XMax+b.XMin)/2
+		pdf.Rect(left+b.XMin, baseline-b.YMax, b.XMax-b.XMin, b.YMax-b.YMin, "D")
+		pdf.SetFont("calligra", "", 28)
+		pdf.Text(left, baseline, txtStr)
+		x += 44
+	}
+	// Glyph coverage
+	x, y = 20, 130
+	pdf.SetFont("helvetica", "", 10)
+	txtStr := "Grüße, Привет, 日本"
+	for _, familyStr := range []string{"dejavu", "calligra"} {
+		var list []string
+		for _, r := range pdf.MissingGlyphs(familyStr, "", txtStr) {
+			list = append(list, fmt.Sprintf("U+%04X", r))
+		}
+		pdf.Text(x, y, fmt.Sprintf("Missing in %s: %s", familyStr, strings.Join(list, " ")))
+		y += 6
+	}
+	if b, ok := pdf.GetGlyphBounds("dejavu", "", 12, 'Ж'); ok {
+		pdf.Text(x, y, fmt.Sprintf("Bounds of Zhe at 12 pt: %.2f, %.2f to %.2f, %.2f mm",
+			b.XMin, b.YMin, b.XMax, b.YMax))
+	}
+	fileStr := example.Filename("Fpdf_GetFontMetrics")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_GetFontMetrics.pdf
+}
+
+// ExampleFpdf_AddUTF8Font_colorGlyphs demonstrates color emoji taken from a
+// fallback font with COLR, CBDT and sbix glyphs.
+func ExampleFpdf_AddUTF8Font_colorGlyphs() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("dejavu", "", example.FontFile("DejaVuSansCondensed.ttf"))
+	pdf.AddUTF8Font("emoji", "", example.FontFile("EmojiTest.ttf"))
+	pdf.SetFontFallback("dejavu", []string{"emoji"})
+	pdf.AddPage()
+	pdf.SetFont("dejavu", "", 16)
+	pdf.CellFormat(0, 10, "Layered vector glyphs: \U0001F600", "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 10, "Bitmap glyphs: \U0001F34E \U0001F4A1 \U0001F31F ⭐", "", 1, "L", false, 0, "")
+	// The text font has its own glyph for U+263A, so the color font is
+	// selected explicitly
+	pdf.SetTextColor(30, 90, 200)
+	pdf.CellFormat(110, 10, "Foreground layers follow the text color:", "", 0, "L", false, 0, "")
+	pdf.SetFont("emoji", "", 16)
+	pdf.CellFormat(0, 10, "☺", "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+	pdf.SetFont("dejavu", "", 12)
+	pdf.MultiCell(100, 6, "Emoji take part in line breaking and justification: "+
+		"an apple \U0001F34E a day, a bright idea \U0001F4A1 and a glowing star \U0001F31F "+
+		"for good measure \U0001F600", "1", "J", false)
+	pdf.Ln(4)
+	pdf.Write(6, "Write() and links work as well \U0001F600 ")
+	pdf.WriteLinkString(6, "github.com ⭐", "https://github.com/jung-kurt/gofpdf")
+	pdf.SetFont("emoji", "", 48)
+	pdf.Text(20, 120, "\U0001F600\U0001F34E\U0001F31F")
+	fileStr := example.Filename("Fpdf_AddUTF8Font_colorGlyphs")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddUTF8Font_colorGlyphs.pdf
+}
+
+// This example demonstrates vertical writing. The test font contains only a
+// handful of ideographs, together with vertical alternates for its
+// punctuation.
+func ExampleFpdf_MultiCellVertical() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("vertical", "", example.FontFile("VerticalTest.ttf"))
+	pdf.AddPage()
+	pdf.SetFont("vertical", "", 16)
+	// Book spine: a single column of upright and rotated text
+	pdf.SetFillColor(230, 225, 210)
+	pdf.SetXY(180, 20)
+	pdf.CellFormatVertical(14, 150, "「山田一二三」 Vol. 2", "1", 0, "M", true, 0, "")
+	// Columns of text from right to left; the Latin word and the number are
+	// rotated, the brackets and the prolonged sound mark are replaced by their
+	// vertical forms
+	pdf.SetXY(150, 20)
+	pdf.MultiCellVertical(10, 60, "山田一二三、十二月三十日。「上下」「中日」ー工王正。PDF 2024 山中田口。\n三月十日。",
+		"1", "", false)
+	pdf.SetXY(150, 90)
+	pdf.MultiCellVertical(10, 60, "上中下、一二三。", "", "B", false)
+	pdf.SetFontSize(12)
+	pdf.SetXY(20, 90)
+	pdf.CellVertical(10, 60, "三月三十日")
+	fileStr := example.Filename("Fpdf_MultiCellVertical")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MultiCellVertical.pdf
+}
+
+// This example demonstrates the CJK fonts that PDF viewers supply. The fonts
+// are not embedded, so the document remains small.
+func ExampleFpdf_AddCJKFont() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddCJKFont("song", "", "STSong-Light")
+	pdf.AddCJKFont("song", "B", "STSong-Light")
+	pdf.AddPage()
+	pdf.SetFont("song", "B", 16)
+	pdf.CellFormat(0, 10, "收据 Receipt", "B", 1, "C", false, 0, "")
+	pdf.SetFont("song", "", 12)
+	pdf.CellFormat(0, 8, "简体中文：谢谢光临，欢迎再来！", "", 1, "L", false, 0, "")
+	// The standard font names can be used directly
+	pdf.SetFont("MSung-Light", "", 12)
+	pdf.CellFormat(0, 8, "繁體中文：謝謝光臨，歡迎再來！", "", 1, "L", false, 0, "")
+	pdf.SetFont("KozMinPro-Regular", "", 12)
+	pdf.CellFormat(0, 8, "日本語：ご来店ありがとうございました。ｶﾀｶﾅ 2024", "", 1, "L", false, 0, "")
+	pdf.SetFont("HYSMyeongJo-Medium", "", 12)
+	pdf.CellFormat(0, 8, "한국어: 방문해 주셔서 감사합니다.", "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("KozMinPro-Regular", "", 11)
+	pdf.MultiCell(80, 6, "領収書の金額は税込みで、合計金額は1,234円です。"+
+		"またのご来店を心よりお待ちしております。", "1", "L", false)
+	pdf.SetXY(150, 80)
+	pdf.MultiCellVertical(9, 60, "縦書きの文章は、右から左へ「行」が進みます。", "1", "", false)
+	fileStr := example.Filename("Fpdf_AddCJKFont")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddCJKFont.pdf
+}
+
+// This example demonstrates PNG images with 16 bits per sample and Adam7
+// interlacing. The images are embedded with their full precision unless the
+// Reduce16Bit option is set; their alpha channels become soft masks.
+func ExampleFpdf_ImageOptions_png() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetFillColor(40, 70, 110)
+	pdf.Rect(10, 20, 190, 70, "F")
+	var opt gofpdf.ImageOptions
+	rgbaStr := example.ImageFile("gradient-16bit-interlaced.png")
+	grayStr := example.ImageFile("gray-alpha-16bit-interlaced.png")
+	pdf.Text(15, 16, "16 bits per sample")
+	pdf.ImageOptions(rgbaStr, 15, 25, 80, 0, false, opt, 0, "")
+	pdf.ImageOptions(grayStr, 105, 25, 80, 0, false, opt, 0, "")
+	// Images are registered by name, so the reduced images need their own
+	opt.ImageType = "png"
+	opt.Reduce16Bit = true
+	for j, fileStr := range []string{rgbaStr, grayStr} {
+		fl, err := os.Open(fileStr)
+		if err == nil {
+			pdf.RegisterImageOptionsReader(fmt.Sprintf("reduced%d", j), opt, fl)
+			fl.Close()
+		} else {
+			pdf.SetError(err)
+		}
+	}
+	pdf.SetFillColor(110, 40, 70)
+	pdf.Rect(10, 110, 190, 70, "F")
+	pdf.Text(15, 106, "Reduced to 8 bits per sample")
+	pdf.ImageOptions("reduced0", 15, 115, 80, 0, false, opt, 0, "")
+	pdf.ImageOptions("reduced1", 105, 115, 80, 0, false, opt, 0, "")
+	fileStr := example.Filename("Fpdf_ImageOptions_png")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_png.pdf
+}
+
+// This example demonstrates images that are generated in memory and
+// registered without being encoded as PNG or JPEG files first.
+func ExampleFpdf_RegisterImageFromImage() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	// A disc with a soft edge on a transparent background
+	const size = 200
+	disc := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			d := math.Hypot(float64(x-size/2), float64(y-size/2)) / (size / 2)
+			a := math.Max(0, math.Min(1, (1-d)*6))
+			disc.Set(x, y, color.NRGBA{uint8(255 * x / size), 90, uint8(255 * y / size), uint8(255 * a)})
+		}
+	}
+	// A checkerboard with a palette of two colors
+	board := image.NewPaletted(image.Rect(0, 0, 8, 8),
+		color.Palette{color.RGBA{30, 30, 30, 255}, color.RGBA{230, 220, 190, 255}})
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			board.SetColorIndex(x, y, uint8((x+y)%2))
+		}
+	}
+	var opt gofpdf.ImageOptions
+	pdf.RegisterImageFromImage("board", board, opt)
+	pdf.RegisterImageFromImage("disc", disc, opt)
+	opt.JPEGQuality = 75
+	pdf.RegisterImageFromImage("discJPEG", disc, opt)
+	pdf.ImageOptions("board", 10, 20, 90, 90, false, opt, 0, "")
+	pdf.ImageOptions("disc", 20, 30, 70, 70, false, opt, 0, "")
+	pdf.ImageOptions("board", 110, 20, 90, 90, false, opt, 0, "")
+	pdf.ImageOptions("discJPEG", 120, 30, 70, 70, false, opt, 0, "")
+	pdf.Text(10, 117, "Flate")
+	pdf.Text(110, 117, "DCT (JPEG) at quality 75; the soft mask is lossless")
+	fileStr := example.Filename("Fpdf_RegisterImageFromImage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_RegisterImageFromImage.pdf
+}
+
+// This example demonstrates TIFF, BMP and WebP images. The pages of the fax
+// document are bilevel images compressed with CCITT encoding, which are
+// embedded without being decoded. Their resolution differs horizontally and
+// vertically, which is taken into account when ReadDpi is set.
+func ExampleFpdf_ImageOptions_formats() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	opt := gofpdf.ImageOptions{ReadDpi: true}
+	faxStr := example.ImageFile("fax.tif")
+	for j := 1; j <= 2; j++ {
+		opt.Page = j
+		x := float64(10 + 100*(j-1))
+		pdf.ImageOptions(faxStr, x, 15, 90, 0, false, opt, 0, "")
+		nameStr := faxStr
+		if j > 1 {
+			nameStr = fmt.Sprintf("%s#%d", faxStr, j)
+		}
+		info := pdf.GetImageInfo(nameStr)
+		pdf.Text(x, 12, fmt.Sprintf("Fax page %d, %.0f x %.0f mm at full size", j,
+			info.Width(), info.Height()))
+	}
+	opt.Page = 0
+	pdf.SetFillColor(200, 220, 240)
+	pdf.Rect(10, 90, 190, 70, "F")
+	pdf.ImageOptions(example.ImageFile("golang-gopher.tiff"), 15, 95, 0, 60, false, opt, 0, "")
+	pdf.ImageOptions(example.ImageFile("logo.bmp"), 85, 110, 40, 0, false, opt, 0, "")
+	pdf.ImageOptions(example.ImageFile("yellow_rose.webp"), 140, 95, 0, 60, false, opt, 0, "")
+	pdf.Text(15, 166, "TIFF")
+	pdf.Text(85, 166, "BMP")
+	pdf.Text(140, 166, "WebP with transparency")
+	fileStr := example.Filename("Fpdf_ImageOptions_formats")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_formats.pdf
+}
+
+// This example demonstrates JPEG 2000 and JBIG2 images, which are embedded
+// without being decoded.
+func ExampleFpdf_ImageOptions_passthrough() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetFillColor(200, 220, 240)
+	pdf.Rect(10, 15, 190, 70, "F")
+	opt := gofpdf.ImageOptions{ReadDpi: true}
+	pdf.ImageOptions(example.ImageFile("gradient.jp2"), 15, 20, 80, 0, false, opt, 0, "")
+	pdf.ImageOptions(example.ImageFile("gradient.j2k"), 105, 20, 80, 0, false, opt, 0, "")
+	pdf.Text(15, 91, "JP2 file with alpha channel")
+	pdf.Text(105, 91, "JPEG 2000 codestream")
+	pdf.ImageOptions(example.ImageFile("shapes.jb2"), 15, 100, -1, -1, false, opt, 0, "")
+	info := pdf.GetImageInfo(example.ImageFile("shapes.jb2"))
+	pdf.Text(15, 106+info.Height(), fmt.Sprintf("JBIG2 page, %.0f x %.0f mm at full size",
+		info.Width(), info.Height()))
+	fileStr := example.Filename("Fpdf_ImageOptions_passthrough")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_passthrough.pdf
+}
+
+// This example demonstrates JPEG images with an EXIF orientation, which are
+// turned as they should appear, and CMYK JPEG images written by Adobe
+// applications, which hold inverted values.
+func ExampleFpdf_ImageOptions_jpeg() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	opt := gofpdf.ImageOptions{ReadDpi: true}
+	pdf.ImageOptions(example.ImageFile("logo.jpg"), 10, 15, 60, 0, false, opt, 0, "")
+	pdf.ImageOptions(example.ImageFile("logo-exif.jpg"), 80, 15, 60, 0, false, opt, 0, "")
+	pdf.Text(10, 62, "Stored upright")
+	pdf.Text(80, 62, "Stored sideways, with EXIF orientation 6")
+	// The resolution of the image is read from its EXIF segment and sets its
+	// size when neither width nor height is given
+	pdf.ImageOptions(example.ImageFile("logo-exif.jpg"), 10, 70, 0, 0, false, opt, 0, "")
+	info := pdf.GetImageInfo(example.ImageFile("logo-exif.jpg"))
+	pdf.Text(10, 76+info.Height(), fmt.Sprintf("At full size, %.1f x %.1f mm", info.Width(), info.Height()))
+	pdf.ImageOptions(example.ImageFile("video-001-cmyk.jpg"), 10, 100, 80, 0, false, opt, 0, "")
+	pdf.Text(10, 161, "Adobe CMYK JPEG")
+	fileStr := example.Filename("Fpdf_ImageOptions_jpeg")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_jpeg.pdf
+}
+
+// This example demonstrates an image policy that reduces images as they are
+// placed. The large JPEG image is resampled to the resolution that its small
+// box needs, and color images are converted to gray.
+func ExampleFpdf_SetImagePolicy() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetImagePolicy(gofpdf.ImagePolicy{MaxDpi: 150, JPEGQuality: 80, Grayscale: true})
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.ImageOptions(example.ImageFile("logo_gofpdf.jpg"), 10, 15, 80, 0, false,
+		gofpdf.ImageOptions{}, 0, "")
+	pdf.ImageOptions(example.ImageFile("golang-gopher.png"), 110, 15, 40, 0, false,
+		gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	pdf.ImageOptions(example.ImageFile("logo.gif"), 160, 15, 40, 0, false,
+		gofpdf.ImageOptions{}, 0, "")
+	pdf.Text(10, 65, "Images reduced to 150 dpi and converted to gray")
+	fileStr := example.Filename("Fpdf_SetImagePolicy")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetImagePolicy.pdf
+}
+
+// This example demonstrates placing an image in a box with fit modes,
+// alignment, and clipping to rounded corners or a circle.
+func ExampleFpdf_ImageOptions_fit() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetDrawColor(160, 160, 160)
+	imgStr := example.ImageFile("logo.jpg")
+	box := func(x, y float64, opt gofpdf.ImageOptions, label string) {
+		pdf.Rect(x, y, 40, 40, "D")
+		pdf.ImageOptions(imgStr, x, y, 40, 40, false, opt, 0, "")
+		pdf.Text(x, y+45, label)
+	}
+	box(10, 15, gofpdf.ImageOptions{Fit: "contain"}, "contain")
+	box(57, 15, gofpdf.ImageOptions{Fit: "cover"}, "cover")
+	box(104, 15, gofpdf.ImageOptions{Fit: "stretch"}, "stretch")
+	box(151, 15, gofpdf.ImageOptions{Fit: "none", Align: "LT"}, "none, aligned LT")
+	box(10, 70, gofpdf.ImageOptions{Fit: "contain", Align: "T"}, "contain, aligned T")
+	box(57, 70, gofpdf.ImageOptions{Fit: "cover", Align: "R"}, "cover, aligned R")
+	box(104, 70, gofpdf.ImageOptions{Fit: "cover", CornerRadius: 6}, "cover, rounded corners")
+	box(151, 70, gofpdf.ImageOptions{Fit: "cover", Circle: true}, "cover, circle")
+	fileStr := example.Filename("Fpdf_ImageOptions_fit")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_fit.pdf
+}
+
+// This example demonstrates image masks: a gray image that is generated here
+// serves as the soft mask of a JPEG image and of a paletted GIF image, a color
+// image is used as a stencil mask that is painted in the current fill color,
+// and a range of light colors is masked out of a JPEG image with a color key.
+func ExampleFpdf_ImageOptions_masks() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	// A vignette that fades out toward the edges
+	vignette := image.NewGray(image.Rect(0, 0, 104, 71))
+	for y := 0; y < 71; y++ {
+		for x := 0; x < 104; x++ {
+			dx, dy := float64(x-52)/52, float64(y-35)/35
+			v := 255 * (1.2 - math.Sqrt(dx*dx+dy*dy))
+			vignette.SetGray(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, v)))})
+		}
+	}
+	pdf.RegisterImageFromImage("vignette", vignette, gofpdf.ImageOptions{})
+	pdf.SetFillColor(255, 220, 120)
+	pdf.Rect(10, 15, 190, 45, "F")
+	pdf.ImageOptions(example.ImageFile("logo.jpg"), 15, 20, 50, 0, false,
+		gofpdf.ImageOptions{SoftMask: "vignette"}, 0, "")
+	pdf.Text(15, 68, "JPEG with a soft mask")
+	pdf.ImageOptions(example.ImageFile("logo.gif"), 145, 20, 50, 0, false,
+		gofpdf.ImageOptions{SoftMask: "vignette"}, 0, "")
+	pdf.Text(145, 68, "GIF with a soft mask")
+	// The light background of the logo is masked with a color key
+	fl, err := os.Open(example.ImageFile("logo.jpg"))
+	if err == nil {
+		pdf.RegisterImageOptionsReader("logo-key", gofpdf.ImageOptions{
+			ImageType:   "JPG",
+			ColorKeyMin: color.RGBA{200, 200, 200, 255},
+			ColorKeyMax: color.RGBA{255, 255, 255, 255},
+		}, fl)
+		fl.Close()
+		pdf.ImageOptions("logo-key", 80, 20, 50, 0, false, gofpdf.ImageOptions{}, 0, "")
+		pdf.Text(80, 68, "JPEG with a color key mask")
+	} else {
+		pdf.SetError(err)
+	}
+	// The gopher is painted in three colors
+	opt := gofpdf.ImageOptions{StencilMask: true}
+	for j, c := range [][3]int{{0, 0, 0}, {200, 40, 40}, {40, 120, 200}} {
+		pdf.SetFillColor(c[0], c[1], c[2])
+		pdf.ImageOptions(example.ImageFile("golang-gopher.png"), 10+float64(j)*45, 80, 40, 0, false, opt, 0, "")
+	}
+	pdf.Text(10, 140, "A stencil mask painted in the fill color")
+	fileStr := example.Filename("Fpdf_ImageOptions_masks")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_masks.pdf
+}
+
+// This example demonstrates tiling patterns. Hatch patterns paint areas with
+// lines or dots, and a pattern that is drawn like a template paints bricks,
+// scaled and rotated. Patterns can be used to fill shapes and cells and to
+// draw lines.
+func ExampleFpdf_CreatePattern() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	styles := []string{"horizontal", "vertical", "cross", "diagonal", "backdiagonal", "diagcross", "dots"}
+	for j, style := range styles {
+		x := 10 + float64(j%4)*48
+		y := 15 + float64(j/4)*45
+		pdf.SetFillPattern(pdf.CreateHatchPattern(style, 2, 0.3, 0, 0, 0, gofpdf.PatternOptions{}))
+		pdf.Rect(x, y, 40, 30, "FD")
+		pdf.Text(x, y+35, style)
+	}
+	bricks := func(tpl *gofpdf.Tpl) {
+		tpl.SetFillColor(180, 80, 60)
+		tpl.Rect(0, 0, 8, 4, "F")
+		tpl.SetDrawColor(230, 230, 230)
+		tpl.SetLineWidth(0.5)
+		tpl.Line(0, 0, 8, 0)
+		tpl.Line(0, 2, 8, 2)
+		tpl.Line(2, 0, 2, 2)
+		tpl.Line(6, 2, 6, 4)
+	}
+	brickID := pdf.CreatePattern(8, 4, gofpdf.PatternOptions{}, bricks)
+	rotatedID := pdf.CreatePattern(8, 4, gofpdf.PatternOptions{Scale: 0.5, Angle: 30}, bricks)
+	pdf.SetFillPattern(brickID)
+	pdf.Circle(173, 75, 15, "FD")
+	pdf.SetFillPattern(rotatedID)
+	pdf.Polygon([]gofpdf.PointType{{X: 10, Y: 140}, {X: 50, Y: 105}, {X: 90, Y: 140}}, "FD")
+	pdf.Text(10, 146, "Bricks, scaled and rotated")
+	// A cell with a hatched background
+	pdf.SetFillPattern(pdf.CreateHatchPattern("diagonal", 1.5, 0.2, 40, 120, 200, gofpdf.PatternOptions{}))
+	pdf.SetXY(106, 110)
+	pdf.CellFormat(90, 20, "Hatched cell", "1", 0, "C", true, 0, "")
+	// A thick line drawn with a pattern
+	pdf.SetDrawPattern(brickID)
+	pdf.SetLineWidth(6)
+	pdf.Line(10, 165, 196, 165)
+	fileStr := example.Filename("Fpdf_CreatePattern")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_CreatePattern.pdf
+}
+
+// This example demonstrates gradients with several color stops, in RGB, CMYK
+// and spot colors, and mesh shadings. They are created as patterns that can
+// paint any path.
+func ExampleFpdf_CreateLinearGradient() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 10)
+	rainbow := gofpdf.GradientOptions{
+		Stops: []gofpdf.GradientStop{
+			{Pos: 0, Color: color.RGBA{220, 40, 40, 255}},
+			{Pos: 0.25, Color: color.RGBA{240, 200, 40, 255}},
+			{Pos: 0.5, Color: color.RGBA{40, 180, 60, 255}},
+			{Pos: 0.75, Color: color.RGBA{40, 120, 220, 255}},
+			{Pos: 1, Color: color.RGBA{140, 40, 180, 255}},
+		},
+		ExtendStart: true,
+		ExtendEnd:   true,
+	}
+	pdf.SetFillPattern(pdf.CreateLinearGradient(10, 0, 100, 0, rainbow))
+	pdf.Rect(10, 15, 90, 30, "F")
+	pdf.Text(10, 50, "Linear, five stops")
+	// Abrupt changes of color, not extended beyond the gradient
+	stripes := gofpdf.GradientOptions{
+		Stops: []gofpdf.GradientStop{
+			{Pos: 0, Color: color.RGBA{40, 60, 140, 255}},
+			{Pos: 0.5, Color: color.RGBA{40, 60, 140, 255}},
+			{Pos: 0.5, Color: color.RGBA{240, 160, 40, 255}},
+			{Pos: 1, Color: color.RGBA{250, 240, 200, 255}},
+		},
+	}
+	pdf.SetFillPattern(pdf.CreateLinearGradient(140, 22, 160, 38, stripes))
+	pdf.Circle(150, 30, 18, "FD")
+	pdf.Text(110, 55, "Hard stop, not extended")
+	pdf.SetFillPattern(pdf.CreateRadialGradient(40, 80, 0, 40, 80, 25, rainbow))
+	pdf.Ellipse(40, 80, 30, 20, 0, "F")
+	pdf.Text(10, 107, "Radial, five stops")
+	cmyk := gofpdf.GradientOptions{
+		Stops: []gofpdf.GradientStop{
+			{Pos: 0, Color: color.CMYK{255, 0, 0, 0}},
+			{Pos: 0.5, Color: color.CMYK{0, 255, 0, 0}},
+			{Pos: 1, Color: color.CMYK{0, 0, 255, 0}},
+		},
+		ExtendStart: true,
+		ExtendEnd:   true,
+	}
+	pdf.SetFillPattern(pdf.CreateLinearGradient(0, 65, 0, 95, cmyk))
+	pdf.Polygon([]gofpdf.PointType{{X: 80, Y: 95}, {X: 100, Y: 65}, {X: 120, Y: 95}}, "F")
+	pdf.Text(80, 102, "CMYK")
+	pdf.AddSpotColor("PANTONE 145 CVC", 0, 42, 100, 25)
+	spot := gofpdf.GradientOptions{
+		Stops: []gofpdf.GradientStop{
+			{Pos: 0, Color: gofpdf.SpotColor{Name: "PANTONE 145 CVC", Tint: 10}},
+			{Pos: 1, Color: gofpdf.SpotColor{Name: "PANTONE 145 CVC", Tint: 100}},
+		},
+		ExtendStart: true,
+		ExtendEnd:   true,
+	}
+	pdf.SetFillPattern(pdf.CreateLinearGradient(140, 0, 200, 0, spot))
+	pdf.RoundedRect(140, 65, 60, 30, 5, "1234", "F")
+	pdf.Text(140, 102, "Spot color tints")
+	// Mesh shadings
+	red, green, blue := color.RGBA{230, 40, 40, 255}, color.RGBA{40, 200, 60, 255}, color.RGBA{40, 80, 230, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	pdf.SetFillPattern(pdf.CreateMeshShading([][3]gofpdf.MeshVertex{
+		{{X: 10, Y: 150, Color: red}, {X: 35, Y: 120, Color: green}, {X: 60, Y: 150, Color: blue}},
+		{{X: 35, Y: 120, Color: green}, {X: 60, Y: 150, Color: blue}, {X: 60, Y: 120, Color: white}},
+	}))
+	pdf.Rect(10, 120, 50, 30, "F")
+	pdf.Text(10, 157, "Triangle mesh")
+	var rows [][]gofpdf.MeshVertex
+	for j := 0; j < 3; j++ {
+		y := 120 + float64(j)*15
+		rows = append(rows, []gofpdf.MeshVertex{
+			{X: 75, Y: y, Color: red}, {X: 100, Y: y + 5, Color: []color.Color{white, green, blue}[j]},
+			{X: 125, Y: y, Color: red},
+		})
+	}
+	pdf.SetFillPattern(pdf.CreateLatticeMeshShading(rows))
+	pdf.Rect(75, 120, 50, 40, "F")
+	pdf.Text(75, 167, "Lattice mesh")
+	pdf.SetFillPattern(pdf.CreateCoonsPatchShading([]gofpdf.CoonsPatch{{
+		Points: [12]gofpdf.PointType{
+			{X: 140, Y: 150}, {X: 150, Y: 140}, {X: 160, Y: 160}, {X: 190, Y: 150},
+			{X: 180, Y: 140}, {X: 200, Y: 130}, {X: 190, Y: 120},
+			{X: 170, Y: 130}, {X: 160, Y: 110}, {X: 140, Y: 120},
+			{X: 130, Y: 130}, {X: 150, Y: 140},
+		},
+		Colors: [4]color.Color{red, green, blue, white},
+	}}))
+	pdf.Rect(130, 110, 70, 50, "F")
+	pdf.Text(140, 167, "Coons patch mesh")
+	fileStr := example.Filename("Fpdf_CreateLinearGradient")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_CreateLinearGradient.pdf
+}
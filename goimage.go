@@ -0,0 +1,200 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// RegisterImageFromImage registers an image held in memory, adding it to the
+// PDF file but not adding it to the page. Use Image() with the name imgName
+// to add the image to the page. Unlike RegisterImageOptionsReader(), the
+// image is not encoded in and parsed from an intermediate format.
+//
+// Gray, CMYK and paletted images keep their color space; all other images,
+// including RGBA, NRGBA and YCbCr images, are embedded as RGB images with 8
+// bits per component. An alpha channel that is not fully opaque becomes a
+// soft mask.
+//
+// By default the image is compressed losslessly with the Flate filter. If
+// options.JPEGQuality is greater than zero, gray and RGB images are instead
+// encoded with the DCT (JPEG) filter at that quality, which is usually much
+// smaller for photographs. CMYK and paletted images are always compressed
+// with the Flate filter.
+//
+// The mask fields of options, SoftMask, StencilMask, ColorKeyMin and
+// ColorKeyMax, are applied as they are by RegisterImageOptions(). Other fields
+// of options are ignored.
+func (f *Fpdf) RegisterImageFromImage(imgName string, img image.Image, options ImageOptions) (info *ImageInfoType) {
+	if f.err != nil {
+		return
+	}
+	info, ok := f.images[imgName]
+	if ok {
+		return
+	}
+	info = f.imageInfoFromImage(img, options.JPEGQuality)
+	if f.err != nil {
+		return
+	}
+	f.applyImageMasks(info, options)
+	if f.err != nil {
+		return
+	}
+	if info.i, f.err = generateImageID(info); f.err != nil {
+		return
+	}
+	f.images[imgName] = info
+	return
+}
+
+// imageInfoFromImage converts img to image information with 8 bits per
+// component. If quality is greater than zero, gray and RGB images are encoded
+// with the DCT filter.
+func (f *Fpdf) imageInfoFromImage(img image.Image, quality int) (info *ImageInfoType) {
+	info = f.newImageInfo()
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("image has no pixels")
+		return
+	}
+	info.w = float64(w)
+	info.h = float64(h)
+	info.bpc = 8
+	colors := 3
+	pix := make([]byte, 0, w*h*colors)
+	alpha := make([]byte, 0, w*h)
+	opaque := true
+	switch im := img.(type) {
+	case *image.Gray:
+		info.cs = "DeviceGray"
+		colors = 1
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pos := im.PixOffset(b.Min.X, y)
+			pix = append(pix, im.Pix[pos:pos+w]...)
+		}
+	case *image.CMYK:
+		info.cs = "DeviceCMYK"
+		colors = 4
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pos := im.PixOffset(b.Min.X, y)
+			pix = append(pix, im.Pix[pos:pos+4*w]...)
+		}
+	case *image.Paletted:
+		if len(im.Palette) == 0 {
+			f.err = fmt.Errorf("paletted image has an empty palette")
+			return
+		}
+		info.cs = "Indexed"
+		colors = 1
+		palAlpha := make([]byte, len(im.Palette))
+		for j, c := range im.Palette {
+			nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+			info.pal = append(info.pal, nc.R, nc.G, nc.B)
+			palAlpha[j] = nc.A
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pos := im.PixOffset(b.Min.X, y)
+			for _, ix := range im.Pix[pos : pos+w] {
+				if int(ix) >= len(im.Palette) {
+					// Colors outside the palette are treated as the first
+					ix = 0
+				}
+				pix = append(pix, ix)
+				alpha = append(alpha, palAlpha[ix])
+				opaque = opaque && palAlpha[ix] == 255
+			}
+		}
+	case *image.NRGBA:
+		info.cs = "DeviceRGB"
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pos := im.PixOffset(b.Min.X, y)
+			for _, p := range bytesSplit(im.Pix[pos:pos+4*w], 4) {
+				pix = append(pix, p[0], p[1], p[2])
+				alpha = append(alpha, p[3])
+				opaque = opaque && p[3] == 255
+			}
+		}
+	case *image.RGBA:
+		info.cs = "DeviceRGB"
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pos := im.PixOffset(b.Min.X, y)
+			for _, p := range bytesSplit(im.Pix[pos:pos+4*w], 4) {
+				// Colors are premultiplied by alpha
+				a := p[3]
+				if a == 255 || a == 0 {
+					pix = append(pix, p[0], p[1], p[2])
+				} else {
+					pix = append(pix, unpremultiply(p[0], a), unpremultiply(p[1], a), unpremultiply(p[2], a))
+				}
+				alpha = append(alpha, a)
+				opaque = opaque && a == 255
+			}
+		}
+	default:
+		// YCbCr and all other images are converted pixel by pixel
+		info.cs = "DeviceRGB"
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				pix = append(pix, c.R, c.G, c.B)
+				alpha = append(alpha, c.A)
+				opaque = opaque && c.A == 255
+			}
+		}
+	}
+	if !opaque {
+		info.smask = sliceCompress(pngFilterRows(alpha, w, h, 1))
+		if f.pdfVersion < "1.4" {
+			f.pdfVersion = "1.4"
+		}
+	}
+	if quality > 0 && (info.cs == "DeviceGray" || info.cs == "DeviceRGB") {
+		var src image.Image
+		rect := image.Rect(0, 0, w, h)
+		if colors == 1 {
+			src = &image.Gray{Pix: pix, Stride: w, Rect: rect}
+		} else {
+			rgba := image.NewRGBA(rect)
+			for j := 0; j < w*h; j++ {
+				copy(rgba.Pix[4*j:], pix[3*j:3*j+3])
+				rgba.Pix[4*j+3] = 255
+			}
+			src = rgba
+		}
+		var buf bytes.Buffer
+		if quality > 100 {
+			quality = 100
+		}
+		f.err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: quality})
+		info.data = buf.Bytes()
+		info.f = "DCTDecode"
+		return
+	}
+	info.data = sliceCompress(pngFilterRows(pix, colors*w, h, colors))
+	info.f = "FlateDecode"
+	info.dp = sprintf("/Predictor 15 /Colors %d /BitsPerComponent 8 /Columns %d", colors, w)
+	return
+}
+
+// bytesSplit returns the consecutive slices of n bytes of buf.
+func bytesSplit(buf []byte, n int) (list [][]byte) {
+	list = make([][]byte, 0, len(buf)/n)
+	for pos := 0; pos+n <= len(buf); pos += n {
+		list = append(list, buf[pos:pos+n])
+	}
+	return
+}
+
+// unpremultiply returns the color component c, premultiplied by alpha a, as a
+// straight color component.
+func unpremultiply(c, a byte) byte {
+	v := (int(c)*255 + int(a)/2) / int(a)
+	if v > 255 {
+		v = 255
+	}
+	return byte(v)
+}
@@ -117,15 +117,20 @@ func (f *Fpdf) HTMLBasicNew() (html HTMLBasicType) {
 // font. See HTMLBasicNew() to create a receiver that is associated with the
 // PDF document instance. The text can be encoded with a basic subset of HTML
 // that includes hyperlinks and tags for italic (I), bold (B), underscore
-// (U) and center (CENTER) attributes. When the right margin is reached a line
-// break occurs and text continues from the left margin. Upon method exit, the
-// current position is left at the end of the text.
+// (U) and center (CENTER) attributes. The content of a FOOTNOTE element is
+// registered as a note with WriteFootnote() and replaced in the text by its
+// superscript marker. When the right margin is reached a line break occurs
+// and text continues from the left margin. Upon method exit, the current
+// position is left at the end of the text.
 //
 // lineHt indicates the line height in the unit of measure specified in New().
 func (html *HTMLBasicType) Write(lineHt float64, htmlStr string) {
 	var boldLvl, italicLvl, underscoreLvl, linkBold, linkItalic, linkUnderscore int
 	var textR, textG, textB = html.pdf.GetTextColor()
 	var hrefStr string
+	var noteStr string
+	var inNote bool
+	var noteLvl [3]int // style levels of the body text around a note
 	if html.Link.Bold {
 		linkBold = 1
 	}
@@ -163,9 +168,16 @@ func (html *HTMLBasicType) Write(lineHt float64, htmlStr string) {
 	var ok bool
 	alignStr := "L"
 	for _, el := range list {
+		if inNote && el.Cat != 'T' && !(el.Str == "b" || el.Str == "i" || el.Str == "u" || el.Str == "footnote") {
+			// A note is plain text; tags that affect the layout of the body
+			// are ignored in it
+			continue
+		}
 		switch el.Cat {
 		case 'T':
-			if len(hrefStr) > 0 {
+			if inNote {
+				noteStr += el.Str
+			} else if len(hrefStr) > 0 {
 				putLink(hrefStr, el.Str)
 				hrefStr = ""
 			} else {
@@ -199,6 +211,10 @@ func (html *HTMLBasicType) Write(lineHt float64, htmlStr string) {
 				if !ok {
 					hrefStr = ""
 				}
+			case "footnote":
+				inNote = true
+				noteStr = ""
+				noteLvl = [3]int{boldLvl, italicLvl, underscoreLvl}
 			}
 		case 'C':
 			switch el.Str {
@@ -214,6 +230,12 @@ func (html *HTMLBasicType) Write(lineHt float64, htmlStr string) {
 			case "right":
 				html.pdf.Ln(lineHt)
 				alignStr = "L"
+			case "footnote":
+				inNote = false
+				// Style tags in the note do not change the font of the body
+				boldLvl, italicLvl, underscoreLvl = noteLvl[0], noteLvl[1], noteLvl[2]
+				setStyle(0, 0, 0)
+				html.pdf.WriteFootnote(lineHt, noteStr)
 			}
 		}
 	}
@@ -0,0 +1,198 @@
+package gofpdf
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// applyImageMasks applies the masks specified by options to an image that is
+// being registered: a registered gray image as soft mask, conversion to a
+// stencil mask, and a color key mask.
+func (f *Fpdf) applyImageMasks(info *ImageInfoType, options ImageOptions) {
+	if options.StencilMask {
+		f.imageStencil(info)
+		if f.err != nil {
+			return
+		}
+	}
+	if options.ColorKeyMin != nil || options.ColorKeyMax != nil {
+		f.imageColorKey(info, options.ColorKeyMin, options.ColorKeyMax)
+		if f.err != nil {
+			return
+		}
+	}
+	if options.SoftMask != "" {
+		mask, ok := f.images[options.SoftMask]
+		if !ok {
+			mask = f.RegisterImageOptions(options.SoftMask, ImageOptions{})
+			if f.err != nil {
+				return
+			}
+		}
+		if info.stencil {
+			f.err = fmt.Errorf("a stencil mask cannot have a soft mask")
+			return
+		}
+		info.smaskImage = f.softMaskImage(mask)
+		if f.err != nil {
+			return
+		}
+		// The soft mask replaces any alpha channel or color key mask of the
+		// image, as it does in viewers that follow the PDF specification
+		info.smask = nil
+		info.trns = nil
+		info.colorKey = nil
+		if f.pdfVersion < "1.4" {
+			f.pdfVersion = "1.4"
+		}
+	}
+}
+
+// softMaskImage returns mask as an image that can be used as a soft mask,
+// converting it to gray if necessary.
+func (f *Fpdf) softMaskImage(mask *ImageInfoType) *ImageInfoType {
+	if mask.cs == "DeviceGray" && len(mask.strips) == 0 && len(mask.smask) == 0 && mask.smaskImage == nil &&
+		len(mask.trns) == 0 && len(mask.colorKey) == 0 && mask.orientation <= 1 && !mask.stencil {
+		return mask
+	}
+	pix, channels, _, ok := f.imagePixels(mask)
+	if f.err != nil {
+		return nil
+	}
+	if !ok || mask.orientation > 1 {
+		f.err = fmt.Errorf("image cannot be used as a soft mask")
+		return nil
+	}
+	gray := f.newImageInfo()
+	gray.w = mask.w
+	gray.h = mask.h
+	gray.cs = "DeviceGray"
+	gray.bpc = 8
+	gray.f = "FlateDecode"
+	gray.dp = sprintf("/Predictor 15 /Colors 1 /BitsPerComponent 8 /Columns %d", int(mask.w))
+	gray.data = sliceCompress(pngFilterRows(grayPixels(pix, channels), int(mask.w), int(mask.h), 1))
+	gray.i, f.err = generateImageID(gray)
+	return gray
+}
+
+// imageStencil converts info to a stencil mask, which paints the current fill
+// color where its samples are zero. Bilevel gray images are used as they are;
+// other images are converted, painting pixels that are dark and not
+// transparent.
+func (f *Fpdf) imageStencil(info *ImageInfoType) {
+	bilevel := func(s *ImageInfoType) bool {
+		return s.cs == "DeviceGray" && s.bpc == 1 && len(s.smask) == 0 && len(s.trns) == 0
+	}
+	if len(info.strips) > 0 {
+		for _, s := range info.strips {
+			if !bilevel(s) {
+				f.err = fmt.Errorf("image cannot be used as a stencil mask")
+				return
+			}
+			s.stencil = true
+		}
+		info.stencil = true
+		return
+	}
+	if bilevel(info) {
+		info.stencil = true
+		return
+	}
+	pix, channels, alpha, ok := f.imagePixels(info)
+	if f.err != nil {
+		return
+	}
+	if !ok {
+		f.err = fmt.Errorf("image cannot be used as a stencil mask")
+		return
+	}
+	w, h := int(info.w), int(info.h)
+	stride := (w + 7) / 8
+	bits := make([]byte, stride*h)
+	for j, v := range grayPixels(pix, channels) {
+		if v >= 128 || alpha != nil && alpha[j] < 128 {
+			// Bits that are set leave the page unchanged
+			y, x := j/w, j%w
+			bits[y*stride+x/8] |= 0x80 >> uint(x%8)
+		}
+	}
+	info.data = sliceCompress(bits)
+	info.f = "FlateDecode"
+	info.dp = ""
+	info.cs = "DeviceGray"
+	info.bpc = 1
+	info.pal = nil
+	info.smask = nil
+	info.trns = nil
+	info.decode = ""
+	info.stencil = true
+}
+
+// imageColorKey sets the color key mask of info, which masks the pixels with
+// components between those of colors lo and hi. If either is nil, the other
+// color alone is masked.
+func (f *Fpdf) imageColorKey(info *ImageInfoType, lo, hi color.Color) {
+	if lo == nil {
+		lo = hi
+	} else if hi == nil {
+		hi = lo
+	}
+	if info.stencil || info.f == "JPXDecode" || info.bpc < 1 || info.bpc > 16 {
+		f.err = fmt.Errorf("a color key mask cannot be applied to this image")
+		return
+	}
+	// Largest sample value
+	max := 1<<uint(info.bpc) - 1
+	components := func(c color.Color) (list []int) {
+		switch info.cs {
+		case "DeviceGray":
+			g := color.Gray16Model.Convert(c).(color.Gray16)
+			list = []int{int(g.Y)}
+		case "DeviceRGB":
+			n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+			list = []int{int(n.R), int(n.G), int(n.B)}
+		case "DeviceCMYK":
+			k := color.CMYKModel.Convert(c).(color.CMYK)
+			list = []int{int(k.C) * 257, int(k.M) * 257, int(k.Y) * 257, int(k.K) * 257}
+		default:
+			f.err = fmt.Errorf("a color key mask cannot be applied to an image with color space %s", info.cs)
+			return
+		}
+		for j, v := range list {
+			list[j] = (v*max + 32767) / 65535
+		}
+		return
+	}
+	loList, hiList := components(lo), components(hi)
+	if f.err != nil {
+		return
+	}
+	info.colorKey = info.colorKey[:0]
+	for j := range loList {
+		a, b := loList[j], hiList[j]
+		if a > b {
+			a, b = b, a
+		}
+		info.colorKey = append(info.colorKey, a, b)
+	}
+}
+
+// grayPixels returns the gray levels of samples with channels components per
+// pixel, which are gray, RGB or CMYK.
+func grayPixels(pix []byte, channels int) []byte {
+	if channels == 1 {
+		return pix
+	}
+	out := make([]byte, len(pix)/channels)
+	for j := range out {
+		p := pix[j*channels : (j+1)*channels]
+		var c color.Color
+		if channels == 3 {
+			c = color.RGBA{R: p[0], G: p[1], B: p[2], A: 255}
+		} else {
+			c = color.CMYK{C: p[0], M: p[1], Y: p[2], K: p[3]}
+		}
+		out[j] = color.GrayModel.Convert(c).(color.Gray).Y
+	}
+	return out
+}
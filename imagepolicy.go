@@ -0,0 +1,290 @@
+package gofpdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"strings"
+)
+
+// ImagePolicy specifies how images are reduced when they are placed on a
+// page. See SetImagePolicy().
+//
+// MaxDpi, if greater than zero, is the highest effective resolution of a
+// placed image, in dots per inch. An image that has more pixels than this
+// resolution requires at the size it is placed is resampled to fewer pixels.
+//
+// JPEGQuality, if greater than zero, is the quality, from 1 to 100, at which
+// reduced gray and RGB images are encoded with the DCT (JPEG) filter. If it
+// is zero, reduced JPEG images are encoded again at the default quality of
+// the image/jpeg package and other images are compressed losslessly.
+//
+// Grayscale converts color images to gray when they are placed, whether or
+// not they are resampled.
+type ImagePolicy struct {
+	MaxDpi      float64
+	JPEGQuality int
+	Grayscale   bool
+}
+
+// SetImagePolicy sets the policy that reduces the size of images as they are
+// placed with Image() or ImageOptions(). This is useful when large images,
+// such as photographs taken with a phone, are placed in small boxes.
+//
+// The policy applies when a registered image is placed for the first time:
+// if it calls for a reduction, the image is decoded, resampled and encoded
+// again, and the reduced image replaces the registered one, so that the
+// original is not embedded in the document. Later placements use the reduced
+// image, even if they are larger. Its dimensions in the units of the Fpdf
+// object stay the same. Images that are placed before the policy is set are
+// not affected.
+//
+// Gray, RGB and CMYK images with 8 bits per component, and paletted images
+// with 8-bit indexes, are reduced, including their soft masks; color key
+// masks become soft masks. Other images, such as bilevel images, images with
+// 16 bits per component and JPEG 2000 images, are embedded as they are. So
+// are JPEG images that the image/jpeg package cannot decode, and images that
+// resampling would not make smaller unless they are converted to gray. Pass
+// the zero value of ImagePolicy to disable reductions.
+func (f *Fpdf) SetImagePolicy(policy ImagePolicy) {
+	f.imagePolicy = policy
+}
+
+// applyImagePolicy returns the image to use in place of info when it is placed
+// for the first time with width w and height h, in user units. If the image
+// policy calls for a reduction, the reduced image replaces info in the images
+// map.
+func (f *Fpdf) applyImagePolicy(info *ImageInfoType, w, h float64) *ImageInfoType {
+	if info.placed {
+		return info
+	}
+	info.placed = true
+	policy := f.imagePolicy
+	if policy.MaxDpi <= 0 && !policy.Grayscale {
+		return info
+	}
+	if info.orientation >= 5 {
+		// The rows of the image run vertically on the page
+		w, h = h, w
+	}
+	nw, nh := int(info.w), int(info.h)
+	if policy.MaxDpi > 0 && w > 0 && h > 0 {
+		// Number of pixels needed at the size of the placed image
+		nw = int(math.Ceil(w * f.k / 72 * policy.MaxDpi))
+		nh = int(math.Ceil(h * f.k / 72 * policy.MaxDpi))
+		if nw > int(info.w) {
+			nw = int(info.w)
+		}
+		if nh > int(info.h) {
+			nh = int(info.h)
+		}
+	}
+	gray := policy.Grayscale && info.cs != "DeviceGray"
+	if nw == int(info.w) && nh == int(info.h) && !gray {
+		return info
+	}
+	pix, channels, alpha, ok := f.imagePixels(info)
+	if f.err != nil || !ok {
+		return info
+	}
+	w0, h0 := int(info.w), int(info.h)
+	if nw != w0 || nh != h0 {
+		pix = resamplePixels(pix, w0, h0, channels, nw, nh)
+		if alpha != nil {
+			alpha = resamplePixels(alpha, w0, h0, 1, nw, nh)
+		}
+	}
+	rect := image.Rect(0, 0, nw, nh)
+	var img image.Image
+	switch {
+	case gray || channels == 1:
+		img = &image.Gray{Pix: grayPixels(pix, channels), Stride: nw, Rect: rect}
+	case channels == 3:
+		rgba := image.NewRGBA(rect)
+		for j := 0; j < nw*nh; j++ {
+			copy(rgba.Pix[4*j:], pix[3*j:3*j+3])
+			rgba.Pix[4*j+3] = 255
+		}
+		img = rgba
+	default:
+		img = &image.CMYK{Pix: pix, Stride: 4 * nw, Rect: rect}
+	}
+	quality := policy.JPEGQuality
+	if quality <= 0 && info.f == "DCTDecode" {
+		quality = jpeg.DefaultQuality
+	}
+	reduced := f.imageInfoFromImage(img, quality)
+	if f.err != nil {
+		return info
+	}
+	if alpha != nil {
+		reduced.smask = sliceCompress(pngFilterRows(alpha, nw, nh, 1))
+	}
+	if !gray && len(reduced.data)+len(reduced.smask) >= len(info.data)+len(info.smask) {
+		// A resampled image can be larger than the original, for example a
+		// CMYK JPEG image, which is compressed losslessly
+		return info
+	}
+	if alpha != nil && f.pdfVersion < "1.4" {
+		f.pdfVersion = "1.4"
+	}
+	// The image keeps its size on the page
+	reduced.dpi = info.dpi * float64(nw) / info.w
+	if dpiY := info.verticalDpi() * float64(nh) / info.h; dpiY != reduced.dpi {
+		reduced.dpiY = dpiY
+	}
+	reduced.dpiRead = info.dpiRead
+	reduced.orientation = info.orientation
+	reduced.smaskImage = info.smaskImage
+	reduced.placed = true
+	if reduced.i, f.err = generateImageID(reduced); f.err != nil {
+		return info
+	}
+	for key, val := range f.images {
+		if val == info {
+			f.images[key] = reduced
+		}
+	}
+	return reduced
+}
+
+// imagePixels returns the decoded samples of an image, with channels
+// components per pixel, and the samples of its soft mask, if any. Components
+// of paletted images are looked up in the palette. The returned ok is false if
+// the image cannot be decoded.
+func (f *Fpdf) imagePixels(info *ImageInfoType) (pix []byte, channels int, alpha []byte, ok bool) {
+	if info.bpc != 8 || len(info.strips) > 0 || len(info.colorKey) > 0 {
+		return
+	}
+	w, h := int(info.w), int(info.h)
+	switch info.cs {
+	case "DeviceGray", "Indexed":
+		channels = 1
+	case "DeviceRGB":
+		channels = 3
+	case "DeviceCMYK":
+		channels = 4
+	default:
+		return
+	}
+	switch info.f {
+	case "DCTDecode":
+		// Valid JPEG images that image/jpeg does not support, such as
+		// arithmetic-coded ones, are embedded as they are
+		img, err := jpeg.Decode(bytes.NewReader(info.data))
+		if err != nil {
+			return
+		}
+		pix = make([]byte, 0, w*h*channels)
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				switch c := img.At(x, y).(type) {
+				case color.Gray:
+					pix = append(pix, c.Y)
+				case color.CMYK:
+					pix = append(pix, c.C, c.M, c.Y, c.K)
+				default:
+					rgb := color.RGBAModel.Convert(c).(color.RGBA)
+					pix = append(pix, rgb.R, rgb.G, rgb.B)
+				}
+			}
+		}
+	case "FlateDecode":
+		pix = flateSamples(info.data, info.dp, w, h, channels)
+	}
+	if len(pix) != w*h*channels {
+		return nil, 0, nil, false
+	}
+	if len(info.smask) > 0 {
+		if alpha = flateSamples(info.smask, "/Predictor 15", w, h, 1); len(alpha) != w*h {
+			return nil, 0, nil, false
+		}
+	} else if len(info.trns) == channels {
+		// A color key mask becomes a soft mask
+		alpha = make([]byte, w*h)
+		for j := range alpha {
+			alpha[j] = 0
+			for c, v := range info.trns {
+				if int(pix[j*channels+c]) != v {
+					alpha[j] = 255
+					break
+				}
+			}
+		}
+	}
+	if info.cs == "Indexed" {
+		// Look up the colors of the palette
+		rgb := make([]byte, 0, 3*w*h)
+		for _, ix := range pix {
+			pos := 3 * int(ix)
+			if pos+3 > len(info.pal) {
+				pos = 0
+			}
+			rgb = append(rgb, info.pal[pos:pos+3]...)
+		}
+		pix = rgb
+		channels = 3
+	}
+	ok = true
+	return
+}
+
+// flateSamples returns the samples of Flate-compressed image data with 8 bits
+// per component, removing PNG predictors if the decode parameters dp specify
+// them. Nil is returned if the data cannot be decoded.
+func flateSamples(data []byte, dp string, w, h, channels int) []byte {
+	buf, err := sliceUncompress(data)
+	if err != nil {
+		return nil
+	}
+	if !strings.Contains(dp, "/Predictor") {
+		return buf
+	}
+	if buf, err = pngDecode(buf, w, h, 8, channels, false, false); err != nil {
+		return nil
+	}
+	// Remove the filter type byte that begins each row
+	stride := w * channels
+	pix := make([]byte, 0, stride*h)
+	for y := 0; y < h; y++ {
+		pix = append(pix, buf[y*(stride+1)+1:(y+1)*(stride+1)]...)
+	}
+	return pix
+}
+
+// resamplePixels reduces an image of w by h pixels with channels components
+// per pixel to nw by nh pixels. Each new pixel is the average of the pixels
+// that it covers.
+func resamplePixels(pix []byte, w, h, channels, nw, nh int) []byte {
+	out := make([]byte, nw*nh*channels)
+	sum := make([]int, channels)
+	for ny := 0; ny < nh; ny++ {
+		y0, y1 := ny*h/nh, (ny+1)*h/nh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for nx := 0; nx < nw; nx++ {
+			x0, x1 := nx*w/nw, (nx+1)*w/nw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			for c := range sum {
+				sum[c] = 0
+			}
+			for y := y0; y < y1; y++ {
+				row := pix[(y*w+x0)*channels : (y*w+x1)*channels]
+				for j, v := range row {
+					sum[j%channels] += int(v)
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			for c, s := range sum {
+				out[(ny*nw+nx)*channels+c] = byte((s + n/2) / n)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,285 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// jbig2Signature begins a JBIG2 file. Data without it is taken to be a
+// sequence of segments in the embedded stream format, like the page and
+// global files written by common encoders.
+const jbig2Signature = "\x97JB2\r\n\x1a\n"
+
+// JBIG2 segment types that are needed to embed a page
+const (
+	jbig2PageInfo    = 48
+	jbig2EndOfPage   = 49
+	jbig2EndOfStripe = 50
+	jbig2EndOfFile   = 51
+)
+
+// RegisterImageJBIG2Reader registers a JBIG2 image, reading it from Reader r,
+// adding it to the PDF file but not adding it to the page. Use Image() with
+// the name imgName to add the image to the page.
+//
+// globals, if not nil, supplies the global segments shared by the pages of a
+// document, such as the symbol dictionaries that encoders write to a file of
+// their own. Segments of r that are associated with no page are used as
+// global segments as well. The page of r specified by options.Page is
+// registered; options.ImageType is ignored.
+func (f *Fpdf) RegisterImageJBIG2Reader(imgName string, options ImageOptions, r, globals io.Reader) (info *ImageInfoType) {
+	if f.err != nil {
+		return
+	}
+	info, ok := f.images[imgName]
+	if ok {
+		return
+	}
+	info = f.parsejbig2(r, globals, options)
+	if f.err != nil {
+		return
+	}
+	f.applyImageMasks(info, options)
+	if f.err != nil {
+		return
+	}
+	if info.i, f.err = generateImageID(info); f.err != nil {
+		return
+	}
+	f.images[imgName] = info
+	return
+}
+
+// jbig2Segment is a segment of JBIG2 data, with its header and data.
+type jbig2Segment struct {
+	tp   int
+	page uint32
+	hdr  []byte
+	data []byte
+	// Length of data, which follows all of the headers in random-access
+	// files
+	length uint32
+	// Position of the page association field in hdr and its size in bytes
+	pagePos, pageSize int
+}
+
+// jbig2Segments returns the segments of JBIG2 data, which is either a JBIG2
+// file or a sequence of segments in the embedded stream format.
+func jbig2Segments(data []byte) (list []jbig2Segment, err error) {
+	sequential := true
+	if len(data) >= len(jbig2Signature) && string(data[:len(jbig2Signature)]) == jbig2Signature {
+		if len(data) < 9 {
+			err = fmt.Errorf("truncated JBIG2 file header")
+			return
+		}
+		flags := data[8]
+		sequential = flags&1 != 0
+		data = data[9:]
+		if flags&2 == 0 {
+			// Number of pages
+			if len(data) < 4 {
+				err = fmt.Errorf("truncated JBIG2 file header")
+				return
+			}
+			data = data[4:]
+		}
+	}
+	pos := 0
+	for pos < len(data) {
+		var s jbig2Segment
+		var length uint32
+		s, length, err = jbig2ReadHeader(data[pos:])
+		if err != nil {
+			return
+		}
+		pos += len(s.hdr)
+		if sequential {
+			if uint64(pos)+uint64(length) > uint64(len(data)) {
+				err = fmt.Errorf("truncated JBIG2 segment")
+				return
+			}
+			s.data = data[pos : pos+int(length)]
+			pos += int(length)
+		} else {
+			// Data follows all of the headers; it is assigned below
+			s.length = length
+		}
+		list = append(list, s)
+		if s.tp == jbig2EndOfFile {
+			break
+		}
+	}
+	if !sequential {
+		for j := range list {
+			n := list[j].length
+			if uint64(pos)+uint64(n) > uint64(len(data)) {
+				err = fmt.Errorf("truncated JBIG2 segment")
+				return
+			}
+			list[j].data = data[pos : pos+int(n)]
+			pos += int(n)
+		}
+	}
+	return
+}
+
+// jbig2ReadHeader reads the segment header at the start of data and returns
+// it with the length of the segment data.
+func jbig2ReadHeader(data []byte) (s jbig2Segment, length uint32, err error) {
+	truncated := fmt.Errorf("truncated JBIG2 segment header")
+	if len(data) < 6 {
+		err = truncated
+		return
+	}
+	number := binary.BigEndian.Uint32(data)
+	flags := data[4]
+	s.tp = int(flags & 0x3f)
+	pos := 5
+	count := int(data[pos] >> 5)
+	switch count {
+	case 5, 6:
+		err = fmt.Errorf("invalid referred-to segment count in JBIG2 segment %d", number)
+		return
+	case 7:
+		if len(data) < pos+4 {
+			err = truncated
+			return
+		}
+		count = int(binary.BigEndian.Uint32(data[pos:]) & 0x1fffffff)
+		pos += 4 + (count+8)/8
+	default:
+		pos++
+	}
+	// Segment numbers are referred to with the least number of bytes that
+	// can hold the number of this segment
+	refSize := 4
+	if number <= 256 {
+		refSize = 1
+	} else if number <= 65536 {
+		refSize = 2
+	}
+	pos += count * refSize
+	s.pagePos, s.pageSize = pos, 1
+	if flags&0x40 != 0 {
+		s.pageSize = 4
+	}
+	pos += s.pageSize
+	if len(data) < pos+4 {
+		err = truncated
+		return
+	}
+	if s.pageSize == 4 {
+		s.page = binary.BigEndian.Uint32(data[s.pagePos:])
+	} else {
+		s.page = uint32(data[s.pagePos])
+	}
+	length = binary.BigEndian.Uint32(data[pos:])
+	if length == 0xffffffff {
+		err = fmt.Errorf("JBIG2 segment %d has unknown length", number)
+		return
+	}
+	s.hdr = data[:pos+4]
+	return
+}
+
+// parsejbig2 extracts info from JBIG2 data. The page specified by
+// options.Page is embedded, with its page association changed to 1, and
+// decoded with the JBIG2Decode filter. Segments that are associated with no
+// page, together with those of globals if it is not nil, are embedded as the
+// global segments of the image.
+func (f *Fpdf) parsejbig2(r io.Reader, globals io.Reader, options ImageOptions) (info *ImageInfoType) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	segments, err := jbig2Segments(data)
+	if err != nil {
+		f.err = err
+		return
+	}
+	if globals != nil {
+		if data, err = ioutil.ReadAll(globals); err != nil {
+			f.err = err
+			return
+		}
+		var list []jbig2Segment
+		if list, err = jbig2Segments(data); err != nil {
+			f.err = err
+			return
+		}
+		segments = append(list, segments...)
+	}
+	page := uint32(options.Page)
+	if page < 1 {
+		page = 1
+	}
+	info = f.newImageInfo()
+	info.cs = "DeviceGray"
+	info.bpc = 1
+	info.f = "JBIG2Decode"
+	var rows uint32
+	var pageInfo []byte
+	for _, s := range segments {
+		switch {
+		case s.tp == jbig2EndOfPage || s.tp == jbig2EndOfFile:
+			// Not part of embedded streams
+		case s.page == 0:
+			info.globals = append(info.globals, s.hdr...)
+			info.globals = append(info.globals, s.data...)
+		case s.page == page:
+			hdr := make([]byte, len(s.hdr))
+			copy(hdr, s.hdr)
+			if s.pageSize == 4 {
+				binary.BigEndian.PutUint32(hdr[s.pagePos:], 1)
+			} else {
+				hdr[s.pagePos] = 1
+			}
+			info.data = append(info.data, hdr...)
+			info.data = append(info.data, s.data...)
+			switch s.tp {
+			case jbig2PageInfo:
+				if len(s.data) < 16 {
+					f.err = fmt.Errorf("invalid JBIG2 page information segment")
+					return
+				}
+				pageInfo = s.data
+			case jbig2EndOfStripe:
+				if len(s.data) >= 4 {
+					rows = binary.BigEndian.Uint32(s.data) + 1
+				}
+			}
+		}
+	}
+	if pageInfo == nil {
+		f.err = fmt.Errorf("JBIG2 buffer has no page %d", page)
+		return
+	}
+	w, h := binary.BigEndian.Uint32(pageInfo), binary.BigEndian.Uint32(pageInfo[4:])
+	if h == 0xffffffff {
+		// The height of a striped page is known from its last stripe
+		h = rows
+	}
+	if w == 0 || h == 0 {
+		f.err = fmt.Errorf("invalid dimensions in JBIG2 buffer")
+		return
+	}
+	info.w = float64(w)
+	info.h = float64(h)
+	if options.ReadDpi {
+		// Resolution in pixels per meter
+		if x := binary.BigEndian.Uint32(pageInfo[8:]); x > 0 {
+			info.dpi = float64(x) * 0.0254
+			info.dpiRead = true
+			if y := binary.BigEndian.Uint32(pageInfo[12:]); y > 0 && y != x {
+				info.dpiY = float64(y) * 0.0254
+			}
+		}
+	}
+	if f.pdfVersion < "1.4" {
+		f.pdfVersion = "1.4"
+	}
+	return
+}
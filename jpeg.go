@@ -0,0 +1,136 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+)
+
+// jpegMetadataType holds the information of a JPEG image that is found in its
+// application segments rather than in its frame header.
+type jpegMetadataType struct {
+	orientation int     // EXIF orientation, from 1 to 8, or 0 if absent
+	adobe       bool    // Adobe segment present, which marks inverted CMYK data
+	dpi, dpiY   float64 // Resolution from the JFIF or EXIF segment, or zero
+}
+
+// jpegMetadata returns the metadata found in the APP0 (JFIF), APP1 (EXIF) and
+// APP14 (Adobe) segments of JPEG data. Segments that cannot be parsed are
+// ignored.
+func jpegMetadata(data []byte) (meta jpegMetadataType) {
+	var jfifDpi, jfifDpiY, exifDpi, exifDpiY float64
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			break
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// Fill byte
+			pos++
+			continue
+		}
+		if marker == 0xd8 || marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			// Markers without a segment
+			pos += 2
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image: the headers have been read
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if n < 2 || pos+2+n > len(data) {
+			break
+		}
+		seg := data[pos+4 : pos+2+n]
+		pos += 2 + n
+		switch {
+		case marker == 0xe0 && len(seg) >= 12 && string(seg[:5]) == "JFIF\x00":
+			x := float64(binary.BigEndian.Uint16(seg[8:]))
+			y := float64(binary.BigEndian.Uint16(seg[10:]))
+			switch seg[7] {
+			case 1:
+				jfifDpi, jfifDpiY = x, y
+			case 2:
+				// Dots per centimeter
+				jfifDpi, jfifDpiY = x*2.54, y*2.54
+			}
+		case marker == 0xe1 && len(seg) >= 14 && string(seg[:6]) == "Exif\x00\x00":
+			meta.orientation, exifDpi, exifDpiY = jpegExif(seg[6:])
+		case marker == 0xee && len(seg) >= 12 && string(seg[:5]) == "Adobe":
+			meta.adobe = true
+		}
+	}
+	// A JFIF segment without units specifies only the aspect ratio of the
+	// pixels, so the EXIF resolution is preferred in that case
+	meta.dpi, meta.dpiY = jfifDpi, jfifDpiY
+	if meta.dpi <= 0 || meta.dpiY <= 0 {
+		meta.dpi, meta.dpiY = exifDpi, exifDpiY
+	}
+	if meta.dpiY == meta.dpi {
+		meta.dpiY = 0
+	}
+	return
+}
+
+// jpegExif returns the orientation and the resolution that are specified in
+// the first image file directory of EXIF data, which is laid out like a TIFF
+// file.
+func jpegExif(data []byte) (orientation int, dpi, dpiY float64) {
+	var bo binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		bo = binary.LittleEndian
+	case "MM\x00*":
+		bo = binary.BigEndian
+	default:
+		return
+	}
+	ifd, _, err := tiffReadIFD(data, bo, bo.Uint32(data[4:]))
+	if err != nil {
+		return
+	}
+	if v := ifd.val(tiffOrientation, 1); v >= 1 && v <= 8 {
+		orientation = int(v)
+	}
+	unit := ifd.val(tiffResolutionUnit, 2)
+	if unit == 2 || unit == 3 {
+		dpi = ifd.rational(tiffXResolution)
+		dpiY = ifd.rational(tiffYResolution)
+		if unit == 3 {
+			dpi *= 2.54
+			dpiY *= 2.54
+		}
+	}
+	return
+}
+
+// orientationMatrix returns the transformation matrix that draws an image in
+// the rectangle with lower left corner (x, y), width w and height h, so that
+// the image appears as specified by the EXIF orientation. For orientations 5
+// to 8 the rows of the image run vertically in the rectangle.
+func orientationMatrix(orientation int, x, y, w, h float64) [6]float64 {
+	switch orientation {
+	case 2:
+		// Mirrored horizontally
+		return [6]float64{-w, 0, 0, h, x + w, y}
+	case 3:
+		// Turned by a half turn
+		return [6]float64{-w, 0, 0, -h, x + w, y + h}
+	case 4:
+		// Mirrored vertically
+		return [6]float64{w, 0, 0, -h, x, y + h}
+	case 5:
+		// The first row is on the left and the first column at the top
+		return [6]float64{0, -h, -w, 0, x + w, y + h}
+	case 6:
+		// The first row is on the right and the first column at the top
+		return [6]float64{0, -h, w, 0, x, y + h}
+	case 7:
+		// The first row is on the right and the first column at the bottom
+		return [6]float64{0, h, w, 0, x, y}
+	case 8:
+		// The first row is on the left and the first column at the bottom
+		return [6]float64{0, h, -w, 0, x + w, y}
+	}
+	return [6]float64{w, 0, 0, h, x, y}
+}
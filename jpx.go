@@ -0,0 +1,209 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// jpxSignature begins a JP2 or JPX file, which holds a JPEG 2000 codestream in
+// a sequence of boxes.
+const jpxSignature = "\x00\x00\x00\x0cjP  \r\n\x87\n"
+
+// jpxBox is a box of a JP2 file, with the box type and contents.
+type jpxBox struct {
+	tp   string
+	data []byte
+}
+
+// jpxBoxes returns the boxes that make up data, which is either a JP2 file or
+// the contents of a superbox.
+func jpxBoxes(data []byte) (list []jpxBox, err error) {
+	for len(data) > 0 {
+		if len(data) < 8 {
+			err = fmt.Errorf("truncated box in JPEG 2000 buffer")
+			return
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		tp := string(data[4:8])
+		hdr := uint64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of the file
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				err = fmt.Errorf("truncated box in JPEG 2000 buffer")
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			hdr = 16
+		}
+		if size < hdr || size > uint64(len(data)) {
+			err = fmt.Errorf("invalid length of box %q in JPEG 2000 buffer", tp)
+			return
+		}
+		list = append(list, jpxBox{tp: tp, data: data[hdr:size]})
+		data = data[size:]
+	}
+	return
+}
+
+// parsejpx extracts info from JPEG 2000 data, either a JP2 file or a bare
+// codestream. The data is embedded as it is and decoded with the JPXDecode
+// filter.
+func (f *Fpdf) parsejpx(r io.Reader) (info *ImageInfoType) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	info = f.newImageInfo()
+	if len(data) >= len(jpxSignature) && string(data[:len(jpxSignature)]) == jpxSignature {
+		f.jpxHeader(info, data)
+	} else {
+		f.jpxCodestream(info, data, true)
+	}
+	if f.err != nil {
+		return
+	}
+	info.data = data
+	info.f = "JPXDecode"
+	if f.pdfVersion < "1.5" {
+		f.pdfVersion = "1.5"
+	}
+	return
+}
+
+// jpxHeader sets the dimensions, color space and bit depth of info from the
+// header box of a JP2 file. The color space is left empty, so that the one
+// specified in the file applies, unless it is an enumerated gray, sRGB or CMYK
+// space without a palette.
+func (f *Fpdf) jpxHeader(info *ImageInfoType, data []byte) {
+	boxes, err := jpxBoxes(data)
+	if err != nil {
+		f.err = err
+		return
+	}
+	var hdr []jpxBox
+	var codestream []byte
+	for _, b := range boxes {
+		switch b.tp {
+		case "jp2h":
+			if hdr, err = jpxBoxes(b.data); err != nil {
+				f.err = err
+				return
+			}
+		case "jp2c":
+			if codestream == nil {
+				codestream = b.data
+			}
+		}
+	}
+	var ihdr, colr, bpcc []byte
+	var palette bool
+	var channels int
+	for _, b := range hdr {
+		switch b.tp {
+		case "ihdr":
+			ihdr = b.data
+		case "colr":
+			if colr == nil {
+				colr = b.data
+			}
+		case "bpcc":
+			bpcc = b.data
+		case "pclr":
+			palette = true
+		case "cdef":
+			if len(b.data) < 2 {
+				f.err = fmt.Errorf("invalid channel definition in JPEG 2000 buffer")
+				return
+			}
+			channels = int(binary.BigEndian.Uint16(b.data))
+			for j := 0; j < channels && 2+6*j+6 <= len(b.data); j++ {
+				// An opacity channel is applied as a soft mask
+				switch binary.BigEndian.Uint16(b.data[2+6*j+2:]) {
+				case 1:
+					info.smaskInData = 1
+				case 2:
+					info.smaskInData = 2
+				}
+			}
+		}
+	}
+	if len(ihdr) < 14 {
+		f.err = fmt.Errorf("JPEG 2000 buffer has no image header")
+		return
+	}
+	info.h = float64(binary.BigEndian.Uint32(ihdr))
+	info.w = float64(binary.BigEndian.Uint32(ihdr[4:]))
+	bpc := ihdr[10]
+	if bpc == 255 && len(bpcc) > 0 {
+		// Components differ in bit depth; the first is reported
+		bpc = bpcc[0]
+	}
+	if bpc == 255 {
+		if codestream == nil {
+			f.err = fmt.Errorf("JPEG 2000 buffer has no codestream")
+			return
+		}
+		f.jpxCodestream(info, codestream, false)
+	} else {
+		info.bpc = int(bpc&0x7f) + 1
+	}
+	if info.w <= 0 || info.h <= 0 {
+		f.err = fmt.Errorf("invalid dimensions in JPEG 2000 buffer")
+		return
+	}
+	if len(colr) >= 7 && colr[0] == 1 && !palette {
+		switch binary.BigEndian.Uint32(colr[3:]) {
+		case 12:
+			info.cs = "DeviceCMYK"
+		case 16:
+			info.cs = "DeviceRGB"
+		case 17:
+			info.cs = "DeviceGray"
+		}
+	}
+}
+
+// jpxCodestream sets the bit depth of info, and if full is true its
+// dimensions and color space, from the SIZ marker segment of a JPEG 2000
+// codestream. The color space follows from the number of components.
+func (f *Fpdf) jpxCodestream(info *ImageInfoType, data []byte, full bool) {
+	// The SIZ marker segment immediately follows the start of codestream
+	if len(data) < 42 || data[0] != 0xff || data[1] != 0x4f || data[2] != 0xff || data[3] != 0x51 {
+		f.err = fmt.Errorf("not a JPEG 2000 buffer")
+		return
+	}
+	siz := data[6:]
+	components := int(binary.BigEndian.Uint16(siz[34:]))
+	if components < 1 || len(siz) < 36+3*components {
+		f.err = fmt.Errorf("invalid image size segment in JPEG 2000 buffer")
+		return
+	}
+	info.bpc = int(siz[36]&0x7f) + 1
+	if !full {
+		return
+	}
+	xsiz, ysiz := binary.BigEndian.Uint32(siz[2:]), binary.BigEndian.Uint32(siz[6:])
+	xosiz, yosiz := binary.BigEndian.Uint32(siz[10:]), binary.BigEndian.Uint32(siz[14:])
+	if xsiz <= xosiz || ysiz <= yosiz {
+		f.err = fmt.Errorf("invalid dimensions in JPEG 2000 buffer")
+		return
+	}
+	info.w = float64(xsiz - xosiz)
+	info.h = float64(ysiz - yosiz)
+	switch components {
+	case 1:
+		info.cs = "DeviceGray"
+	case 3:
+		info.cs = "DeviceRGB"
+	case 4:
+		info.cs = "DeviceCMYK"
+	default:
+		f.err = fmt.Errorf("unsupported number of components in JPEG 2000 buffer: %d", components)
+	}
+}
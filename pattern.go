@@ -0,0 +1,231 @@
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// patternType is a tiling pattern, which paints an area by repeating the
+// content of a cell, or a shading pattern, which paints a smooth shading.
+type patternType struct {
+	data    []byte       // content of a cell
+	w, h    float64      // size of a cell, in points
+	matrix  [6]float64   // maps the pattern space to the default page space
+	shading *shadingType // shading of a shading pattern
+	objNum  int
+}
+
+// PatternOptions specifies how the cells of a tiling pattern are laid out on
+// the page. See CreatePattern() and CreateHatchPattern().
+//
+// Scale enlarges or reduces the cells; zero is the same as one. Angle rotates
+// the cells counter-clockwise, in degrees. X and Y are the position of the
+// top left corner of one of the cells, in user units on the current page, as
+// with the coordinates of gradients. The layout of a pattern does not depend
+// on the transformations that are in effect where it is used.
+type PatternOptions struct {
+	Scale float64
+	Angle float64
+	X, Y  float64
+}
+
+// CreatePattern creates a tiling pattern and returns its identifier, which is
+// passed to SetFillPattern() or SetDrawPattern() to paint areas or lines with
+// the pattern. The pattern repeats a cell of width w and height h, in user
+// units, across the area that it paints.
+//
+// fn draws the content of the cell in the same way as the content of a
+// template (see CreateTemplate()); the top left corner of the cell is at
+// (0, 0). Content outside of the cell is clipped.
+func (f *Fpdf) CreatePattern(w, h float64, options PatternOptions, fn func(*Tpl)) (patternID int) {
+	if f.err != nil {
+		return
+	}
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("invalid pattern cell size %.2f x %.2f", w, h)
+		return
+	}
+	t := newTpl(PointType{0, 0}, SizeType{Wd: w, Ht: h}, "P", f.unitStr, f.fontDirStr, fn, f)
+	// The images and templates of the cell are resources of the document
+	existingImages := map[string]bool{}
+	for _, image := range f.images {
+		existingImages[image.i] = true
+	}
+	for name, ti := range t.Images() {
+		if !existingImages[ti.i] {
+			f.images[sprintf("t%s-%s", t.ID(), name)] = ti
+		}
+	}
+	for _, tt := range t.Templates() {
+		f.templates[tt.ID()] = tt
+	}
+	return f.addPattern(t.Bytes(), w*f.k, h*f.k, options)
+}
+
+// CreateHatchPattern creates a tiling pattern of lines or dots and returns its
+// identifier, which is passed to SetFillPattern() or SetDrawPattern() to paint
+// areas or lines with the pattern.
+//
+// styleStr is one of "horizontal", "vertical", "cross" (horizontal and
+// vertical lines), "diagonal" (lines that rise to the right), "backdiagonal"
+// (lines that fall to the right), "diagcross" (both kinds of diagonal lines)
+// or "dots". spacing is the distance between the lines or dots, and lineWidth
+// is the width of the lines or the diameter of the dots, both in user units.
+// The lines and dots are painted in the color specified by r, g and b (0 -
+// 255); the area between them is left unpainted.
+func (f *Fpdf) CreateHatchPattern(styleStr string, spacing, lineWidth float64, r, g, b int, options PatternOptions) (patternID int) {
+	if f.err != nil {
+		return
+	}
+	if spacing <= 0 || lineWidth <= 0 {
+		f.err = fmt.Errorf("invalid hatch pattern spacing %.2f or line width %.2f", spacing, lineWidth)
+		return
+	}
+	d := spacing * f.k
+	rising := [][4]float64{{0, 0, 1, 1}, {-1, 0, 0, 1}, {1, 0, 2, 1}}
+	falling := [][4]float64{{0, 1, 1, 0}, {-1, 1, 0, 0}, {1, 1, 2, 0}}
+	// Lines from (x1, y1) to (x2, y2) in units of the cell size; a diagonal
+	// line is repeated in the neighboring cells to fill the corners
+	var lines [][4]float64
+	capStyle := 0
+	diagonal := false
+	switch styleStr {
+	case "horizontal":
+		lines = [][4]float64{{0, 0.5, 1, 0.5}}
+	case "vertical":
+		lines = [][4]float64{{0.5, 0, 0.5, 1}}
+	case "cross":
+		lines = [][4]float64{{0, 0.5, 1, 0.5}, {0.5, 0, 0.5, 1}}
+	case "diagonal":
+		lines = rising
+		diagonal = true
+	case "backdiagonal":
+		lines = falling
+		diagonal = true
+	case "diagcross":
+		lines = append(rising, falling...)
+		diagonal = true
+	case "dots":
+		// A line of no length with round caps is a dot
+		lines = [][4]float64{{0.5, 0.5, 0.5, 0.5}}
+		capStyle = 1
+	default:
+		f.err = fmt.Errorf("unknown hatch pattern style %s", styleStr)
+		return
+	}
+	if diagonal {
+		// The distance between diagonal lines is the spacing
+		d *= math.Sqrt2
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%.2f w %d J %s\n", lineWidth*f.k, capStyle, rgbColorValue(r, g, b, "G", "RG").str)
+	for _, l := range lines {
+		fmt.Fprintf(&buf, "%.5f %.5f m %.5f %.5f l S\n", l[0]*d, l[1]*d, l[2]*d, l[3]*d)
+	}
+	return f.addPattern(buf.Bytes(), d, d, options)
+}
+
+// addPattern adds a pattern with a cell of width w and height h, in points,
+// and returns its identifier.
+func (f *Fpdf) addPattern(data []byte, w, h float64, options PatternOptions) int {
+	scale := options.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	sin, cos := math.Sincos(options.Angle * math.Pi / 180)
+	a, b, c, d := scale*cos, scale*sin, -scale*sin, scale*cos
+	// The top left corner of the cell, (0, h) in pattern space, is placed at
+	// (X, Y) on the current page
+	x := options.X * f.k
+	y := (f.h - options.Y) * f.k
+	f.patternList = append(f.patternList, patternType{
+		data:   data,
+		w:      w,
+		h:      h,
+		matrix: [6]float64{a, b, c, d, x - c*h, y - d*h},
+	})
+	return len(f.patternList) - 1
+}
+
+// SetFillPattern sets the pattern with the identifier patternID, as returned
+// by CreatePattern(), CreateHatchPattern(), CreateLinearGradient() and
+// similar methods, as the paint of all filling operations, such as those of
+// Rect(), Polygon(), Circle(), DrawPath() and the backgrounds of cells. Like a
+// fill color, it is retained from page to page; it is replaced by calling
+// SetFillColor() or SetFillSpotColor().
+func (f *Fpdf) SetFillPattern(patternID int) {
+	if !f.validPattern(patternID) {
+		return
+	}
+	f.color.fill.mode = colorModePattern
+	f.color.fill.str = sprintf("/Pattern cs /P%d scn", patternID)
+	f.colorFlag = f.color.fill.str != f.color.text.str
+	if f.page > 0 {
+		f.out(f.color.fill.str)
+	}
+}
+
+// SetDrawPattern sets the pattern with the identifier patternID, as returned
+// by CreatePattern(), CreateHatchPattern(), CreateLinearGradient() and
+// similar methods, as the paint of all drawing operations, such as lines and
+// the outlines of shapes and cells. It is replaced by calling SetDrawColor()
+// or SetDrawSpotColor().
+func (f *Fpdf) SetDrawPattern(patternID int) {
+	if !f.validPattern(patternID) {
+		return
+	}
+	f.color.draw.mode = colorModePattern
+	f.color.draw.str = sprintf("/Pattern CS /P%d SCN", patternID)
+	if f.page > 0 {
+		f.out(f.color.draw.str)
+	}
+}
+
+// validPattern returns true if patternID identifies a pattern that has been
+// created. Otherwise, it sets an error.
+func (f *Fpdf) validPattern(patternID int) bool {
+	if f.err != nil {
+		return false
+	}
+	if patternID < 1 || patternID >= len(f.patternList) {
+		f.err = fmt.Errorf("pattern %d is not defined", patternID)
+		return false
+	}
+	return true
+}
+
+func (f *Fpdf) putPatterns() {
+	filter := ""
+	if f.compress {
+		filter = "/Filter /FlateDecode "
+	}
+	count := len(f.patternList)
+	for j := 1; j < count; j++ {
+		p := f.patternList[j]
+		if p.shading != nil {
+			// The shading is written in default page space, right before
+			// the pattern
+			f.putShading(p.shading)
+			f.newobj()
+			f.outf("<</Type /Pattern /PatternType 2 /Shading %d 0 R>>", f.n-1)
+			f.out("endobj")
+			f.patternList[j].objNum = f.n
+			continue
+		}
+		data := p.data
+		if f.compress {
+			data = sliceCompress(data)
+		}
+		f.newobj()
+		f.outf("<<%s/Type /Pattern /PatternType 1 /PaintType 1 /TilingType 1", filter)
+		f.outf("/BBox [0 0 %.5f %.5f] /XStep %.5f /YStep %.5f", p.w, p.h, p.w, p.h)
+		f.outf("/Matrix [%.5f %.5f %.5f %.5f %.5f %.5f]", p.matrix[0], p.matrix[1],
+			p.matrix[2], p.matrix[3], p.matrix[4], p.matrix[5])
+		f.out("/Resources 2 0 R")
+		f.outf("/Length %d >>", len(data))
+		f.putstream(data)
+		f.out("endobj")
+		f.patternList[j].objNum = f.n
+	}
+}
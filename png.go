@@ -38,7 +38,7 @@ func (f *Fpdf) pngColorSpace(ct byte) (colspace string, colorVal int) {
 	return
 }
 
-func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoType) {
+func (f *Fpdf) parsepngstream(buf *bytes.Buffer, options ImageOptions) (info *ImageInfoType) {
 	info = f.newImageInfo()
 	// 	Check signature
 	if string(buf.Next(8)) != "\x89PNG\x0d\x0a\x1a\x0a" {
@@ -54,9 +54,6 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	w := f.readBeInt32(buf)
 	h := f.readBeInt32(buf)
 	bpc := f.readByte(buf)
-	if bpc > 8 {
-		f.err = fmt.Errorf("16-bit depth not supported in PNG file")
-	}
 	ct := f.readByte(buf)
 	var colspace string
 	var colorVal int
@@ -72,12 +69,28 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 		f.err = fmt.Errorf("'unknown filter method in PNG buffer")
 		return
 	}
-	if f.readByte(buf) != 0 {
-		f.err = fmt.Errorf("interlacing not supported in PNG buffer")
+	interlaced := false
+	switch f.readByte(buf) {
+	case 0:
+	case 1:
+		interlaced = true
+	default:
+		f.err = fmt.Errorf("unknown interlace method in PNG buffer")
+		return
+	}
+	if f.err != nil {
+		return
+	}
+	channels := pngChannels[ct]
+	if !pngBitDepthValid(ct, bpc) {
+		f.err = fmt.Errorf("invalid bit depth %d for color type %d in PNG buffer", bpc, ct)
 		return
 	}
 	_ = buf.Next(4)
-	dp := sprintf("/Predictor 15 /Colors %d /BitsPerComponent %d /Columns %d", colorVal, bpc, w)
+	// The image data is decoded if the rows must be rearranged or the
+	// samples reduced; otherwise it is passed to the PDF unchanged
+	reduce := bpc == 16 && options.Reduce16Bit
+	decode := interlaced || reduce
 	// Scan chunks looking for palette, transparency and image data
 	pal := make([]byte, 0, 32)
 	var trns []int
@@ -98,9 +111,9 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 			t := buf.Next(n)
 			switch ct {
 			case 0:
-				trns = []int{int(t[1])} // ord(substr($t,1,1)));
+				trns = []int{int(t[0])<<8 | int(t[1])} // ord(substr($t,1,1)));
 			case 2:
-				trns = []int{int(t[1]), int(t[3]), int(t[5])} // array(ord(substr($t,1,1)), ord(substr($t,3,1)), ord(substr($t,5,1)));
+				trns = []int{int(t[0])<<8 | int(t[1]), int(t[2])<<8 | int(t[3]), int(t[4])<<8 | int(t[5])} // array(ord(substr($t,1,1)), ord(substr($t,3,1)), ord(substr($t,5,1)));
 			default:
 				pos := strings.Index(string(t), "\x00")
 				if pos >= 0 {
@@ -128,7 +141,7 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 			// fmt.Printf("got a pHYs block, x=%d, y=%d, u=%d, readdpi=%t\n",
 			// x, y, int(units), readdpi)
 			// only modify the info block if the user wants us to
-			if x == y && readdpi {
+			if x == y && options.ReadDpi {
 				switch units {
 				// if units is 1 then measurement is px/meter
 				case 1:
@@ -149,6 +162,30 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	if colspace == "Indexed" && len(pal) == 0 {
 		f.err = fmt.Errorf("missing palette in PNG buffer")
 	}
+	if decode && f.err == nil {
+		var err error
+		data, err = sliceUncompress(data)
+		if err == nil {
+			data, err = pngDecode(data, int(w), int(h), int(bpc), channels, interlaced, reduce)
+		}
+		if err != nil {
+			f.err = err
+			return
+		}
+		if reduce {
+			bpc = 8
+			for j := range trns {
+				trns[j] >>= 8
+			}
+		}
+		if ct < 4 {
+			data = sliceCompress(data)
+		}
+	}
+	if bpc == 16 && f.pdfVersion < "1.5" {
+		f.pdfVersion = "1.5"
+	}
+	dp := sprintf("/Predictor 15 /Colors %d /BitsPerComponent %d /Columns %d", colorVal, bpc, w)
 	info.w = float64(w)
 	info.h = float64(h)
 	info.cs = colspace
@@ -159,47 +196,38 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	info.trns = trns
 	// dbg("ct [%d]", ct)
 	if ct >= 4 {
-		// Separate alpha and color channels
-		var err error
-		data, err = sliceUncompress(data)
-		if err != nil {
-			f.err = err
-			return
+		// Separate alpha and color channels. The filter of each row applies
+		// to both, since PNG filters work on corresponding bytes of adjacent
+		// pixels.
+		if !decode {
+			var err error
+			data, err = sliceUncompress(data)
+			if err != nil {
+				f.err = err
+				return
+			}
 		}
 		var color, alpha bytes.Buffer
-		if ct == 4 {
-			// Gray image
-			width := int(w)
-			height := int(h)
-			length := 2 * width
-			var pos, elPos int
-			for i := 0; i < height; i++ {
-				pos = (1 + length) * i
-				color.WriteByte(data[pos])
-				alpha.WriteByte(data[pos])
-				elPos = pos + 1
-				for k := 0; k < width; k++ {
-					color.WriteByte(data[elPos])
-					alpha.WriteByte(data[elPos+1])
-					elPos += 2
-				}
-			}
-		} else {
-			// RGB image
-			width := int(w)
-			height := int(h)
-			length := 4 * width
-			var pos, elPos int
-			for i := 0; i < height; i++ {
-				pos = (1 + length) * i
-				color.WriteByte(data[pos])
-				alpha.WriteByte(data[pos])
-				elPos = pos + 1
-				for k := 0; k < width; k++ {
-					color.Write(data[elPos : elPos+3])
-					alpha.WriteByte(data[elPos+3])
-					elPos += 4
-				}
+		width := int(w)
+		height := int(h)
+		// Bytes per sample, and per color of a pixel
+		n := int(bpc) / 8
+		colorLen := (channels - 1) * n
+		length := (colorLen + n) * width
+		if len(data) < (1+length)*height {
+			f.err = fmt.Errorf("truncated image data in PNG buffer")
+			return
+		}
+		var pos, elPos int
+		for i := 0; i < height; i++ {
+			pos = (1 + length) * i
+			color.WriteByte(data[pos])
+			alpha.WriteByte(data[pos])
+			elPos = pos + 1
+			for k := 0; k < width; k++ {
+				color.Write(data[elPos : elPos+colorLen])
+				alpha.Write(data[elPos+colorLen : elPos+colorLen+n])
+				elPos += colorLen + n
 			}
 		}
 		data = sliceCompress(color.Bytes())
@@ -211,3 +239,167 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 	info.data = data
 	return
 }
+
+// pngChannels holds the number of samples per pixel of each PNG color type.
+var pngChannels = map[byte]int{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}
+
+// pngBitDepthValid returns true if bpc is a bit depth that the PNG
+// specification allows for color type ct.
+func pngBitDepthValid(ct, bpc byte) bool {
+	switch ct {
+	case 0:
+		return bpc == 1 || bpc == 2 || bpc == 4 || bpc == 8 || bpc == 16
+	case 3:
+		return bpc == 1 || bpc == 2 || bpc == 4 || bpc == 8
+	}
+	return bpc == 8 || bpc == 16
+}
+
+// pngPasses holds the starting column and row, and the column and row
+// increments, of the seven passes of an Adam7-interlaced image.
+var pngPasses = [7][4]int{
+	{0, 0, 8, 8}, {4, 0, 8, 8}, {0, 4, 4, 8}, {2, 0, 4, 4},
+	{0, 2, 2, 4}, {1, 0, 2, 2}, {0, 1, 1, 2},
+}
+
+// pngDecode returns the uncompressed image data of a PNG image with the
+// filters removed, interlaced passes merged and, if reduce is true, 16-bit
+// samples reduced to 8 bits. Each row of the result begins with a zero filter
+// type byte so that the data can be decoded with the PNG predictors of PDF.
+func pngDecode(data []byte, w, h, bpc, channels int, interlaced, reduce bool) (out []byte, err error) {
+	bitsPerPixel := bpc * channels
+	// Distance, in bytes, to the corresponding byte of the previous pixel
+	bpp := (bitsPerPixel + 7) / 8
+	stride := (w*bitsPerPixel + 7) / 8
+	img := make([]byte, stride*h)
+	passes := pngPasses[:]
+	if !interlaced {
+		passes = [][4]int{{0, 0, 1, 1}}
+	}
+	pos := 0
+	for _, pass := range passes {
+		pw := (w - pass[0] + pass[2] - 1) / pass[2]
+		ph := (h - pass[1] + pass[3] - 1) / pass[3]
+		if pw <= 0 || ph <= 0 {
+			continue
+		}
+		rowLen := (pw*bitsPerPixel + 7) / 8
+		prev := make([]byte, rowLen)
+		for y := 0; y < ph; y++ {
+			if pos+1+rowLen > len(data) {
+				return nil, fmt.Errorf("truncated image data in PNG buffer")
+			}
+			row := data[pos+1 : pos+1+rowLen]
+			if err = pngUnfilter(data[pos], row, prev, bpp); err != nil {
+				return
+			}
+			pos += 1 + rowLen
+			dst := img[(pass[1]+y*pass[3])*stride:]
+			if !interlaced {
+				copy(dst, row)
+			} else if bitsPerPixel >= 8 {
+				for x := 0; x < pw; x++ {
+					copy(dst[(pass[0]+x*pass[2])*bpp:], row[x*bpp:(x+1)*bpp])
+				}
+			} else {
+				// Pixels smaller than a byte: a single sample of bpc bits
+				mask := byte(1<<uint(bpc) - 1)
+				for x := 0; x < pw; x++ {
+					bit := x * bpc
+					v := row[bit/8] >> uint(8-bpc-bit%8) & mask
+					bit = (pass[0] + x*pass[2]) * bpc
+					dst[bit/8] |= v << uint(8-bpc-bit%8)
+				}
+			}
+			prev = row
+		}
+	}
+	if reduce {
+		// Keep the most significant byte of each sample
+		stride /= 2
+		for j := range img[:stride*h] {
+			img[j] = img[2*j]
+		}
+	}
+	out = make([]byte, 0, (stride+1)*h)
+	for y := 0; y < h; y++ {
+		out = append(out, 0)
+		out = append(out, img[y*stride:(y+1)*stride]...)
+	}
+	return
+}
+
+// pngUnfilter reverses the PNG filter of type ft in place on row, given the
+// unfiltered previous row of the same pass and the number of bytes per pixel.
+func pngUnfilter(ft byte, row, prev []byte, bpp int) error {
+	switch ft {
+	case 0:
+	case 1: // Sub
+		for j := bpp; j < len(row); j++ {
+			row[j] += row[j-bpp]
+		}
+	case 2: // Up
+		for j := range row {
+			row[j] += prev[j]
+		}
+	case 3: // Average
+		for j := range row {
+			var left int
+			if j >= bpp {
+				left = int(row[j-bpp])
+			}
+			row[j] += byte((left + int(prev[j])) / 2)
+		}
+	case 4: // Paeth
+		for j := range row {
+			var a, c int
+			if j >= bpp {
+				a, c = int(row[j-bpp]), int(prev[j-bpp])
+			}
+			b := int(prev[j])
+			p := a + b - c
+			pa, pb, pc := intAbs(p-a), intAbs(p-b), intAbs(p-c)
+			if pa <= pb && pa <= pc {
+				row[j] += byte(a)
+			} else if pb <= pc {
+				row[j] += byte(b)
+			} else {
+				row[j] += byte(c)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown filter type %d in PNG image data", ft)
+	}
+	return nil
+}
+
+// pngFilterRows returns the rows of stride bytes in data, each preceded by
+// the type byte of the Paeth filter that is applied to it, so that the result
+// can be decoded with the PNG predictors of PDF. bpp is the number of bytes
+// per pixel.
+func pngFilterRows(data []byte, stride, h, bpp int) (out []byte) {
+	out = make([]byte, 0, (stride+1)*h)
+	prev := make([]byte, stride)
+	for y := 0; y < h; y++ {
+		row := data[y*stride : (y+1)*stride]
+		out = append(out, 4)
+		for j := range row {
+			var a, c int
+			if j >= bpp {
+				a, c = int(row[j-bpp]), int(prev[j-bpp])
+			}
+			b := int(prev[j])
+			p := a + b - c
+			pa, pb, pc := intAbs(p-a), intAbs(p-b), intAbs(p-c)
+			pred := c
+			if pa <= pb && pa <= pc {
+				pred = a
+			} else if pb <= pc {
+				pred = b
+			}
+			out = append(out, row[j]-byte(pred))
+		}
+		prev = row
+	}
+	return
+}
@@ -0,0 +1,293 @@
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SectionType describes a run of consecutive pages that share page geometry,
+// header and footer functions, and page numbering. It is passed to
+// AddSection().
+//
+// OrientationStr and Size specify the page format used by AddPage() within
+// the section. An empty OrientationStr and a zero Size retain the document
+// defaults established in New().
+//
+// LeftMargin, TopMargin, RightMargin and BottomMargin specify the page margins
+// of the section. A value of zero selects the margin of the document, which
+// is the margin that is in effect when the first section begins.
+//
+// HeaderFnc and FooterFnc are called for each page of the section. If
+// HeaderFirstFnc or FooterFirstFnc is not nil, it is called instead for the
+// first page of the section. If HeaderEvenFnc or FooterEvenFnc is not nil, it
+// is called instead for pages with an even section page number. While a
+// section is active, these functions replace the ones set with
+// SetHeaderFunc() and SetFooterFunc(); a nil function means no header or
+// footer.
+//
+// LabelStyle selects the page label numbering style that PDF viewers display
+// for the pages of the section: "D" for decimal numerals, "R" and "r" for
+// upper- and lowercase roman numerals, "A" and "a" for upper- and lowercase
+// letters. An empty string indicates that the labels consist of LabelPrefix
+// only. LabelPrefix is prepended to each page label, for example "A-".
+//
+// StartNumber is the number of the first page of the section. A value of zero
+// is treated as one.
+//
+// AliasNbPagesStr, if not empty, is replaced in each page of the section with
+// the number of pages in the section, in the same way that AliasNbPages()
+// works for the document as a whole.
+type SectionType struct {
+	OrientationStr  string
+	Size            SizeType
+	LeftMargin      float64
+	TopMargin       float64
+	RightMargin     float64
+	BottomMargin    float64
+	HeaderFnc       func()
+	HeaderFirstFnc  func()
+	HeaderEvenFnc   func()
+	FooterFnc       func()
+	FooterFirstFnc  func()
+	FooterEvenFnc   func()
+	LabelStyle      string
+	LabelPrefix     string
+	StartNumber     int
+	AliasNbPagesStr string
+}
+
+// sectionRecType associates a section definition with the document page on
+// which it begins.
+type sectionRecType struct {
+	SectionType
+	firstPage int // 1-based document page number of first page in section
+	lastPage  int // 1-based document page number of last page in section
+	// Left, top, right and bottom margins of the document, which apply
+	// where the section specifies none
+	docMargins [4]float64
+}
+
+// AddSection terminates the current page, if any, and begins a new section
+// with the first page of that section. Subsequent calls to AddPage() add
+// pages to this section until AddSection() is called again. See SectionType
+// for the properties that are scoped to a section.
+//
+// Sections cause a page label dictionary to be written to the document
+// catalog so that viewers can display labels like "iv" or "A-3" rather than
+// the physical page index. If the first section does not begin on the first
+// page of the document, the preceding pages are labeled with decimal numerals.
+func (f *Fpdf) AddSection(sec SectionType) {
+	if f.err != nil {
+		return
+	}
+	switch sec.LabelStyle {
+	case "", "D", "R", "r", "A", "a":
+	default:
+		f.err = fmt.Errorf("unrecognized page label style %s", sec.LabelStyle)
+		return
+	}
+	if sec.StartNumber == 0 {
+		sec.StartNumber = 1
+	} else if sec.StartNumber < 0 {
+		f.err = fmt.Errorf("section start number must be positive")
+		return
+	}
+	if sec.OrientationStr == "" {
+		sec.OrientationStr = f.defOrientation
+	} else {
+		sec.OrientationStr = strings.ToUpper(sec.OrientationStr[0:1])
+	}
+	if sec.Size.Wd == 0 || sec.Size.Ht == 0 {
+		sec.Size = f.defPageSize
+	}
+	f.sectionPending = &sectionRecType{SectionType: sec}
+	f.AddPageFormat(sec.OrientationStr, sec.Size)
+}
+
+// sectionBegin activates a section that has been requested with AddSection().
+// It is called between the end of the previous page and the beginning of the
+// first page of the section.
+func (f *Fpdf) sectionBegin() {
+	sec := f.sectionPending
+	f.sectionPending = nil
+	if sec == nil {
+		return
+	}
+	sec.firstPage = f.page + 1
+	sec.lastPage = sec.firstPage
+	sec.docMargins = [4]float64{f.lMargin, f.tMargin, f.rMargin, f.bMargin}
+	if prev := f.sectionCurrent(); prev != nil {
+		sec.docMargins = prev.docMargins
+	}
+	margins := sec.docMargins
+	for j, m := range []float64{sec.LeftMargin, sec.TopMargin, sec.RightMargin, sec.BottomMargin} {
+		if m > 0 {
+			margins[j] = m
+		}
+	}
+	f.lMargin, f.tMargin, f.rMargin = margins[0], margins[1], margins[2]
+	f.SetAutoPageBreak(f.autoPageBreak, margins[3])
+	f.sections = append(f.sections, sec)
+}
+
+// sectionCurrent returns the active section, or nil if no section has been
+// started.
+func (f *Fpdf) sectionCurrent() *sectionRecType {
+	if len(f.sections) > 0 {
+		return f.sections[len(f.sections)-1]
+	}
+	return nil
+}
+
+// SectionNo returns the 1-based index of the current section, or zero if
+// AddSection() has not been called.
+func (f *Fpdf) SectionNo() int {
+	return len(f.sections)
+}
+
+// SectionPageNo returns the number of the current page within the current
+// section, taking into account the section's StartNumber. If no section has
+// been started, the value returned is the same as PageNo().
+func (f *Fpdf) SectionPageNo() int {
+	sec := f.sectionCurrent()
+	if sec == nil {
+		return f.page
+	}
+	return sec.StartNumber + f.page - sec.firstPage
+}
+
+// SectionPageLabel returns the label of the current page as it is displayed
+// by PDF viewers, for example "iv" or "A-3". If no section has been started,
+// the decimal page number is returned.
+func (f *Fpdf) SectionPageLabel() string {
+	sec := f.sectionCurrent()
+	if sec == nil {
+		return sprintf("%d", f.page)
+	}
+	return sec.LabelPrefix + pageLabelNumber(sec.LabelStyle, f.SectionPageNo())
+}
+
+// pageLabelNumber formats val in the specified page label style.
+func pageLabelNumber(style string, val int) string {
+	switch style {
+	case "D":
+		return sprintf("%d", val)
+	case "R":
+		return romanNumeral(val)
+	case "r":
+		return strings.ToLower(romanNumeral(val))
+	case "A":
+		return alphaNumeral(val)
+	case "a":
+		return strings.ToLower(alphaNumeral(val))
+	}
+	return ""
+}
+
+// romanNumeral returns the uppercase roman numeral representation of val.
+func romanNumeral(val int) string {
+	var b strings.Builder
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	for j, v := range values {
+		for val >= v {
+			b.WriteString(symbols[j])
+			val -= v
+		}
+	}
+	return b.String()
+}
+
+// alphaNumeral returns the letter representation of val used by PDF page
+// labels: A to Z for 1 to 26, AA to ZZ for 27 to 52, and so on.
+func alphaNumeral(val int) string {
+	if val < 1 {
+		return ""
+	}
+	return strings.Repeat(string(rune('A'+(val-1)%26)), (val-1)/26+1)
+}
+
+// sectionHeader calls the header function of the active section that applies
+// to the current page.
+func (f *Fpdf) sectionHeader() {
+	sec := f.sectionCurrent()
+	fnc := sec.HeaderFnc
+	if f.page == sec.firstPage && sec.HeaderFirstFnc != nil {
+		fnc = sec.HeaderFirstFnc
+	} else if f.SectionPageNo()%2 == 0 && sec.HeaderEvenFnc != nil {
+		fnc = sec.HeaderEvenFnc
+	}
+	if fnc != nil {
+		fnc()
+	}
+}
+
+// sectionFooter calls the footer function that applies to the current page.
+// It returns false if no section is active, in which case the document
+// footer function is used.
+func (f *Fpdf) sectionFooter() bool {
+	sec := f.sectionCurrent()
+	if sec == nil {
+		return false
+	}
+	sec.lastPage = f.page
+	fnc := sec.FooterFnc
+	if f.page == sec.firstPage && sec.FooterFirstFnc != nil {
+		fnc = sec.FooterFirstFnc
+	} else if f.SectionPageNo()%2 == 0 && sec.FooterEvenFnc != nil {
+		fnc = sec.FooterEvenFnc
+	}
+	if fnc != nil {
+		fnc()
+	}
+	return true
+}
+
+// sectionReplaceAliases replaces each section's page count alias in the pages
+// of that section.
+func (f *Fpdf) sectionReplaceAliases() {
+	for _, sec := range f.sections {
+		if sec.AliasNbPagesStr == "" {
+			continue
+		}
+		nbStr := sprintf("%d", sec.lastPage-sec.firstPage+1)
+		for mode := 0; mode < 2; mode++ {
+			alias, replacement := sec.AliasNbPagesStr, nbStr
+			if mode == 1 {
+				alias = utf8toutf16(alias, false)
+				replacement = utf8toutf16(replacement, false)
+			}
+			for n := sec.firstPage; n <= sec.lastPage; n++ {
+				s := f.pages[n].String()
+				if strings.Contains(s, alias) {
+					s = strings.Replace(s, alias, replacement, -1)
+					f.pages[n].Truncate(0)
+					f.pages[n].WriteString(s)
+				}
+			}
+		}
+	}
+}
+
+// sectionPutCatalog writes the page label number tree to the document
+// catalog.
+func (f *Fpdf) sectionPutCatalog() {
+	if len(f.sections) == 0 {
+		return
+	}
+	var nums fmtBuffer
+	if f.sections[0].firstPage > 1 {
+		nums.printf("0 <</S /D>> ")
+	}
+	for _, sec := range f.sections {
+		nums.printf("%d <<", sec.firstPage-1)
+		if sec.LabelStyle != "" {
+			nums.printf("/S /%s ", sec.LabelStyle)
+		}
+		if sec.LabelPrefix != "" {
+			nums.printf("/P %s ", f.textstring(utf8toutf16(sec.LabelPrefix)))
+		}
+		nums.printf("/St %d>> ", sec.StartNumber)
+	}
+	f.outf("/PageLabels <</Nums [%s]>>", strings.TrimSpace(nums.String()))
+}
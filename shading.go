@@ -0,0 +1,389 @@
+package gofpdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// shadingType is a smooth shading that is used as the paint of a pattern.
+type shadingType struct {
+	tp      int    // 2: axial, 3: radial, 4: triangle mesh, 5: lattice mesh, 6: Coons patch mesh
+	csStr   string // device color space, or empty for a spot color
+	spotStr string // name of the spot color
+	dictStr string // entries that depend on the type
+	data    []byte // vertices and patches of a mesh
+}
+
+// SpotColor is a tint of a spot color that has been registered with
+// AddSpotColor(). It can be used as a color of a gradient or mesh shading.
+// The value for Tint ranges from 0 (no intensity) to 100 (full intensity).
+type SpotColor struct {
+	Name string
+	Tint byte
+}
+
+// RGBA implements the color.Color interface. The components of a spot color
+// are not known to SpotColor, so the tint is returned as a gray level.
+func (c SpotColor) RGBA() (r, g, b, a uint32) {
+	v := uint32(100-byteBound(c.Tint)) * 0xffff / 100
+	return v, v, v, 0xffff
+}
+
+// GradientStop is a color stop of a gradient. Pos is the position of the stop
+// along the gradient, from 0 at its start to 1 at its end.
+type GradientStop struct {
+	Pos   float64
+	Color color.Color
+}
+
+// GradientOptions specifies the colors of a gradient. See
+// CreateLinearGradient() and CreateRadialGradient().
+//
+// Stops holds at least two color stops. Colors are blended between adjacent
+// stops; the color of the first stop is used before it, and the color of the
+// last stop after it. Two stops at the same position make an abrupt change of
+// color.
+//
+// ExtendStart and ExtendEnd extend the gradient beyond its start and end with
+// the colors of the first and last stops. Areas beyond an end that is not
+// extended are left unpainted.
+type GradientOptions struct {
+	Stops                  []GradientStop
+	ExtendStart, ExtendEnd bool
+}
+
+// MeshVertex is a vertex of a mesh shading. X and Y are its position in user
+// units and Color is its color.
+type MeshVertex struct {
+	X, Y  float64
+	Color color.Color
+}
+
+// CoonsPatch is a patch of a Coons patch mesh shading. Its boundary is made
+// up of four cubic Bézier curves: the first goes from the corner Points[0]
+// to the corner Points[3] with the control points Points[1] and Points[2],
+// the second continues from Points[3] to Points[6], the third to Points[9]
+// and the fourth returns to Points[0] with the control points Points[10] and
+// Points[11]. Colors are the colors of the corners Points[0], Points[3],
+// Points[6] and Points[9], in that order. Positions are in user units.
+type CoonsPatch struct {
+	Points [12]PointType
+	Colors [4]color.Color
+}
+
+// CreateLinearGradient creates a pattern that paints a linear (axial)
+// gradient and returns its identifier, which is passed to SetFillPattern() or
+// SetDrawPattern() to paint any path with the gradient. The gradient runs from
+// the point (x1, y1) to the point (x2, y2), in user units on the current
+// page, and its colors are blended perpendicularly to this vector. The colors
+// are specified by options; see GradientOptions. See CreateMeshShading() for
+// the color space of a gradient, which allows CMYK and spot colors.
+func (f *Fpdf) CreateLinearGradient(x1, y1, x2, y2 float64, options GradientOptions) (patternID int) {
+	coordStr := sprintf("/Coords [%.5f %.5f %.5f %.5f]", x1*f.k, (f.h-y1)*f.k, x2*f.k, (f.h-y2)*f.k)
+	return f.addGradient(2, coordStr, options)
+}
+
+// CreateRadialGradient creates a pattern that paints a radial gradient and
+// returns its identifier, which is passed to SetFillPattern() or
+// SetDrawPattern() to paint any path with the gradient. The gradient blends
+// its colors from the circle with center (x1, y1) and radius r1 to the circle
+// with center (x2, y2) and radius r2, in user units on the current page. A
+// radius of zero makes a point. The colors are specified by options; see
+// GradientOptions.
+//
+// The CreateLinearGradient() example demonstrates this method.
+func (f *Fpdf) CreateRadialGradient(x1, y1, r1, x2, y2, r2 float64, options GradientOptions) (patternID int) {
+	coordStr := sprintf("/Coords [%.5f %.5f %.5f %.5f %.5f %.5f]", x1*f.k, (f.h-y1)*f.k, r1*f.k,
+		x2*f.k, (f.h-y2)*f.k, r2*f.k)
+	return f.addGradient(3, coordStr, options)
+}
+
+// addGradient adds a pattern that paints an axial (tp 2) or radial (tp 3)
+// shading with the coordinates coordStr.
+func (f *Fpdf) addGradient(tp int, coordStr string, options GradientOptions) int {
+	if f.err != nil {
+		return 0
+	}
+	if len(options.Stops) < 2 {
+		f.SetErrorf("a gradient requires at least two color stops")
+		return 0
+	}
+	stops := make([]GradientStop, len(options.Stops))
+	copy(stops, options.Stops)
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].Pos < stops[j].Pos })
+	// The colors of the first and last stops are used up to the ends of the
+	// gradient
+	if first := stops[0]; first.Pos > 0 {
+		stops = append([]GradientStop{{0, first.Color}}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.Pos < 1 {
+		stops = append(stops, GradientStop{1, last.Color})
+	}
+	clrs := make([]color.Color, len(stops))
+	for j, s := range stops {
+		clrs[j] = s.Color
+	}
+	sh := shadingType{tp: tp}
+	comps := f.shadingColors(&sh, clrs)
+	if f.err != nil {
+		return 0
+	}
+	// Each pair of adjacent stops is blended by an exponential interpolation
+	// function; the functions are stitched together at the stops in between
+	var fnList, boundList, encodeList bytes.Buffer
+	for j := 1; j < len(stops); j++ {
+		fmt.Fprintf(&fnList, "<</FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1>>",
+			componentStr(comps[j-1]), componentStr(comps[j]))
+		if j > 1 {
+			fmt.Fprintf(&boundList, " %.5f", math.Max(0, math.Min(1, stops[j-1].Pos)))
+		}
+		encodeList.WriteString(" 0 1")
+	}
+	sh.dictStr = sprintf("%s /Function <</FunctionType 3 /Domain [0 1] /Functions [%s] "+
+		"/Bounds [%s] /Encode [%s]>> /Extend [%v %v]", coordStr, fnList.String(),
+		bytes.TrimSpace(boundList.Bytes()), bytes.TrimSpace(encodeList.Bytes()),
+		options.ExtendStart, options.ExtendEnd)
+	return f.addShading(sh)
+}
+
+// CreateMeshShading creates a pattern that paints a free-form triangle mesh
+// shading and returns its identifier, which is passed to SetFillPattern() or
+// SetDrawPattern() to paint any path with the shading. Each triangle is
+// painted by blending the colors of its three vertices, which are positioned
+// in user units on the current page. Areas outside of the triangles are left
+// unpainted.
+//
+// The colors of a shading are all in the same color space. If they are all of
+// type SpotColor, with the same name, the shading is painted with tints of
+// the spot color. If they are all of type color.CMYK, the shading is painted
+// in the DeviceCMYK color space, and otherwise in the DeviceRGB color space.
+// The alpha components of colors are ignored.
+func (f *Fpdf) CreateMeshShading(triangles [][3]MeshVertex) (patternID int) {
+	if len(triangles) == 0 {
+		f.SetErrorf("a mesh shading requires at least one triangle")
+		return
+	}
+	// Each vertex has a flag; a flag of zero for every vertex makes
+	// triangles that share no vertices
+	var list []meshRecord
+	for _, t := range triangles {
+		for _, v := range t {
+			list = append(list, meshRecord{[]PointType{{X: v.X, Y: v.Y}}, []color.Color{v.Color}})
+		}
+	}
+	return f.addMeshShading(4, list, true, "")
+}
+
+// CreateLatticeMeshShading creates a pattern that paints a lattice-form mesh
+// shading and returns its identifier, which is passed to SetFillPattern() or
+// SetDrawPattern() to paint any path with the shading. The vertices are
+// arranged in rows of equal length, at least two rows of at least two
+// vertices; each quadrilateral formed by two adjacent vertices of a row and
+// the corresponding vertices of the next row is painted as two triangles that
+// blend the colors of their vertices. Positions are in user units on the
+// current page. See CreateMeshShading() for the colors of a shading.
+func (f *Fpdf) CreateLatticeMeshShading(rows [][]MeshVertex) (patternID int) {
+	if len(rows) < 2 || len(rows[0]) < 2 {
+		f.SetErrorf("a lattice mesh shading requires at least two rows of two vertices")
+		return
+	}
+	var list []meshRecord
+	for _, row := range rows {
+		if len(row) != len(rows[0]) {
+			f.SetErrorf("the rows of a lattice mesh shading differ in length")
+			return
+		}
+		for _, v := range row {
+			list = append(list, meshRecord{[]PointType{{X: v.X, Y: v.Y}}, []color.Color{v.Color}})
+		}
+	}
+	return f.addMeshShading(5, list, false, sprintf(" /VerticesPerRow %d", len(rows[0])))
+}
+
+// CreateCoonsPatchShading creates a pattern that paints a Coons patch mesh
+// shading and returns its identifier, which is passed to SetFillPattern() or
+// SetDrawPattern() to paint any path with the shading. Each patch is bounded
+// by four Bézier curves and is painted by blending the colors of its corners
+// across its area; see CoonsPatch. Positions are in user units on the current
+// page. See CreateMeshShading() for the colors of a shading.
+func (f *Fpdf) CreateCoonsPatchShading(patches []CoonsPatch) (patternID int) {
+	if len(patches) == 0 {
+		f.SetErrorf("a Coons patch shading requires at least one patch")
+		return
+	}
+	var list []meshRecord
+	for _, p := range patches {
+		list = append(list, meshRecord{p.Points[:], p.Colors[:]})
+	}
+	return f.addMeshShading(6, list, true, "")
+}
+
+// meshRecord is a vertex or a patch of a mesh shading, with its positions in
+// user units and its colors.
+type meshRecord struct {
+	points []PointType
+	clrs   []color.Color
+}
+
+// addMeshShading adds a pattern that paints a mesh shading of type tp made up
+// of list. If flagged is true, each record begins with a flag of zero, which
+// starts a new triangle or patch. dictStr holds further entries of the
+// shading dictionary.
+func (f *Fpdf) addMeshShading(tp int, list []meshRecord, flagged bool, dictStr string) int {
+	if f.err != nil {
+		return 0
+	}
+	var clrs []color.Color
+	for _, r := range list {
+		clrs = append(clrs, r.clrs...)
+	}
+	sh := shadingType{tp: tp}
+	comps := f.shadingColors(&sh, clrs)
+	if f.err != nil {
+		return 0
+	}
+	// Positions are encoded as fractions of the range that holds them all
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, r := range list {
+		for _, pt := range r.points {
+			x, y := pt.X*f.k, (f.h-pt.Y)*f.k
+			x0, x1 = math.Min(x0, x), math.Max(x1, x)
+			y0, y1 = math.Min(y0, y), math.Max(y1, y)
+		}
+	}
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	var buf bytes.Buffer
+	encode := func(v, lo, hi float64) {
+		binary.Write(&buf, binary.BigEndian, uint32(math.Round((v-lo)/(hi-lo)*math.MaxUint32)))
+	}
+	c := 0
+	for _, r := range list {
+		if flagged {
+			buf.WriteByte(0)
+		}
+		for _, pt := range r.points {
+			encode(pt.X*f.k, x0, x1)
+			encode((f.h-pt.Y)*f.k, y0, y1)
+		}
+		for range r.clrs {
+			for _, val := range comps[c] {
+				binary.Write(&buf, binary.BigEndian, uint16(math.Round(val*math.MaxUint16)))
+			}
+			c++
+		}
+	}
+	sh.dictStr = sprintf("/BitsPerCoordinate 32 /BitsPerComponent 16%s /Decode [%.5f %.5f %.5f %.5f",
+		dictStr, x0, x1, y0, y1)
+	if flagged {
+		sh.dictStr = "/BitsPerFlag 8 " + sh.dictStr
+	}
+	for range comps[0] {
+		sh.dictStr += " 0 1"
+	}
+	sh.dictStr += "]"
+	sh.data = buf.Bytes()
+	return f.addShading(sh)
+}
+
+// shadingColors sets the color space of sh from the colors clrs and returns
+// their components.
+func (f *Fpdf) shadingColors(sh *shadingType, clrs []color.Color) (comps [][]float64) {
+	spot, cmyk := 0, 0
+	for _, clr := range clrs {
+		switch c := clr.(type) {
+		case nil:
+			f.SetErrorf("a shading color is missing")
+			return
+		case SpotColor:
+			if spot == 0 {
+				sh.spotStr = c.Name
+			} else if c.Name != sh.spotStr {
+				f.SetErrorf("the spot colors of a shading differ")
+				return
+			}
+			spot++
+		case color.CMYK:
+			cmyk++
+		}
+	}
+	switch {
+	case spot > 0:
+		if spot < len(clrs) {
+			f.SetErrorf("spot colors of a shading cannot be mixed with other colors")
+			return
+		}
+		if _, ok := f.getSpotColor(sh.spotStr); !ok {
+			return
+		}
+	case cmyk == len(clrs):
+		sh.csStr = "DeviceCMYK"
+	default:
+		sh.csStr = "DeviceRGB"
+	}
+	for _, clr := range clrs {
+		switch {
+		case spot > 0:
+			comps = append(comps, []float64{float64(byteBound(clr.(SpotColor).Tint)) / 100})
+		case sh.csStr == "DeviceCMYK":
+			c := clr.(color.CMYK)
+			comps = append(comps, []float64{float64(c.C) / 255, float64(c.M) / 255,
+				float64(c.Y) / 255, float64(c.K) / 255})
+		default:
+			c := color.NRGBAModel.Convert(clr).(color.NRGBA)
+			comps = append(comps, []float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255})
+		}
+	}
+	return
+}
+
+// componentStr returns the color components list as they are written in a
+// function dictionary.
+func componentStr(list []float64) string {
+	var buf bytes.Buffer
+	for j, v := range list {
+		if j > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%.3f", v)
+	}
+	return buf.String()
+}
+
+// addShading adds a pattern that paints sh and returns its identifier.
+func (f *Fpdf) addShading(sh shadingType) int {
+	f.patternList = append(f.patternList, patternType{shading: &sh})
+	return len(f.patternList) - 1
+}
+
+// putShading writes the shading object of a shading pattern.
+func (f *Fpdf) putShading(sh *shadingType) {
+	csStr := "/" + sh.csStr
+	if sh.spotStr != "" {
+		csStr = sprintf("%d 0 R", f.spotColorMap[sh.spotStr].objID)
+	}
+	f.newobj()
+	if sh.data == nil {
+		f.outf("<</ShadingType %d /ColorSpace %s %s>>", sh.tp, csStr, sh.dictStr)
+	} else {
+		data := sh.data
+		filter := ""
+		if f.compress {
+			data = sliceCompress(data)
+			filter = "/Filter /FlateDecode "
+		}
+		f.outf("<<%s/ShadingType %d /ColorSpace %s %s", filter, sh.tp, csStr, sh.dictStr)
+		f.outf("/Length %d>>", len(data))
+		f.putstream(data)
+	}
+	f.out("endobj")
+}
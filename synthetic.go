@@ -0,0 +1,103 @@
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// syntheticBoldStroke is the width of the outline drawn around glyphs of
+	// synthetic bold text, relative to the font size
+	syntheticBoldStroke = 0.03
+	// syntheticItalicSkew is the horizontal shear applied to synthetic italic
+	// text; it slants glyphs by about 12 degrees
+	syntheticItalicSkew = 0.21
+)
+
+// AddSyntheticFontStyle registers a pseudo-style for the font family
+// familyStr. styleStr is "B" for bold, "I" for italic, or "BI" or "IB" for
+// bold and italic combined. After registration, SetFont(), SetFontStyle() and
+// the basic HTML renderer accept the style even if no font file has been
+// added for it. Text in a synthetic style is rendered with the glyphs of
+// another style of the family: bold is simulated by filling and stroking the
+// glyph outlines with a line width proportional to the font size, and italic
+// by slanting the glyphs with a skewed text matrix. Character widths are those
+// of the underlying font, so GetStringWidth() and the line breaking of
+// MultiCell() and Write() are unaffected.
+//
+// A font that has been added for the style with AddFont() or AddUTF8Font()
+// takes precedence over the pseudo-style. For "BI", a bold or italic font of
+// the family is used as the basis if one has been added, so that only the
+// missing part of the style is simulated.
+//
+// The AddSyntheticFontStyle example demonstrates this method.
+func (f *Fpdf) AddSyntheticFontStyle(familyStr, styleStr string) {
+	if f.err != nil {
+		return
+	}
+	styleStr = strings.ToUpper(styleStr)
+	if styleStr == "IB" {
+		styleStr = "BI"
+	}
+	switch styleStr {
+	case "B", "I", "BI":
+	default:
+		f.err = fmt.Errorf("invalid synthetic font style %s", styleStr)
+		return
+	}
+	if f.syntheticStyles == nil {
+		f.syntheticStyles = make(map[string]bool)
+	}
+	f.syntheticStyles[getFontKey(fontFamilyEscape(familyStr), styleStr)] = true
+}
+
+// syntheticFont returns the key of the font that is used to render the
+// pseudo-style styleStr of familyStr, and the part of the style that is
+// simulated. ok is false if no suitable font has been added.
+func (f *Fpdf) syntheticFont(familyStr, styleStr string) (fontKey, synthStr string, ok bool) {
+	for _, baseStr := range []string{"B", "I", ""} {
+		if !strings.Contains(styleStr, baseStr) || baseStr == styleStr {
+			continue
+		}
+		if _, ok = f.fonts[familyStr+baseStr]; ok {
+			return familyStr + baseStr, strings.Replace(styleStr, baseStr, "", 1), true
+		}
+	}
+	return
+}
+
+// textOrigin returns the text operator that positions a text object at x, y,
+// which are specified in points. The operator skews the text if the current
+// font is a synthetic italic.
+func (f *Fpdf) textOrigin(x, y float64) string {
+	if strings.Contains(f.fontSynthetic, "I") {
+		return sprintf("1 0 %.2f 1 %.2f %.2f Tm", syntheticItalicSkew, x, y)
+	}
+	return sprintf("%.2f %.2f Td", x, y)
+}
+
+// syntheticBoldBegin returns the operators that precede a text object to
+// render it in synthetic bold, or an empty string if the current font does not
+// require it. The outline of the glyphs is stroked in the text color.
+func (f *Fpdf) syntheticBoldBegin() string {
+	if !strings.Contains(f.fontSynthetic, "B") {
+		return ""
+	}
+	fields := strings.Fields(f.color.text.str)
+	for j, field := range fields {
+		if field[0] >= 'a' && field[0] <= 'z' {
+			// Nonstroking color operator to stroking one, for example rg to RG
+			fields[j] = strings.ToUpper(field)
+		}
+	}
+	return sprintf("q %s %.2f w 2 Tr ", strings.Join(fields, " "), syntheticBoldStroke*f.fontSizePt)
+}
+
+// syntheticBoldEnd returns the operator that follows a text object started
+// after syntheticBoldBegin().
+func (f *Fpdf) syntheticBoldEnd() string {
+	if !strings.Contains(f.fontSynthetic, "B") {
+		return ""
+	}
+	return " Q"
+}
@@ -140,6 +140,9 @@ func (f *Fpdf) templateFontCatalog() {
 	for _, key = range keyList {
 		font = f.fonts[key]
 		f.outf("/F%s %d 0 R", font.i, font.N)
+		if n := f.verticalFonts[font.i]; n > 0 {
+			f.outf("/F%sV %d 0 R", font.i, n)
+		}
 	}
 	f.out(">>")
 }
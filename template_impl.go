@@ -290,6 +290,10 @@ func (t *Tpl) loadParamsFromFpdf(f *Fpdf) {
 	t.Fpdf.color.text = f.color.text
 
 	t.Fpdf.fonts = f.fonts
+	t.Fpdf.verticalFonts = f.verticalFonts
+	t.Fpdf.syntheticStyles = f.syntheticStyles
+	t.Fpdf.fontFallbacks = f.fontFallbacks
+	t.Fpdf.fontSynthetic = f.fontSynthetic
 	t.Fpdf.currentFont = f.currentFont
 	t.Fpdf.fontFamily = f.fontFamily
 	t.Fpdf.fontSize = f.fontSize
@@ -0,0 +1,425 @@
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// TextFrameBoxType specifies one of the linked rectangles of a text frame.
+// Page is the one-based number of the page on which the rectangle is located;
+// zero indicates the page that is current when text is written. X, Y, Wd and
+// Ht specify the position and size of the rectangle in the unit of measure
+// specified in New().
+type TextFrameBoxType struct {
+	Page         int
+	X, Y, Wd, Ht float64
+}
+
+// TextFrameType is a chain of linked rectangles into which text flows. Text
+// that does not fit into one rectangle continues in the next one, which may
+// be located on a different page. Use TextFrameNew() to create an instance.
+type TextFrameType struct {
+	pdf   *Fpdf
+	boxes []TextFrameBoxType
+	box   int     // index of the box that receives the next line
+	y     float64 // top of the next line in current box
+	Link  struct {
+		ClrR, ClrG, ClrB int
+	}
+}
+
+// textFramePieceType is a run of text that is rendered without an
+// intervening line break in a single style.
+type textFramePieceType struct {
+	str     string
+	style   string // combination of "B", "I" and "U"
+	linkStr string
+	space   bool // piece is preceded by a space
+	wd      float64
+}
+
+// textFrameUnitType is a sequence of pieces that can not be separated by a
+// line break. A unit without pieces represents a forced line break.
+type textFrameUnitType []textFramePieceType
+
+// TextFrameNew returns a text frame that flows text through the rectangles
+// specified by boxes, in order. The pages referenced by the rectangles must
+// exist when text is written to the frame. More rectangles can be appended
+// with AddBox().
+//
+// The TextFrameNew example demonstrates this method.
+func (f *Fpdf) TextFrameNew(boxes ...TextFrameBoxType) (tf *TextFrameType) {
+	tf = &TextFrameType{pdf: f, boxes: boxes}
+	if len(boxes) > 0 {
+		tf.y = boxes[0].Y
+	}
+	tf.Link.ClrR, tf.Link.ClrG, tf.Link.ClrB = 0, 0, 128
+	return
+}
+
+// AddBox appends a rectangle to the chain of the text frame. Text that has
+// overflowed the frame previously is not moved into it; pass the overflow
+// text returned by Write() or WriteHTML() to continue the flow.
+func (tf *TextFrameType) AddBox(box TextFrameBoxType) {
+	if tf.box >= len(tf.boxes) {
+		tf.y = box.Y
+	}
+	tf.boxes = append(tf.boxes, box)
+}
+
+// Full returns true if no space remains in the text frame.
+func (tf *TextFrameType) Full() bool {
+	return tf.box >= len(tf.boxes)
+}
+
+// Write flows the plain text specified by txtStr into the text frame using
+// the current font, beginning below any text that has been previously written
+// to the frame. lineHt is the height of each line in the unit of measure
+// specified in New(). alignStr specifies the horizontal alignment of lines:
+// "L" for left, "C" for center, "R" for right and "J" for justified, in which
+// case the last line of each paragraph is left aligned. Newline characters
+// start new paragraphs. A word that is wider than a box is broken where it
+// reaches the edge of the box. Writing to the frame never breaks pages, even
+// if a box extends below the page break margin.
+//
+// Text that does not fit in the frame is returned in overflowStr. Runs of
+// white space in the overflow text are replaced by single spaces.
+func (tf *TextFrameType) Write(lineHt float64, txtStr, alignStr string) (overflowStr string) {
+	var units []textFrameUnitType
+	for j, para := range strings.Split(strings.Replace(txtStr, "\r", "", -1), "\n") {
+		if j > 0 {
+			units = append(units, textFrameUnitType{})
+		}
+		for _, word := range strings.FieldsFunc(para, unicode.IsSpace) {
+			units = append(units, textFrameUnitType{{str: word, space: true}})
+		}
+	}
+	units = tf.write(lineHt, units, alignStr)
+	return textFrameString(units, false)
+}
+
+// WriteHTML flows text encoded with a basic subset of HTML into the text
+// frame in the same way as Write(). Bold (B), italic (I), underscore (U),
+// hyperlink (A) and line break (BR) tags are supported. The ClrR, ClrG and
+// ClrB fields of the Link structure (0 through 255) define the color of
+// hyperlinks.
+//
+// Text that does not fit in the frame is returned in overflowStr using the
+// same subset of HTML.
+func (tf *TextFrameType) WriteHTML(lineHt float64, htmlStr, alignStr string) (overflowStr string) {
+	var units []textFrameUnitType
+	var boldLvl, italicLvl, underscoreLvl int
+	var hrefStr string
+	style := func() (styleStr string) {
+		if boldLvl > 0 {
+			styleStr += "B"
+		}
+		if italicLvl > 0 {
+			styleStr += "I"
+		}
+		if underscoreLvl > 0 || hrefStr != "" {
+			styleStr += "U"
+		}
+		return
+	}
+	space := true
+	for _, el := range HTMLBasicTokenize(htmlStr) {
+		switch el.Cat {
+		case 'T':
+			str := el.Str
+			for len(str) > 0 {
+				pos := strings.IndexFunc(str, unicode.IsSpace)
+				if pos == 0 {
+					space = true
+					str = strings.TrimLeftFunc(str, unicode.IsSpace)
+					continue
+				}
+				if pos < 0 {
+					pos = len(str)
+				}
+				pc := textFramePieceType{str: str[:pos], style: style(), linkStr: hrefStr, space: space}
+				if !space && len(units) > 0 && len(units[len(units)-1]) > 0 {
+					units[len(units)-1] = append(units[len(units)-1], pc)
+				} else {
+					units = append(units, textFrameUnitType{pc})
+				}
+				space = false
+				str = str[pos:]
+			}
+		case 'O':
+			switch el.Str {
+			case "b":
+				boldLvl++
+			case "i":
+				italicLvl++
+			case "u":
+				underscoreLvl++
+			case "a":
+				hrefStr = el.Attr["href"]
+			case "br":
+				units = append(units, textFrameUnitType{})
+				space = true
+			}
+		case 'C':
+			switch el.Str {
+			case "b":
+				boldLvl--
+			case "i":
+				italicLvl--
+			case "u":
+				underscoreLvl--
+			case "a":
+				hrefStr = ""
+			}
+		}
+	}
+	units = tf.write(lineHt, units, alignStr)
+	return textFrameString(units, true)
+}
+
+// textFrameString reconstructs text from units that have not been written.
+func textFrameString(units []textFrameUnitType, html bool) string {
+	var b strings.Builder
+	for j, unit := range units {
+		if len(unit) == 0 {
+			if html {
+				b.WriteString("<br>")
+			} else {
+				b.WriteString("\n")
+			}
+			continue
+		}
+		for k, pc := range unit {
+			if k == 0 && j > 0 && len(units[j-1]) > 0 && pc.space {
+				b.WriteString(" ")
+			}
+			str := pc.str
+			if html {
+				for _, c := range pc.style {
+					if c != 'U' || pc.linkStr == "" {
+						tag := strings.ToLower(string(c))
+						str = "<" + tag + ">" + str + "</" + tag + ">"
+					}
+				}
+				if pc.linkStr != "" {
+					str = "<a href=\"" + pc.linkStr + "\">" + str + "</a>"
+				}
+			}
+			b.WriteString(str)
+		}
+	}
+	return b.String()
+}
+
+// write lays out units in the frame's boxes and returns the units that do
+// not fit.
+func (tf *TextFrameType) write(lineHt float64, units []textFrameUnitType, alignStr string) []textFrameUnitType {
+	f := tf.pdf
+	if f.err != nil {
+		return units
+	}
+	if f.page == 0 {
+		f.err = fmt.Errorf("text frame requires an open page")
+		return units
+	}
+	alignStr = strings.ToUpper(alignStr)
+	// Save state that is modified while writing
+	page := f.page
+	x, y := f.x, f.y
+	trigger := f.pageBreakTrigger
+	font, familyStr, fontStyle, sizePt := f.currentFont, f.fontFamily, f.fontStyle, f.fontSizePt
+	underline, strikeout, synthStr, utf8 := f.underline, f.strikeout, f.fontSynthetic, f.isCurrentUTF8
+	styleStr := fontStyle
+	if f.underline {
+		styleStr += "U"
+	}
+	if f.strikeout {
+		styleStr += "S"
+	}
+	textClr, colorFlag := f.color.text, f.colorFlag
+	// Lines are placed in the boxes, never by page breaks
+	f.pageBreakTrigger = math.MaxFloat64
+	curStyleStr := styleStr
+	setStyle := func(pcStyleStr string) {
+		s := styleStr
+		for _, c := range pcStyleStr {
+			if !strings.ContainsRune(s, c) {
+				s += string(c)
+			}
+		}
+		if s != curStyleStr {
+			f.SetFont(familyStr, s, sizePt)
+			curStyleStr = s
+		}
+	}
+	// Measure pieces
+	for _, unit := range units {
+		for k := range unit {
+			setStyle(unit[k].style)
+			unit[k].wd = f.GetStringWidth(unit[k].str)
+		}
+	}
+	setStyle("")
+	spaceWd := f.GetStringWidth(" ")
+	// splitUnit splits a unit that is wider than wd into a head that fits and
+	// the tail that follows it. The head has at least one character.
+	splitUnit := func(unit textFrameUnitType, wd float64) (head, tail textFrameUnitType, headWd float64) {
+		for k, pc := range unit {
+			if headWd+pc.wd <= wd {
+				head = append(head, pc)
+				headWd += pc.wd
+				continue
+			}
+			setStyle(pc.style)
+			runes := []rune(pc.str)
+			n := 0
+			for n < len(runes) && headWd+f.GetStringWidth(string(runes[:n+1])) <= wd {
+				n++
+			}
+			if n == 0 && len(head) == 0 {
+				n = 1
+			}
+			if n > 0 {
+				hp := pc
+				hp.str = string(runes[:n])
+				hp.wd = f.GetStringWidth(hp.str)
+				head = append(head, hp)
+				headWd += hp.wd
+			}
+			if n < len(runes) {
+				tp := pc
+				tp.str = string(runes[n:])
+				tp.wd = f.GetStringWidth(tp.str)
+				tp.space = false
+				tail = append(tail, tp)
+			}
+			tail = append(tail, unit[k+1:]...)
+			break
+		}
+		return
+	}
+	curPage := 0
+	for len(units) > 0 && tf.box < len(tf.boxes) && f.err == nil {
+		box := tf.boxes[tf.box]
+		if tf.y+lineHt > box.Y+box.Ht+0.001 {
+			tf.box++
+			if tf.box < len(tf.boxes) {
+				tf.y = tf.boxes[tf.box].Y
+			}
+			continue
+		}
+		boxPage := box.Page
+		if boxPage == 0 {
+			boxPage = page
+		}
+		if boxPage != curPage {
+			if boxPage > f.PageCount() {
+				f.err = fmt.Errorf("text frame page %d does not exist", boxPage)
+				break
+			}
+			f.SetPage(boxPage)
+			// The font needs to be selected in the content of each page
+			f.SetFont(familyStr, styleStr, sizePt)
+			curStyleStr = styleStr
+			curPage = boxPage
+		}
+		// Collect units for one line
+		maxWd := box.Wd - 2*f.cMargin
+		var line []textFrameUnitType
+		lineWd := 0.0
+		gapCount := 0
+		paraEnd := false
+		for len(units) > 0 {
+			unit := units[0]
+			if len(unit) == 0 {
+				units = units[1:]
+				paraEnd = true
+				break
+			}
+			unitWd := 0.0
+			for _, pc := range unit {
+				unitWd += pc.wd
+			}
+			if len(line) > 0 {
+				unitWd += spaceWd
+			}
+			if len(line) > 0 && lineWd+unitWd > maxWd {
+				break
+			}
+			if len(line) == 0 && unitWd > maxWd {
+				// A word that is wider than the box continues on the next line
+				head, tail, headWd := splitUnit(unit, maxWd)
+				line = append(line, head)
+				lineWd = headWd
+				if len(tail) > 0 {
+					units[0] = tail
+				} else {
+					units = units[1:]
+				}
+				break
+			}
+			if len(line) > 0 {
+				gapCount++
+			}
+			line = append(line, unit)
+			lineWd += unitWd
+			units = units[1:]
+		}
+		if !paraEnd && len(units) > 0 && len(units[0]) == 0 {
+			// A line break that immediately follows a full line ends it
+			units = units[1:]
+			paraEnd = true
+		} else if len(units) == 0 {
+			paraEnd = true
+		}
+		// Render the line
+		gapWd := spaceWd
+		pos := box.X + f.cMargin
+		switch alignStr {
+		case "C":
+			pos += (maxWd - lineWd) / 2
+		case "R":
+			pos += maxWd - lineWd
+		case "J":
+			if !paraEnd && gapCount > 0 {
+				gapWd += (maxWd - lineWd) / float64(gapCount)
+			}
+		}
+		for j, unit := range line {
+			if j > 0 {
+				pos += gapWd
+			}
+			for _, pc := range unit {
+				setStyle(pc.style)
+				if pc.linkStr != "" {
+					f.SetTextColor(tf.Link.ClrR, tf.Link.ClrG, tf.Link.ClrB)
+				}
+				f.SetXY(pos-f.cMargin, tf.y)
+				f.CellFormat(pc.wd+2*f.cMargin, lineHt, pc.str, "", 0, "L", false, 0, "")
+				if pc.linkStr != "" {
+					f.LinkString(pos, tf.y, pc.wd, lineHt, pc.linkStr)
+					f.color.text, f.colorFlag = textClr, colorFlag
+				}
+				pos += pc.wd
+			}
+		}
+		tf.y += lineHt
+	}
+	// Restore state. The font is selected again in the content of the
+	// caller's page, which may have received lines in other styles.
+	if curPage != 0 {
+		f.SetPage(page)
+	}
+	f.currentFont, f.fontFamily, f.fontStyle = font, familyStr, fontStyle
+	f.fontSizePt, f.fontSize = sizePt, sizePt/f.k
+	f.underline, f.strikeout, f.fontSynthetic, f.isCurrentUTF8 = underline, strikeout, synthStr, utf8
+	if f.currentFont.Name != "" {
+		f.outf("BT /F%s %.2f Tf ET", f.currentFont.i, f.fontSizePt)
+	}
+	f.color.text, f.colorFlag = textClr, colorFlag
+	f.pageBreakTrigger = trigger
+	f.x, f.y = x, y
+	return units
+}
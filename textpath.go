@@ -0,0 +1,522 @@
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TextPath draws the character string specified by txtStr as vector paths
+// rather than as text. The origin (x, y) is on the left of the first character
+// at the baseline, as with Text(). The glyph outlines of the current font, at
+// the current font size, are converted to PDF path operations; the quadratic
+// Bézier curves of TrueType outlines are expressed as equivalent cubic curves.
+// The resulting page contains no text object and does not require the font to
+// be embedded, which suits plotters, cutters and sign-making software that
+// only process paths.
+//
+// styleStr specifies how the outlines are painted, as described for
+// DrawPath(). "F" fills the glyphs with the current fill color and "D"
+// strokes their outlines with the current draw color and line width. The
+// nonzero winding number rule of TrueType and OpenType fonts is used for
+// filling.
+//
+// The current font must have been imported with AddUTF8Font() or one of its
+// variants; the outlines of core fonts and of fonts added with AddFont() are
+// not available. Runes missing from the font are drawn from the fonts set with
+// SetFontFallback(), and a synthetic italic style slants the outlines.
+//
+// The TextPath example demonstrates this method.
+func (f *Fpdf) TextPath(x, y float64, txtStr, styleStr string) {
+	paths := f.TextPathSegments(x, y, txtStr)
+	if f.err != nil || len(paths) == 0 {
+		return
+	}
+	var s fmtBuffer
+	f.pathSegments(&s, paths)
+	s.printf("%s", fillDrawOp(styleStr))
+	f.out(s.String())
+}
+
+// TextPathSegments returns the outlines of the glyphs of txtStr as they would
+// be drawn by TextPath() with the same arguments. Each contour of a glyph is
+// returned as a closed path that begins with an 'M' (moveto: x, y) segment,
+// continues with 'L' (lineto: x, y) and 'C' (cubic Bézier curve: cx0, cy0,
+// cx1, cy1, x1, y1) segments, and ends with a 'Z' (closepath) segment.
+// Coordinates are absolute and use the units and orientation established in
+// New().
+//
+// The paths can be transformed by the caller and drawn with MoveTo(),
+// LineTo(), CurveBezierCubicTo(), ClosePath() and DrawPath(), or used as a
+// clipping path with ClipPath().
+func (f *Fpdf) TextPathSegments(x, y float64, txtStr string) (paths [][]SVGBasicSegmentType) {
+	if f.err != nil {
+		return
+	}
+	if !f.isCurrentUTF8 || f.currentFont.utf8File == nil {
+		f.err = fmt.Errorf("text paths require a font added with AddUTF8Font()")
+		return
+	}
+	if f.isRTL {
+		txtStr = reverseText(txtStr)
+		x -= f.GetStringWidth(txtStr)
+	}
+	skew := 0.0
+	if strings.Contains(f.fontSynthetic, "I") {
+		skew = syntheticItalicSkew
+	}
+	for _, r := range txtStr {
+		font := f.currentFont
+		if fb, ok := f.fallbackFont(r); ok {
+			font = fb
+		}
+		utf := font.utf8File
+		scale := f.fontSize / float64(utf.fontElementSize)
+		if gid, ok := utf.glyphIndex(r); ok {
+			contours, err := utf.glyphOutline(gid)
+			if err != nil {
+				f.err = err
+				return
+			}
+			for _, contour := range contours {
+				path := make([]SVGBasicSegmentType, len(contour))
+				for j, seg := range contour {
+					path[j].Cmd = seg.Cmd
+					for k := 0; k < 2*segmentPointCount(seg.Cmd); k += 2 {
+						path[j].Arg[k] = x + (seg.Arg[k]+skew*seg.Arg[k+1])*scale
+						path[j].Arg[k+1] = y - seg.Arg[k+1]*scale
+					}
+				}
+				paths = append(paths, path)
+			}
+		}
+		if int(r) < len(font.Cw) && font.Cw[r] != 65535 {
+			x += float64(font.Cw[r]) * f.fontSize / 1000
+		}
+	}
+	return
+}
+
+// ClipPath begins a clipping operation in which rendering is confined to the
+// area enclosed by paths, for example the glyph outlines returned by
+// TextPathSegments(). Each path is a sequence of absolute 'M', 'L', 'C', 'Q'
+// (quadratic Bézier curve: cx, cy, x1, y1) and 'Z' segments in the units
+// established in New(), as returned by TextPathSegments() and SVGBasicParse().
+// The enclosed area is determined with the nonzero winding number rule.
+// outline is true to draw the paths with the current draw color and line
+// width. After calling this method, all rendering operations will be clipped.
+// Call ClipEnd() to restore unclipped operations.
+//
+// The TextPath example demonstrates this method.
+func (f *Fpdf) ClipPath(paths [][]SVGBasicSegmentType, outline bool) {
+	f.clipNest++
+	var s fmtBuffer
+	s.printf("q ")
+	f.pathSegments(&s, paths)
+	s.printf("W %s", strIf(outline, "S", "n"))
+	f.out(s.String())
+}
+
+// pathSegments writes the path construction operators of paths to s.
+func (f *Fpdf) pathSegments(s *fmtBuffer, paths [][]SVGBasicSegmentType) {
+	k, h := f.k, f.h
+	for _, path := range paths {
+		var x, y float64
+		for _, seg := range path {
+			a := seg.Arg
+			switch seg.Cmd {
+			case 'M':
+				s.printf("%.5f %.5f m ", a[0]*k, (h-a[1])*k)
+				x, y = a[0], a[1]
+			case 'L':
+				s.printf("%.5f %.5f l ", a[0]*k, (h-a[1])*k)
+				x, y = a[0], a[1]
+			case 'C':
+				s.printf("%.5f %.5f %.5f %.5f %.5f %.5f c ", a[0]*k, (h-a[1])*k,
+					a[2]*k, (h-a[3])*k, a[4]*k, (h-a[5])*k)
+				x, y = a[4], a[5]
+			case 'Q':
+				// Degree elevation of the quadratic curve
+				cx0, cy0 := x+2*(a[0]-x)/3, y+2*(a[1]-y)/3
+				cx1, cy1 := a[2]+2*(a[0]-a[2])/3, a[3]+2*(a[1]-a[3])/3
+				s.printf("%.5f %.5f %.5f %.5f %.5f %.5f c ", cx0*k, (h-cy0)*k,
+					cx1*k, (h-cy1)*k, a[2]*k, (h-a[3])*k)
+				x, y = a[2], a[3]
+			case 'Z':
+				s.printf("h ")
+			}
+		}
+	}
+}
+
+// segmentPointCount returns the number of points in the arguments of a path
+// segment with command cmd.
+func segmentPointCount(cmd byte) int {
+	switch cmd {
+	case 'M', 'L':
+		return 1
+	case 'Q':
+		return 2
+	case 'C':
+		return 3
+	}
+	return 0
+}
+
+// glyphIndex returns the ID of the glyph that the font maps rune r to.
+func (utf *utf8FontFile) glyphIndex(r rune) (gid int, ok bool) {
+	if len(utf.charSymbolDictionary) == 0 {
+		utf.generateCMAP()
+	}
+	gid, ok = utf.charSymbolDictionary[int(r)]
+	return
+}
+
+// glyphOutline returns the contours of glyph gid in font units, with the y
+// axis pointing upward.
+func (utf *utf8FontFile) glyphOutline(gid int) (contours [][]SVGBasicSegmentType, err error) {
+	if contours, ok := utf.outlines[gid]; ok {
+		return contours, nil
+	}
+	if utf.isCFF {
+		contours, err = utf.cffOutline(gid)
+	} else {
+		contours, err = utf.glyfOutline(gid, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("outline of glyph %d: %s", gid, err)
+	}
+	if utf.outlines == nil {
+		utf.outlines = make(map[int][][]SVGBasicSegmentType)
+	}
+	utf.outlines[gid] = contours
+	return
+}
+
+// glyfOutline returns the contours of glyph gid of a TrueType font. Components
+// of composite glyphs are resolved recursively.
+func (utf *utf8FontFile) glyfOutline(gid, depth int) (contours [][]SVGBasicSegmentType, err error) {
+	if depth > 8 {
+		return nil, fmt.Errorf("composite glyphs nested too deeply")
+	}
+	var data []byte
+	if utf.variation != nil && utf.variation.loca != nil {
+		if data, _, _, err = utf.variation.glyph(gid); err != nil {
+			return
+		}
+	} else {
+		if utf.glyfOffsets == nil {
+			if utf.glyfData, utf.glyfOffsets, err = utf.glyphLocations(); err != nil {
+				return
+			}
+		}
+		if gid < 0 || gid+1 >= len(utf.glyfOffsets) {
+			return nil, fmt.Errorf("glyph out of range")
+		}
+		data = utf.glyfData[utf.glyfOffsets[gid]:utf.glyfOffsets[gid+1]]
+	}
+	if len(data) < 10 {
+		return
+	}
+	if data[0]&0x80 == 0 {
+		var endPts []int
+		var points []glyfPointType
+		if endPts, points, _, err = parseSimpleGlyph(data); err != nil {
+			return
+		}
+		return glyfContours(endPts, points), nil
+	}
+	components, offsets, err := parseCompositeGlyph(data)
+	if err != nil {
+		return
+	}
+	for j, c := range components {
+		var sub [][]SVGBasicSegmentType
+		if sub, err = utf.glyfOutline(c.gid, depth+1); err != nil {
+			return
+		}
+		t := c.transform
+		if c.flags&glyfArgsXY == 0 {
+			// Skip the point numbers that precede the transformation
+			t = t[intIf(c.flags&symbolWords != 0, 4, 2):]
+		}
+		xx, yx, xy, yy := 1.0, 0.0, 0.0, 1.0
+		switch {
+		case c.flags&symbolScale != 0 && len(t) >= 2:
+			xx, yy = f2dot14(t, 0), f2dot14(t, 0)
+		case c.flags&symbolAllScale != 0 && len(t) >= 4:
+			xx, yy = f2dot14(t, 0), f2dot14(t, 2)
+		case c.flags&symbol2x2 != 0 && len(t) >= 8:
+			xx, yx, xy, yy = f2dot14(t, 0), f2dot14(t, 2), f2dot14(t, 4), f2dot14(t, 6)
+		}
+		for _, contour := range sub {
+			path := make([]SVGBasicSegmentType, len(contour))
+			for k, seg := range contour {
+				path[k].Cmd = seg.Cmd
+				for p := 0; p < 2*segmentPointCount(seg.Cmd); p += 2 {
+					x, y := seg.Arg[p], seg.Arg[p+1]
+					path[k].Arg[p] = xx*x + xy*y + offsets[j].x
+					path[k].Arg[p+1] = yx*x + yy*y + offsets[j].y
+				}
+			}
+			contours = append(contours, path)
+		}
+	}
+	return
+}
+
+// glyfContours converts the quadratic contours of a simple TrueType glyph to
+// paths of lines and cubic Bézier curves.
+func glyfContours(endPts []int, points []glyfPointType) (contours [][]SVGBasicSegmentType) {
+	mid := func(a, b glyfPointType) glyfPointType {
+		return glyfPointType{x: (a.x + b.x) / 2, y: (a.y + b.y) / 2, onCurve: true}
+	}
+	first := 0
+	for _, last := range endPts {
+		pts := points[first : last+1]
+		first = last + 1
+		if len(pts) == 0 {
+			continue
+		}
+		// Start at an on-curve point, or between two off-curve points if there
+		// is none
+		start := mid(pts[len(pts)-1], pts[0])
+		seq := pts
+		for j, p := range pts {
+			if p.onCurve {
+				start = p
+				seq = append(append([]glyfPointType{}, pts[j+1:]...), pts[:j]...)
+				break
+			}
+		}
+		path := []SVGBasicSegmentType{{Cmd: 'M', Arg: [6]float64{start.x, start.y}}}
+		cur := start
+		var ctrl *glyfPointType
+		quad := func(c, p glyfPointType) {
+			path = append(path, SVGBasicSegmentType{Cmd: 'C', Arg: [6]float64{
+				cur.x + 2*(c.x-cur.x)/3, cur.y + 2*(c.y-cur.y)/3,
+				p.x + 2*(c.x-p.x)/3, p.y + 2*(c.y-p.y)/3, p.x, p.y}})
+			cur = p
+		}
+		for j := range seq {
+			p := seq[j]
+			switch {
+			case p.onCurve && ctrl != nil:
+				quad(*ctrl, p)
+				ctrl = nil
+			case p.onCurve:
+				path = append(path, SVGBasicSegmentType{Cmd: 'L', Arg: [6]float64{p.x, p.y}})
+				cur = p
+			case ctrl != nil:
+				quad(*ctrl, mid(*ctrl, p))
+				ctrl = &seq[j]
+			default:
+				ctrl = &seq[j]
+			}
+		}
+		if ctrl != nil {
+			quad(*ctrl, start)
+		}
+		contours = append(contours, append(path, SVGBasicSegmentType{Cmd: 'Z'}))
+	}
+	return
+}
+
+// cffOutline returns the contours of glyph gid of a font with PostScript
+// outlines.
+func (utf *utf8FontFile) cffOutline(gid int) (contours [][]SVGBasicSegmentType, err error) {
+	if utf.cff == nil {
+		tableName := "CFF "
+		if _, ok := utf.tableDescriptions[tableName]; !ok {
+			tableName = "CFF2"
+		}
+		var coords []float64
+		if utf.variation != nil {
+			coords = utf.variation.coords
+		}
+		if utf.cff, err = cffParse(utf.getTableData(tableName), coords); err != nil {
+			return
+		}
+	}
+	cs, err := utf.cff.charString(gid)
+	if err != nil {
+		return
+	}
+	return cffCharStringOutline(cs, utf.cff.version == 2)
+}
+
+// cffCharStringOutline interprets the path operators of a Type 2 charstring
+// without subroutine calls, as returned by charString(), and returns its
+// contours.
+func cffCharStringOutline(cs []byte, cff2 bool) (contours [][]SVGBasicSegmentType, err error) {
+	var stack []float64
+	var x, y float64
+	var path []SVGBasicSegmentType
+	stems := 0
+	widthDone := cff2
+	closePath := func() {
+		if len(path) > 1 {
+			contours = append(contours, append(path, SVGBasicSegmentType{Cmd: 'Z'}))
+		}
+		path = nil
+	}
+	moveTo := func(dx, dy float64) {
+		closePath()
+		x, y = x+dx, y+dy
+		path = []SVGBasicSegmentType{{Cmd: 'M', Arg: [6]float64{x, y}}}
+	}
+	lineTo := func(dx, dy float64) {
+		x, y = x+dx, y+dy
+		path = append(path, SVGBasicSegmentType{Cmd: 'L', Arg: [6]float64{x, y}})
+	}
+	curveTo := func(dxa, dya, dxb, dyb, dxc, dyc float64) {
+		x0, y0 := x+dxa, y+dya
+		x1, y1 := x0+dxb, y0+dyb
+		x, y = x1+dxc, y1+dyc
+		path = append(path, SVGBasicSegmentType{Cmd: 'C', Arg: [6]float64{x0, y0, x1, y1, x, y}})
+	}
+	// The first stack-clearing operator of a CFF charstring may be preceded
+	// by the advance width, which is recognized by an extra operand
+	dropWidth := func(extra bool) {
+		if !widthDone && extra && len(stack) > 0 {
+			stack = stack[1:]
+		}
+		widthDone = true
+	}
+	for pos := 0; pos < len(cs); {
+		b0 := cs[pos]
+		if b0 >= 32 || b0 == 28 {
+			var operand cffOperandType
+			if operand, pos, err = cffReadOperand(cs, pos, false); err != nil {
+				return
+			}
+			stack = append(stack, operand.val)
+			continue
+		}
+		op := int(b0)
+		pos++
+		if b0 == 12 {
+			if pos >= len(cs) {
+				return nil, fmt.Errorf("truncated CFF charstring")
+			}
+			op = 1200 + int(cs[pos])
+			pos++
+		}
+		s := stack
+		switch op {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			dropWidth(len(stack)%2 != 0)
+			stems += len(stack) / 2
+		case 19, 20: // hintmask, cntrmask
+			dropWidth(len(stack)%2 != 0)
+			stems += len(stack) / 2
+			pos += (stems + 7) / 8
+		case 21: // rmoveto
+			dropWidth(len(stack) > 2)
+			if len(stack) < 2 {
+				return nil, fmt.Errorf("CFF rmoveto stack underflow")
+			}
+			moveTo(stack[0], stack[1])
+		case 22, 4: // hmoveto, vmoveto
+			dropWidth(len(stack) > 1)
+			if len(stack) < 1 {
+				return nil, fmt.Errorf("CFF moveto stack underflow")
+			}
+			if op == 22 {
+				moveTo(stack[0], 0)
+			} else {
+				moveTo(0, stack[0])
+			}
+		case 5: // rlineto
+			for j := 0; j+1 < len(s); j += 2 {
+				lineTo(s[j], s[j+1])
+			}
+		case 6, 7: // hlineto, vlineto
+			for j := range s {
+				if (j%2 == 0) == (op == 6) {
+					lineTo(s[j], 0)
+				} else {
+					lineTo(0, s[j])
+				}
+			}
+		case 8, 24: // rrcurveto, rcurveline
+			j := 0
+			for ; j+5 < len(s); j += 6 {
+				curveTo(s[j], s[j+1], s[j+2], s[j+3], s[j+4], s[j+5])
+			}
+			if op == 24 && j+1 < len(s) {
+				lineTo(s[j], s[j+1])
+			}
+		case 25: // rlinecurve
+			j := 0
+			for ; j+7 < len(s); j += 2 {
+				lineTo(s[j], s[j+1])
+			}
+			if j+5 < len(s) {
+				curveTo(s[j], s[j+1], s[j+2], s[j+3], s[j+4], s[j+5])
+			}
+		case 26, 27: // vvcurveto, hhcurveto
+			d1 := 0.0
+			if len(s)%2 != 0 {
+				d1, s = s[0], s[1:]
+			}
+			for j := 0; j+3 < len(s); j += 4 {
+				if op == 26 {
+					curveTo(d1, s[j], s[j+1], s[j+2], 0, s[j+3])
+				} else {
+					curveTo(s[j], d1, s[j+1], s[j+2], s[j+3], 0)
+				}
+				d1 = 0
+			}
+		case 30, 31: // vhcurveto, hvcurveto
+			horizontal := op == 31
+			for j := 0; j+3 < len(s); j += 4 {
+				last := 0.0
+				if len(s)-j == 5 {
+					last = s[j+4]
+				}
+				if horizontal {
+					curveTo(s[j], 0, s[j+1], s[j+2], last, s[j+3])
+				} else {
+					curveTo(0, s[j], s[j+1], s[j+2], s[j+3], last)
+				}
+				horizontal = !horizontal
+			}
+		case 1235: // flex
+			if len(s) >= 12 {
+				curveTo(s[0], s[1], s[2], s[3], s[4], s[5])
+				curveTo(s[6], s[7], s[8], s[9], s[10], s[11])
+			}
+		case 1234: // hflex
+			if len(s) >= 7 {
+				curveTo(s[0], 0, s[1], s[2], s[3], 0)
+				curveTo(s[4], 0, s[5], -s[2], s[6], 0)
+			}
+		case 1236: // hflex1
+			if len(s) >= 9 {
+				curveTo(s[0], s[1], s[2], s[3], s[4], 0)
+				curveTo(s[5], 0, s[6], s[7], s[8], -(s[1] + s[3] + s[7]))
+			}
+		case 1237: // flex1
+			if len(s) >= 11 {
+				dx := s[0] + s[2] + s[4] + s[6] + s[8]
+				dy := s[1] + s[3] + s[5] + s[7] + s[9]
+				dx6, dy6 := s[10], -dy
+				if math.Abs(dy) > math.Abs(dx) {
+					dx6, dy6 = -dx, s[10]
+				}
+				curveTo(s[0], s[1], s[2], s[3], s[4], s[5])
+				curveTo(s[6], s[7], s[8], s[9], dx6, dy6)
+			}
+		case 14: // endchar
+			dropWidth(len(stack) == 1 || len(stack) == 5)
+			closePath()
+			return
+		default:
+			return nil, fmt.Errorf("unsupported CFF charstring operator %d", op)
+		}
+		stack = stack[:0]
+	}
+	closePath()
+	return
+}
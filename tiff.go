@@ -0,0 +1,355 @@
+package gofpdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// TIFF tags that are used to embed or size an image
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffPhotometric     = 262
+	tiffFillOrder       = 266
+	tiffOrientation     = 274
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffXResolution     = 282
+	tiffYResolution     = 283
+	tiffT4Options       = 292
+	tiffT6Options       = 293
+	tiffResolutionUnit  = 296
+	tiffTileWidth       = 322
+)
+
+// tiffIFDType holds the numeric values of the fields of a TIFF image file
+// directory, keyed by tag. Rational values are stored as numerator and
+// denominator pairs.
+type tiffIFDType map[uint16][]uint32
+
+// val returns the first value of the field with the specified tag, or dflt if
+// the field is absent.
+func (ifd tiffIFDType) val(tag uint16, dflt uint32) uint32 {
+	if list := ifd[tag]; len(list) > 0 {
+		return list[0]
+	}
+	return dflt
+}
+
+// rational returns the value of the rational field with the specified tag, or
+// zero if the field is absent or invalid.
+func (ifd tiffIFDType) rational(tag uint16) float64 {
+	if list := ifd[tag]; len(list) >= 2 && list[1] != 0 {
+		return float64(list[0]) / float64(list[1])
+	}
+	return 0
+}
+
+// tiffFieldSizes holds the size in bytes of a value of each TIFF field type.
+var tiffFieldSizes = [...]uint32{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// tiffReadIFD reads the image file directory at offset pos of the TIFF data
+// and returns it with the offset of the next directory.
+func tiffReadIFD(data []byte, bo binary.ByteOrder, pos uint32) (ifd tiffIFDType, next uint32, err error) {
+	if uint64(pos)+2 > uint64(len(data)) {
+		err = fmt.Errorf("invalid image file directory offset in TIFF buffer")
+		return
+	}
+	count := uint32(bo.Uint16(data[pos:]))
+	end := uint64(pos) + 2 + 12*uint64(count)
+	if end+4 > uint64(len(data)) {
+		err = fmt.Errorf("truncated image file directory in TIFF buffer")
+		return
+	}
+	ifd = make(tiffIFDType)
+	for j := uint32(0); j < count; j++ {
+		entry := data[pos+2+12*j:]
+		tag, typ, n := bo.Uint16(entry), bo.Uint16(entry[2:]), bo.Uint32(entry[4:])
+		if int(typ) >= len(tiffFieldSizes) || typ == 2 || typ == 7 || typ >= 11 {
+			// ASCII, undefined and floating point fields are not needed
+			continue
+		}
+		size := uint64(tiffFieldSizes[typ]) * uint64(n)
+		buf := entry[8:12]
+		if size > 4 {
+			off := uint64(bo.Uint32(entry[8:]))
+			if off+size > uint64(len(data)) {
+				err = fmt.Errorf("invalid offset of TIFF field %d", tag)
+				return
+			}
+			buf = data[off : off+size]
+		}
+		list := make([]uint32, 0, n)
+		for k := uint32(0); k < n; k++ {
+			switch typ {
+			case 1, 6:
+				list = append(list, uint32(buf[k]))
+			case 3, 8:
+				list = append(list, uint32(bo.Uint16(buf[2*k:])))
+			case 4, 9:
+				list = append(list, bo.Uint32(buf[4*k:]))
+			case 5, 10:
+				list = append(list, bo.Uint32(buf[8*k:]), bo.Uint32(buf[8*k+4:]))
+			}
+		}
+		ifd[tag] = list
+	}
+	next = bo.Uint32(data[end:])
+	return
+}
+
+// parsetiff extracts info from TIFF data. The page specified by options.Page
+// is used. Bilevel images compressed with CCITT Group 3 or Group 4 encoding are
+// embedded as they are; other images are decoded.
+func (f *Fpdf) parsetiff(r io.Reader, options ImageOptions) (info *ImageInfoType) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	var bo binary.ByteOrder
+	switch {
+	case len(data) < 8:
+		f.err = fmt.Errorf("not a TIFF buffer")
+		return
+	case string(data[:4]) == "II*\x00":
+		bo = binary.LittleEndian
+	case string(data[:4]) == "MM\x00*":
+		bo = binary.BigEndian
+	default:
+		f.err = fmt.Errorf("not a TIFF buffer")
+		return
+	}
+	page := options.Page
+	if page < 1 {
+		page = 1
+	}
+	pos := bo.Uint32(data[4:])
+	var ifd tiffIFDType
+	for j := 1; ; j++ {
+		var next uint32
+		ifd, next, err = tiffReadIFD(data, bo, pos)
+		if err != nil {
+			f.err = err
+			return
+		}
+		if j == page {
+			break
+		}
+		if next == 0 || j > len(data)/12 {
+			f.err = fmt.Errorf("TIFF buffer has %d pages, page %d requested", j, page)
+			return
+		}
+		pos = next
+	}
+	info = f.tiffCCITT(data, ifd)
+	if f.err != nil {
+		return
+	}
+	if info == nil {
+		// Decode the page, which becomes the first one when the offset in
+		// the header points to it
+		buf := make([]byte, len(data))
+		copy(buf, data)
+		bo.PutUint32(buf[4:], pos)
+		img, err := tiff.Decode(bytes.NewReader(buf))
+		if err != nil {
+			f.err = err
+			return
+		}
+		info = f.imageInfoFromImage(img, options.JPEGQuality)
+		if f.err != nil {
+			return
+		}
+	}
+	if options.ReadDpi && ifd.val(tiffResolutionUnit, 2) != 1 {
+		x := ifd.rational(tiffXResolution)
+		y := ifd.rational(tiffYResolution)
+		if ifd.val(tiffResolutionUnit, 2) == 3 {
+			// Pixels per centimeter
+			x *= 2.54
+			y *= 2.54
+		}
+		if x > 0 {
+			info.dpi = x
+			info.dpiRead = true
+			if y > 0 && y != x {
+				info.dpiY = y
+			}
+		}
+	}
+	return
+}
+
+// tiffCCITT returns the image information of a bilevel TIFF page that is
+// compressed with CCITT encoding, so that its strips are embedded without
+// being decoded. It returns nil if the page cannot be embedded this way.
+func (f *Fpdf) tiffCCITT(data []byte, ifd tiffIFDType) (info *ImageInfoType) {
+	compression := ifd.val(tiffCompression, 1)
+	offsets := ifd[tiffStripOffsets]
+	counts := ifd[tiffStripByteCounts]
+	if compression < 2 || compression > 4 || ifd.val(tiffBitsPerSample, 1) != 1 ||
+		ifd.val(tiffSamplesPerPixel, 1) != 1 || ifd[tiffTileWidth] != nil ||
+		len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil
+	}
+	w := int(ifd.val(tiffImageWidth, 0))
+	h := int(ifd.val(tiffImageLength, 0))
+	rowsPerStrip := int(ifd.val(tiffRowsPerStrip, uint32(h)))
+	if w <= 0 || h <= 0 || rowsPerStrip <= 0 {
+		f.err = fmt.Errorf("invalid dimensions in TIFF buffer")
+		return
+	}
+	if rowsPerStrip > h {
+		rowsPerStrip = h
+	}
+	if (h+rowsPerStrip-1)/rowsPerStrip != len(offsets) {
+		f.err = fmt.Errorf("strip count does not match image length in TIFF buffer")
+		return
+	}
+	var dp fmtBuffer
+	switch compression {
+	case 2:
+		// Modified Huffman: one-dimensional coding with byte-aligned rows
+		// and without end-of-line codes
+		dp.printf("/K 0 /EncodedByteAlign true")
+	case 3:
+		t4 := ifd.val(tiffT4Options, 0)
+		dp.printf("/K %d", intIf(t4&1 != 0, 4, 0))
+		if t4&2 != 0 {
+			dp.printf(" /Uncompressed true")
+		}
+		// Fill bits that align end-of-line codes to byte boundaries (bit 2)
+		// are part of the T.4 coding and need no parameter
+	case 4:
+		dp.printf("/K -1")
+		if ifd.val(tiffT6Options, 0)&2 != 0 {
+			dp.printf(" /Uncompressed true")
+		}
+	}
+	if ifd.val(tiffPhotometric, 0) == 1 {
+		// Zero bits, which the codes call white, are black
+		dp.printf(" /BlackIs1 true")
+	}
+	dp.printf(" /Columns %d", w)
+	reverse := ifd.val(tiffFillOrder, 1) == 2
+	strip := func(j int) []byte {
+		off, n := uint64(offsets[j]), uint64(counts[j])
+		if off+n > uint64(len(data)) {
+			f.err = fmt.Errorf("invalid strip offset in TIFF buffer")
+			return nil
+		}
+		buf := make([]byte, n)
+		copy(buf, data[off:off+n])
+		if reverse {
+			for k, b := range buf {
+				buf[k] = bitReverse[b]
+			}
+		}
+		return buf
+	}
+	info = f.newImageInfo()
+	info.w = float64(w)
+	info.h = float64(h)
+	info.cs = "DeviceGray"
+	info.bpc = 1
+	if len(offsets) == 1 || compression == 2 || compression == 3 && ifd.val(tiffT4Options, 0)&1 == 0 {
+		// The strips can be joined: either there is a single one, or rows
+		// are coded one-dimensionally, independently of the previous row,
+		// and strips end on byte boundaries
+		for j := range offsets {
+			info.data = append(info.data, strip(j)...)
+		}
+		info.f = "CCITTFaxDecode"
+		info.dp = sprintf("%s /Rows %d", dp.String(), h)
+		return
+	}
+	// The coding of each strip starts afresh, so each becomes an image of
+	// its own; together they are drawn by a form XObject
+	for j := range offsets {
+		rows := rowsPerStrip
+		if (j+1)*rowsPerStrip > h {
+			rows = h - j*rowsPerStrip
+		}
+		s := f.newImageInfo()
+		s.w = info.w
+		s.h = float64(rows)
+		s.cs = info.cs
+		s.bpc = info.bpc
+		s.f = "CCITTFaxDecode"
+		s.dp = sprintf("%s /Rows %d", dp.String(), rows)
+		s.data = strip(j)
+		info.strips = append(info.strips, s)
+	}
+	return
+}
+
+// bitReverse maps each byte to the byte with the bits in reverse order.
+var bitReverse = func() (table [256]byte) {
+	for j := range table {
+		for k := uint(0); k < 8; k++ {
+			if j&(1<<k) != 0 {
+				table[j] |= 0x80 >> k
+			}
+		}
+	}
+	return
+}()
+
+// putimageStrips writes the strip images of an image and the form XObject
+// that draws them in the unit square, like an image XObject.
+func (f *Fpdf) putimageStrips(info *ImageInfoType) {
+	for _, s := range info.strips {
+		f.putimage(s)
+	}
+	var content, res fmtBuffer
+	y := info.h
+	for j, s := range info.strips {
+		y -= s.h
+		content.printf("q 1 0 0 %.6f 0 %.6f cm /S%d Do Q\n", s.h/info.h, y/info.h, j)
+		res.printf("/S%d %d 0 R ", j, s.n)
+	}
+	f.newobj()
+	info.n = f.n
+	data := content.Bytes()
+	filter := ""
+	if f.compress {
+		data = sliceCompress(data)
+		filter = "/Filter /FlateDecode "
+	}
+	f.outf("<</Type /XObject /Subtype /Form /BBox [0 0 1 1] /Resources <</XObject <<%s>>>> %s/Length %d>>",
+		res.String(), filter, len(data))
+	f.putstream(data)
+	f.out("endobj")
+}
+
+// parsebmp extracts info from BMP data.
+func (f *Fpdf) parsebmp(r io.Reader, options ImageOptions) (info *ImageInfoType) {
+	img, err := bmp.Decode(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	return f.imageInfoFromImage(img, options.JPEGQuality)
+}
+
+// parsewebp extracts info from WebP data.
+func (f *Fpdf) parsewebp(r io.Reader, options ImageOptions) (info *ImageInfoType) {
+	img, err := webp.Decode(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	return f.imageInfoFromImage(img, options.JPEGQuality)
+}
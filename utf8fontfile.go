@@ -55,6 +55,16 @@ type utf8FontFile struct {
 	DefaultWidth         float64
 	symbolData           map[int]map[string][]int
 	CodeSymbolDictionary map[int]int
+	isCFF                bool // font has PostScript outlines in a CFF or CFF2 table
+	faceOffset           int  // position of the table directory; nonzero for collection faces
+	names                map[int]string
+	variation            *fontVariationType              // selected instance of a variable font, if any
+	outlines             map[int][][]SVGBasicSegmentType // glyph outlines used by TextPath()
+	glyfData             []byte
+	glyfOffsets          []int
+	cff                  *cffFontType
+	colorGlyphs          map[rune]*colorGlyphType // color glyphs by rune, read on first use
+	vertical             *verticalType            // vertical writing data, read on first use
 }
 
 type tableDescription struct {
@@ -94,7 +104,7 @@ func newUTF8Font(reader *fileReader) *utf8FontFile {
 }
 
 func (utf *utf8FontFile) parseFile() error {
-	utf.fileReader.readerPosition = 0
+	utf.fileReader.readerPosition = int64(utf.faceOffset)
 	utf.symbolPosition = make([]int, 0)
 	utf.charSymbolDictionary = make(map[int]int)
 	utf.tableDescriptions = make(map[string]*tableDescription)
@@ -102,13 +112,23 @@ func (utf *utf8FontFile) parseFile() error {
 	utf.Ascent = 0
 	utf.Descent = 0
 	codeType := uint32(utf.readUint32())
-	if codeType == 0x4F54544F {
-		return fmt.Errorf("not supported\n ")
-	}
+	utf.isCFF = codeType == 0x4F54544F
 	if codeType == 0x74746366 {
-		return fmt.Errorf("not supported\n ")
+		// The first face of a collection is used unless one has been selected
+		if utf.faceOffset != 0 {
+			return fmt.Errorf("invalid font collection face")
+		}
+		offset, err := utf8FontFaceOffset(utf.fileReader.array, 0)
+		if err != nil {
+			return err
+		}
+		if offset == 0 {
+			return fmt.Errorf("invalid font collection header")
+		}
+		utf.faceOffset = offset
+		return utf.parseFile()
 	}
-	if codeType != 0x00010000 && codeType != 0x74727565 {
+	if codeType != 0x00010000 && codeType != 0x74727565 && !utf.isCFF {
 		return fmt.Errorf("Not a TrueType font: codeType=%v\n ", codeType)
 	}
 	utf.generateTableDescriptions()
@@ -286,6 +306,7 @@ func (utf *utf8FontFile) parseNAMETable() int {
 	nameCount := utf.readUint16()
 	stringDataPosition := namePosition + utf.readUint16()
 	names := map[int]string{1: "", 2: "", 3: "", 4: "", 6: ""}
+	utf.names = names
 	keys := arrayKeys(names)
 	counter := len(names)
 	for i := 0; i < nameCount; i++ {
@@ -523,6 +544,12 @@ func (utf *utf8FontFile) generateCMAP() map[int][]int {
 	charSymbolDictionary := make(map[int]int)
 	utf.generateSCCSDictionaries(runeCmapPosition, symbolCharDictionary, charSymbolDictionary)
 
+	if utf.vertical != nil {
+		// Vertical alternates are addressed by the CIDs assigned to them
+		for cid, gid := range utf.vertical.gids {
+			charSymbolDictionary[cid] = gid
+		}
+	}
 	utf.charSymbolDictionary = charSymbolDictionary
 
 	return symbolCharDictionary
@@ -636,17 +663,20 @@ func (utf *utf8FontFile) generateCMAPTable(cidSymbolPairCollection map[int]int,
 }
 
 //GenerateCutFont fill utf8FontFile from .utf file, only with runes from usedRunes
-func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
-	utf.fileReader.readerPosition = 0
+func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) ([]byte, error) {
+	utf.fileReader.readerPosition = int64(utf.faceOffset)
 	utf.symbolPosition = make([]int, 0)
 	utf.charSymbolDictionary = make(map[int]int)
 	utf.tableDescriptions = make(map[string]*tableDescription)
 	utf.outTablesData = make(map[string][]byte)
 	utf.Ascent = 0
 	utf.Descent = 0
-	utf.skip(4)
+	utf.isCFF = utf.readUint32() == 0x4F54544F
 	utf.LastRune = 0
 	utf.generateTableDescriptions()
+	if utf.isCFF {
+		return utf.generateCutFontCFF(usedRunes)
+	}
 
 	utf.SeekTable("head")
 	utf.skip(50)
@@ -663,7 +693,7 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 
 	symbolCharDictionary := utf.generateCMAP()
 	if symbolCharDictionary == nil {
-		return nil
+		return nil, fmt.Errorf("font does not have cmap for Unicode")
 	}
 
 	utf.parseHMTXTable(metricsCount, numSymbols, symbolCharDictionary, 1.0)
@@ -676,9 +706,12 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 	numSymbols = metricsCount
 
 	utf.setOutTable("name", utf.getTableData("name"))
-	utf.setOutTable("cvt ", utf.getTableData("cvt "))
-	utf.setOutTable("fpgm", utf.getTableData("fpgm"))
-	utf.setOutTable("prep", utf.getTableData("prep"))
+	if utf.variation == nil {
+		// Hinting instructions do not apply to the outlines of an instance
+		utf.setOutTable("cvt ", utf.getTableData("cvt "))
+		utf.setOutTable("fpgm", utf.getTableData("fpgm"))
+		utf.setOutTable("prep", utf.getTableData("prep"))
+	}
 	utf.setOutTable("gasp", utf.getTableData("gasp"))
 
 	postTable := utf.getTableData("post")
@@ -699,12 +732,21 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 
 	for _, originalSymbolIdx := range symbolCollectionKeys {
 		hm := utf.getMetrics(oldMetrics, originalSymbolIdx)
-		hmtxData = append(hmtxData, hm...)
 
 		offsets = append(offsets, pos)
 		symbolPos := utf.symbolPosition[originalSymbolIdx]
 		symbolLen := utf.symbolPosition[originalSymbolIdx+1] - symbolPos
 		data := symbolData[symbolPos : symbolPos+symbolLen]
+		if utf.variation != nil {
+			var advance, lsb int
+			var err error
+			data, advance, lsb, err = utf.variation.glyph(originalSymbolIdx)
+			if err != nil {
+				return nil, err
+			}
+			symbolLen = len(data)
+			hm = append(packUint16(advance), packUint16(lsb)...)
+		}
 		var up int
 		if symbolLen > 0 {
 			up = unpackUint16(data[0:2])
@@ -744,6 +786,7 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 			}
 		}
 
+		hmtxData = append(hmtxData, hm...)
 		glyfData = append(glyfData, data...)
 		pos += symbolLen
 		if pos%4 != 0 {
@@ -787,7 +830,7 @@ func (utf *utf8FontFile) GenerateCutFont(usedRunes map[int]int) []byte {
 	os2Data := utf.getTableData("OS/2")
 	utf.setOutTable("OS/2", os2Data)
 
-	return utf.assembleTables()
+	return utf.assembleTables(), nil
 }
 
 func (utf *utf8FontFile) getSymbols(originalSymbolIdx int, start *int, symbolSet map[int]int, SymbolsCollection map[int]int, SymbolsCollectionKeys []int) (*int, map[int]int, map[int]int, []int) {
@@ -994,7 +1037,11 @@ func (utf *utf8FontFile) assembleTables() []byte {
 	findSize = findSize * 16
 	rOffset := tablesCount*16 - findSize
 
-	answer = append(answer, packHeader(0x00010000, tablesCount, findSize, writer, rOffset)...)
+	sfntVersion := uint32(0x00010000)
+	if utf.isCFF {
+		sfntVersion = 0x4F54544F // OTTO
+	}
+	answer = append(answer, packHeader(sfntVersion, tablesCount, findSize, writer, rOffset)...)
 
 	tables := utf.outTablesData
 	tablesNames := keySortStrings(tables)
@@ -1145,10 +1192,13 @@ func keySortArrayRangeMap(s map[int][]int) []int {
 // function is demonstrated in ExampleUTF8CutFont().
 func UTF8CutFont(inBuf []byte, cutset string) (outBuf []byte) {
 	f := newUTF8Font(&fileReader{readerPosition: 0, array: inBuf})
+	if offset, err := utf8FontFaceOffset(inBuf, 0); err == nil {
+		f.faceOffset = offset
+	}
 	runes := map[int]int{}
 	for i, r := range cutset {
 		runes[i] = int(r)
 	}
-	outBuf = f.GenerateCutFont(runes)
+	outBuf, _ = f.GenerateCutFont(runes)
 	return
 }
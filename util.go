@@ -151,6 +151,14 @@ func intIf(cnd bool, a, b int) int {
 	return b
 }
 
+// intAbs returns the absolute value of a
+func intAbs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
 // strIf returns aStr if cnd is true, otherwise bStr
 func strIf(cnd bool, aStr, bStr string) string {
 	if cnd {
@@ -0,0 +1,1186 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+// FontAxisType describes a design axis of a variable font. It is returned by
+// FontVariations().
+type FontAxisType struct {
+	Tag     string  // four-character axis tag, for example "wght" or "wdth"
+	Name    string  // name of the axis, for example "Weight"
+	Min     float64 // smallest coordinate on the axis
+	Default float64 // coordinate of the default instance
+	Max     float64 // largest coordinate on the axis
+}
+
+// FontInstanceType describes a named instance of a variable font. It is
+// returned by FontVariations().
+type FontInstanceType struct {
+	Name   string             // subfamily name, for example "Semibold Condensed"
+	Coords map[string]float64 // coordinate on each axis, keyed by axis tag
+}
+
+// FontVariations returns the design axes and the named instances of the
+// variable TrueType or OpenType font specified by fileStr. Both lists are
+// empty if the font is not a variable font. The first face of a font
+// collection is examined.
+func FontVariations(fileStr string) (axes []FontAxisType, instances []FontInstanceType, err error) {
+	var data []byte
+	data, err = ioutil.ReadFile(fileStr)
+	if err == nil {
+		axes, instances, err = FontVariationsFromBytes(data)
+	}
+	return
+}
+
+// FontVariationsFromBytes returns the design axes and the named instances of
+// the variable font specified by utf8Bytes. See FontVariations() for more
+// details.
+func FontVariationsFromBytes(utf8Bytes []byte) (axes []FontAxisType, instances []FontInstanceType, err error) {
+	var utf *utf8FontFile
+	utf, err = utf8FontFace(utf8Bytes, fontFaceSelectType{})
+	if err == nil {
+		axes, instances, err = utf.parseFVARTable()
+	}
+	return
+}
+
+// AddUTF8FontInstance imports an instance of a variable TrueType or OpenType
+// font and makes it available in the same way as AddUTF8Font(). A variable
+// font contains a continuous range of designs, for example all weights from
+// thin to black, in one file. coords specifies the design of the instance as
+// coordinates on the axes of the font, keyed by axis tag; for example,
+// map[string]float64{"wght": 650, "wdth": 85} selects a semibold, condensed
+// design. Coordinates are clamped to the range of their axis, and axes that
+// are not specified keep their default value. FontVariations() lists the axes
+// of a font.
+//
+// The outlines and advance widths of the glyphs are computed for the instance
+// from the font's variation tables (gvar, HVAR and, for PostScript outlines,
+// CFF2), and a static subset is embedded in the document. Hinting
+// instructions are not retained for instances of TrueType fonts.
+//
+// Different instances of a font are imported under different style or family
+// names, for example AddUTF8FontInstance("Sans", "", file,
+// map[string]float64{"wght": 400}) and AddUTF8FontInstance("Sans", "B", file,
+// map[string]float64{"wght": 700}).
+//
+// The AddUTF8FontInstance example demonstrates this method.
+func (f *Fpdf) AddUTF8FontInstance(familyStr, styleStr, fileStr string, coords map[string]float64) {
+	if coords == nil {
+		coords = map[string]float64{}
+	}
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true, fontFaceSelectType{coords: coords})
+}
+
+// AddUTF8FontNamedInstance imports the named instance instanceStr of a
+// variable font, for example "Bold" or "Light Condensed". The name is
+// compared without regard to case. See AddUTF8FontInstance() for more
+// details.
+func (f *Fpdf) AddUTF8FontNamedInstance(familyStr, styleStr, fileStr, instanceStr string) {
+	f.addFont(fontFamilyEscape(familyStr), styleStr, fileStr, true, fontFaceSelectType{instance: instanceStr})
+}
+
+// AddUTF8FontInstanceFromBytes imports an instance of a variable font from
+// static bytes within the executable. See AddUTF8FontInstance() for more
+// details.
+func (f *Fpdf) AddUTF8FontInstanceFromBytes(familyStr, styleStr string, utf8Bytes []byte, coords map[string]float64) {
+	if coords == nil {
+		coords = map[string]float64{}
+	}
+	f.addFontFromBytes(fontFamilyEscape(familyStr), styleStr, nil, nil, utf8Bytes, fontFaceSelectType{coords: coords})
+}
+
+// fontVariationType holds the normalized coordinates of an instance of a
+// variable font and the tables from which its glyphs are computed.
+type fontVariationType struct {
+	coords       []float64 // normalized coordinate on each axis, from -1 to 1
+	hmtx         []byte
+	metricsCount int
+	glyf         []byte
+	loca         []int // glyph offsets into glyf; empty for CFF fonts
+	gvar         []byte
+	hvar         *itemVariationStoreType
+	hvarScalars  []float64
+	hvarMap      []int // HVAR advance width mapping; nil maps glyph IDs directly
+	hvarInnerBit uint
+}
+
+// variationRegionType is the range of an axis in which a variation applies.
+// A peak of zero means that the variation does not depend on the axis.
+type variationRegionType struct {
+	start, peak, end float64
+}
+
+// itemVariationStoreType holds the deltas of an OpenType item variation
+// store, which the HVAR and CFF2 tables use.
+type itemVariationStoreType struct {
+	regions [][]variationRegionType // axis ranges of each region
+	data    []itemVariationDataType
+}
+
+// itemVariationDataType is one subtable of an item variation store.
+type itemVariationDataType struct {
+	regionIndexes []int
+	deltas        [][]int // deltas of each item, one per region index
+}
+
+// f2dot14 returns the value of the signed 2.14 fixed point number at pos.
+func f2dot14(data []byte, pos int) float64 {
+	return float64(int16(binary.BigEndian.Uint16(data[pos:]))) / 16384
+}
+
+// fontNameString returns the English string with ID nameID of a name table,
+// or an empty string if there is none.
+func fontNameString(data []byte, nameID int) (s string) {
+	if len(data) < 6 {
+		return
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	storage := int(binary.BigEndian.Uint16(data[4:]))
+	for j := 0; j < count && 6+12*j+12 <= len(data); j++ {
+		rec := data[6+12*j:]
+		platform := binary.BigEndian.Uint16(rec[0:])
+		encoding := binary.BigEndian.Uint16(rec[2:])
+		language := binary.BigEndian.Uint16(rec[4:])
+		id := int(binary.BigEndian.Uint16(rec[6:]))
+		size := int(binary.BigEndian.Uint16(rec[8:]))
+		off := storage + int(binary.BigEndian.Uint16(rec[10:]))
+		if id != nameID || off+size > len(data) {
+			continue
+		}
+		str := data[off : off+size]
+		if platform == 3 && encoding == 1 && language == 0x409 {
+			var runes []rune
+			for k := 0; k+1 < len(str); k += 2 {
+				runes = append(runes, rune(binary.BigEndian.Uint16(str[k:])))
+			}
+			return string(runes)
+		}
+		if platform == 1 && encoding == 0 && language == 0 && s == "" {
+			s = string(str)
+		}
+	}
+	return
+}
+
+// parseFVARTable returns the axes and named instances of a variable font.
+func (utf *utf8FontFile) parseFVARTable() (axes []FontAxisType, instances []FontInstanceType, err error) {
+	data := utf.getTableData("fvar")
+	if data == nil {
+		return
+	}
+	if len(data) < 16 {
+		return nil, nil, fmt.Errorf("invalid fvar table")
+	}
+	axesOffset := int(binary.BigEndian.Uint16(data[4:]))
+	axisCount := int(binary.BigEndian.Uint16(data[8:]))
+	axisSize := int(binary.BigEndian.Uint16(data[10:]))
+	instanceCount := int(binary.BigEndian.Uint16(data[12:]))
+	instanceSize := int(binary.BigEndian.Uint16(data[14:]))
+	if axisSize < 20 || instanceSize < 4+4*axisCount ||
+		axesOffset+axisCount*axisSize+instanceCount*instanceSize > len(data) {
+		return nil, nil, fmt.Errorf("invalid fvar table")
+	}
+	names := utf.getTableData("name")
+	fixed := func(pos int) float64 {
+		return float64(int32(binary.BigEndian.Uint32(data[pos:]))) / 65536
+	}
+	for j := 0; j < axisCount; j++ {
+		pos := axesOffset + j*axisSize
+		axes = append(axes, FontAxisType{
+			Tag:     string(data[pos : pos+4]),
+			Min:     fixed(pos + 4),
+			Default: fixed(pos + 8),
+			Max:     fixed(pos + 12),
+			Name:    fontNameString(names, int(binary.BigEndian.Uint16(data[pos+18:]))),
+		})
+	}
+	for j := 0; j < instanceCount; j++ {
+		pos := axesOffset + axisCount*axisSize + j*instanceSize
+		instance := FontInstanceType{
+			Name:   fontNameString(names, int(binary.BigEndian.Uint16(data[pos:]))),
+			Coords: make(map[string]float64),
+		}
+		for k, axis := range axes {
+			instance.Coords[axis.Tag] = fixed(pos + 4 + 4*k)
+		}
+		instances = append(instances, instance)
+	}
+	return
+}
+
+// normalizedCoords returns the normalized coordinates of the design specified
+// by the axis values of coords, with the mapping of the avar table applied.
+func (utf *utf8FontFile) normalizedCoords(axes []FontAxisType, coords map[string]float64) ([]float64, error) {
+	norm := make([]float64, len(axes))
+	for tag := range coords {
+		found := false
+		for _, axis := range axes {
+			found = found || axis.Tag == tag
+		}
+		if !found {
+			return nil, fmt.Errorf("font has no variation axis %s", tag)
+		}
+	}
+	for j, axis := range axes {
+		v, ok := coords[axis.Tag]
+		if !ok {
+			continue
+		}
+		v = math.Max(axis.Min, math.Min(axis.Max, v))
+		switch {
+		case v < axis.Default && axis.Default > axis.Min:
+			norm[j] = (v - axis.Default) / (axis.Default - axis.Min)
+		case v > axis.Default && axis.Max > axis.Default:
+			norm[j] = (v - axis.Default) / (axis.Max - axis.Default)
+		}
+	}
+	if avar := utf.getTableData("avar"); len(avar) >= 8 {
+		pos := 8
+		for j := 0; j < len(norm) && pos+2 <= len(avar); j++ {
+			count := int(binary.BigEndian.Uint16(avar[pos:]))
+			pos += 2
+			if pos+4*count > len(avar) {
+				return nil, fmt.Errorf("invalid avar table")
+			}
+			for k := 1; k < count; k++ {
+				from0, to0 := f2dot14(avar, pos+4*k-4), f2dot14(avar, pos+4*k-2)
+				from1, to1 := f2dot14(avar, pos+4*k), f2dot14(avar, pos+4*k+2)
+				if norm[j] >= from0 && norm[j] <= from1 {
+					if from1 > from0 {
+						norm[j] = to0 + (norm[j]-from0)*(to1-to0)/(from1-from0)
+					} else {
+						norm[j] = to0
+					}
+					break
+				}
+			}
+			pos += 4 * count
+		}
+	}
+	for j := range norm {
+		norm[j] = math.Round(norm[j]*16384) / 16384
+	}
+	return norm, nil
+}
+
+// setVariation selects the instance of a variable font that is named
+// instanceStr or, if it is empty, that is specified by the axis values of
+// coords. The character widths of the font are updated for the instance.
+func (utf *utf8FontFile) setVariation(instanceStr string, coords map[string]float64) (err error) {
+	axes, instances, err := utf.parseFVARTable()
+	if err != nil {
+		return
+	}
+	if len(axes) == 0 {
+		return fmt.Errorf("font is not a variable font")
+	}
+	if instanceStr != "" {
+		coords = nil
+		for _, instance := range instances {
+			if strings.EqualFold(instance.Name, instanceStr) {
+				coords = instance.Coords
+				break
+			}
+		}
+		if coords == nil {
+			return fmt.Errorf("font instance \"%s\" not found", instanceStr)
+		}
+	}
+	v := &fontVariationType{}
+	if v.coords, err = utf.normalizedCoords(axes, coords); err != nil {
+		return
+	}
+	v.hmtx = utf.getTableData("hmtx")
+	utf.SeekTable("hhea")
+	utf.skip(34)
+	v.metricsCount = utf.readUint16()
+	if v.metricsCount == 0 || len(v.hmtx) < 4*v.metricsCount {
+		return fmt.Errorf("invalid hmtx table")
+	}
+	if !utf.isCFF {
+		if err = v.loadGlyphs(utf); err != nil {
+			return
+		}
+	}
+	if hvar := utf.getTableData("HVAR"); hvar != nil {
+		if err = v.parseHVARTable(hvar); err != nil {
+			return
+		}
+	}
+	utf.variation = v
+	return utf.instanceWidths()
+}
+
+// loadGlyphs reads the glyph locations and the glyph variations of a variable
+// TrueType font.
+func (v *fontVariationType) loadGlyphs(utf *utf8FontFile) (err error) {
+	if v.glyf, v.loca, err = utf.glyphLocations(); err != nil {
+		return
+	}
+	v.gvar = utf.getTableData("gvar")
+	if v.gvar != nil && len(v.gvar) < 20 {
+		return fmt.Errorf("invalid gvar table")
+	}
+	return nil
+}
+
+// glyphLocations returns the glyf table of a TrueType font and the offset of
+// each glyph in it. The last offset marks the end of the last glyph.
+func (utf *utf8FontFile) glyphLocations() (glyf []byte, offsets []int, err error) {
+	utf.SeekTable("head")
+	utf.skip(50)
+	longLoca := utf.readUint16() == 1
+	utf.SeekTable("maxp")
+	utf.skip(4)
+	numSymbols := utf.readUint16()
+	loca := utf.getTableData("loca")
+	glyf = utf.getTableData("glyf")
+	offsets = make([]int, numSymbols+1)
+	for j := range offsets {
+		if longLoca && 4*j+4 <= len(loca) {
+			offsets[j] = int(binary.BigEndian.Uint32(loca[4*j:]))
+		} else if !longLoca && 2*j+2 <= len(loca) {
+			offsets[j] = 2 * int(binary.BigEndian.Uint16(loca[2*j:]))
+		} else {
+			return nil, nil, fmt.Errorf("invalid loca table")
+		}
+		if offsets[j] > len(glyf) || (j > 0 && offsets[j] < offsets[j-1]) {
+			return nil, nil, fmt.Errorf("invalid loca table")
+		}
+	}
+	return
+}
+
+// parseHVARTable reads the advance width variations of a variable font.
+func (v *fontVariationType) parseHVARTable(data []byte) (err error) {
+	if len(data) < 20 {
+		return fmt.Errorf("invalid HVAR table")
+	}
+	if v.hvar, err = parseItemVariationStore(data, int(binary.BigEndian.Uint32(data[4:]))); err != nil {
+		return
+	}
+	v.hvarScalars = v.hvar.regionScalars(v.coords)
+	pos := int(binary.BigEndian.Uint32(data[8:]))
+	if pos == 0 {
+		return
+	}
+	if pos+4 > len(data) {
+		return fmt.Errorf("invalid HVAR advance width mapping")
+	}
+	format, entryFormat := data[pos], int(data[pos+1])
+	count := int(binary.BigEndian.Uint16(data[pos+2:]))
+	pos += 4
+	if format == 1 {
+		if pos+2 > len(data) {
+			return fmt.Errorf("invalid HVAR advance width mapping")
+		}
+		count = int(binary.BigEndian.Uint32(data[pos-2:]))
+		pos += 2
+	}
+	size := (entryFormat>>4)&3 + 1
+	if pos+count*size > len(data) {
+		return fmt.Errorf("invalid HVAR advance width mapping")
+	}
+	v.hvarInnerBit = uint(entryFormat&15) + 1
+	v.hvarMap = make([]int, count)
+	for j := range v.hvarMap {
+		for k := 0; k < size; k++ {
+			v.hvarMap[j] = v.hvarMap[j]<<8 | int(data[pos+j*size+k])
+		}
+	}
+	return
+}
+
+// parseItemVariationStore reads the item variation store at position pos of
+// data.
+func parseItemVariationStore(data []byte, pos int) (store *itemVariationStoreType, err error) {
+	invalid := fmt.Errorf("invalid item variation store")
+	if pos+8 > len(data) {
+		return nil, invalid
+	}
+	store = &itemVariationStoreType{}
+	regionPos := pos + int(binary.BigEndian.Uint32(data[pos+2:]))
+	if regionPos+4 > len(data) {
+		return nil, invalid
+	}
+	axisCount := int(binary.BigEndian.Uint16(data[regionPos:]))
+	regionCount := int(binary.BigEndian.Uint16(data[regionPos+2:]))
+	if regionPos+4+6*axisCount*regionCount > len(data) {
+		return nil, invalid
+	}
+	for j := 0; j < regionCount; j++ {
+		region := make([]variationRegionType, axisCount)
+		for k := range region {
+			p := regionPos + 4 + 6*(j*axisCount+k)
+			region[k] = variationRegionType{f2dot14(data, p), f2dot14(data, p+2), f2dot14(data, p+4)}
+		}
+		store.regions = append(store.regions, region)
+	}
+	count := int(binary.BigEndian.Uint16(data[pos+6:]))
+	if pos+8+4*count > len(data) {
+		return nil, invalid
+	}
+	for j := 0; j < count; j++ {
+		p := pos + int(binary.BigEndian.Uint32(data[pos+8+4*j:]))
+		if p+6 > len(data) {
+			return nil, invalid
+		}
+		itemCount := int(binary.BigEndian.Uint16(data[p:]))
+		wordCount := int(binary.BigEndian.Uint16(data[p+2:]))
+		longWords := wordCount&0x8000 != 0
+		wordCount &= 0x7FFF
+		indexCount := int(binary.BigEndian.Uint16(data[p+4:]))
+		p += 6
+		if wordCount > indexCount || p+2*indexCount > len(data) {
+			return nil, invalid
+		}
+		var ivd itemVariationDataType
+		for k := 0; k < indexCount; k++ {
+			ivd.regionIndexes = append(ivd.regionIndexes, int(binary.BigEndian.Uint16(data[p+2*k:])))
+		}
+		p += 2 * indexCount
+		wordSize, byteSize := 2, 1
+		if longWords {
+			wordSize, byteSize = 4, 2
+		}
+		rowSize := wordCount*wordSize + (indexCount-wordCount)*byteSize
+		if p+itemCount*rowSize > len(data) {
+			return nil, invalid
+		}
+		for k := 0; k < itemCount; k++ {
+			row := make([]int, indexCount)
+			q := p + k*rowSize
+			for r := range row {
+				size := byteSize
+				if r < wordCount {
+					size = wordSize
+				}
+				switch size {
+				case 1:
+					row[r] = int(int8(data[q]))
+				case 2:
+					row[r] = int(int16(binary.BigEndian.Uint16(data[q:])))
+				default:
+					row[r] = int(int32(binary.BigEndian.Uint32(data[q:])))
+				}
+				q += size
+			}
+			ivd.deltas = append(ivd.deltas, row)
+		}
+		store.data = append(store.data, ivd)
+	}
+	return
+}
+
+// regionScalars returns the factor with which the deltas of each region of
+// the store are applied to the instance at the normalized coordinates coords.
+func (store *itemVariationStoreType) regionScalars(coords []float64) []float64 {
+	scalars := make([]float64, len(store.regions))
+	for j, region := range store.regions {
+		scalars[j] = tupleScalar(coords, region)
+	}
+	return scalars
+}
+
+// delta returns the interpolated delta of item inner of subtable outer.
+func (store *itemVariationStoreType) delta(outer, inner int, scalars []float64) (d float64) {
+	if outer >= len(store.data) || inner >= len(store.data[outer].deltas) {
+		return
+	}
+	ivd := store.data[outer]
+	for r, idx := range ivd.regionIndexes {
+		if idx < len(scalars) {
+			d += float64(ivd.deltas[inner][r]) * scalars[idx]
+		}
+	}
+	return
+}
+
+// tupleScalar returns the factor with which a variation that applies in the
+// specified region contributes to the instance at coords.
+func tupleScalar(coords []float64, region []variationRegionType) float64 {
+	scalar := 1.0
+	for j, r := range region {
+		if j >= len(coords) || r.peak == 0 || r.start > r.peak || r.peak > r.end ||
+			(r.start < 0 && r.end > 0) {
+			continue
+		}
+		c := coords[j]
+		switch {
+		case c == r.peak:
+		case c <= r.start || c >= r.end:
+			return 0
+		case c < r.peak:
+			scalar *= (c - r.start) / (r.peak - r.start)
+		default:
+			scalar *= (r.end - c) / (r.end - r.peak)
+		}
+	}
+	return scalar
+}
+
+// instanceWidths sets the character widths of the font to the advance widths
+// of the selected instance.
+func (utf *utf8FontFile) instanceWidths() error {
+	symbolCharDictionary := utf.generateCMAP()
+	if symbolCharDictionary == nil {
+		return fmt.Errorf("font has no Unicode cmap")
+	}
+	scale := 1000.0 / float64(utf.fontElementSize)
+	advance, err := utf.variation.advance(0)
+	if err != nil {
+		return err
+	}
+	utf.DefaultWidth = scale * float64(advance)
+	for symbol, chars := range symbolCharDictionary {
+		if advance, err = utf.variation.advance(symbol); err != nil {
+			return err
+		}
+		width := int(math.Round(scale * float64(advance)))
+		if width == 0 {
+			width = 65535
+		}
+		for _, char := range chars {
+			if char != 0 && char != 65535 && char < 196608 {
+				utf.CharWidths[char] = width
+			}
+		}
+	}
+	return nil
+}
+
+// metrics returns the advance width and the left side bearing of glyph gid in
+// the default instance.
+func (v *fontVariationType) metrics(gid int) (advance, lsb int) {
+	if gid < v.metricsCount {
+		advance = int(binary.BigEndian.Uint16(v.hmtx[4*gid:]))
+		lsb = int(int16(binary.BigEndian.Uint16(v.hmtx[4*gid+2:])))
+		return
+	}
+	advance = int(binary.BigEndian.Uint16(v.hmtx[4*v.metricsCount-4:]))
+	if pos := 4*v.metricsCount + 2*(gid-v.metricsCount); pos+2 <= len(v.hmtx) {
+		lsb = int(int16(binary.BigEndian.Uint16(v.hmtx[pos:])))
+	}
+	return
+}
+
+// hvarAdvance returns the advance width of glyph gid computed with the HVAR
+// table.
+func (v *fontVariationType) hvarAdvance(gid int) int {
+	advance, _ := v.metrics(gid)
+	outer, inner := 0, gid
+	if len(v.hvarMap) > 0 {
+		entry := v.hvarMap[len(v.hvarMap)-1]
+		if gid < len(v.hvarMap) {
+			entry = v.hvarMap[gid]
+		}
+		outer, inner = entry>>v.hvarInnerBit, entry&(1<<v.hvarInnerBit-1)
+	}
+	return advance + int(math.Round(v.hvar.delta(outer, inner, v.hvarScalars)))
+}
+
+// advance returns the advance width of glyph gid in the instance.
+func (v *fontVariationType) advance(gid int) (advance int, err error) {
+	switch {
+	case v.hvar != nil:
+		advance = v.hvarAdvance(gid)
+	case v.loca != nil:
+		_, advance, _, err = v.glyph(gid)
+	default:
+		advance, _ = v.metrics(gid)
+	}
+	return
+}
+
+// cffMetrics returns the hmtx entry of glyph gid of the instance of a variable
+// font with PostScript outlines.
+func (v *fontVariationType) cffMetrics(gid int) []byte {
+	advance, lsb := v.metrics(gid)
+	if v.hvar != nil {
+		advance = v.hvarAdvance(gid)
+	}
+	return append(packUint16(advance), packUint16(lsb)...)
+}
+
+// glyfPointType is a point of a TrueType glyph outline. For composite glyphs,
+// each point is the offset of a component.
+type glyfPointType struct {
+	x, y    float64
+	onCurve bool
+}
+
+// glyfComponentType is a component of a composite TrueType glyph.
+type glyfComponentType struct {
+	flags     int
+	gid       int
+	transform []byte
+}
+
+const (
+	glyfOnCurve     = 0x01
+	glyfXShort      = 0x02
+	glyfYShort      = 0x04
+	glyfRepeat      = 0x08
+	glyfXSame       = 0x10
+	glyfYSame       = 0x20
+	glyfOverlap     = 0x40
+	glyfArgsXY      = 0x0002
+	glyfInstruction = 0x0100
+)
+
+// glyph returns the glyf data, advance width and left side bearing of glyph
+// gid in the instance.
+func (v *fontVariationType) glyph(gid int) (data []byte, advance, lsb int, err error) {
+	if gid < 0 || gid+1 >= len(v.loca) {
+		return nil, 0, 0, fmt.Errorf("glyph %d out of range", gid)
+	}
+	data = v.glyf[v.loca[gid]:v.loca[gid+1]]
+	advance, lsb = v.metrics(gid)
+	var points []glyfPointType
+	var endPts []int
+	var components []glyfComponentType
+	var overlap bool
+	contours, xMin := 0, 0
+	if len(data) > 0 {
+		if len(data) < 10 {
+			return nil, 0, 0, fmt.Errorf("invalid glyph %d", gid)
+		}
+		contours = int(int16(binary.BigEndian.Uint16(data)))
+		xMin = int(int16(binary.BigEndian.Uint16(data[2:])))
+		if contours >= 0 {
+			endPts, points, overlap, err = parseSimpleGlyph(data)
+		} else {
+			components, points, err = parseCompositeGlyph(data)
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("glyph %d: %s", gid, err)
+		}
+	}
+	// Phantom points give the horizontal origin and advance
+	origin := float64(xMin - lsb)
+	points = append(points, glyfPointType{x: origin}, glyfPointType{x: origin + float64(advance)},
+		glyfPointType{}, glyfPointType{})
+	deltas, err := v.glyphDeltas(gid, points, endPts)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("glyph %d: %s", gid, err)
+	}
+	for j := range points {
+		points[j].x += deltas[j].x
+		points[j].y += deltas[j].y
+	}
+	n := len(points) - 4
+	shift := points[n].x
+	advance = int(math.Round(points[n+1].x - shift))
+	if v.hvar != nil {
+		advance = v.hvarAdvance(gid)
+	}
+	points = points[:n]
+	for j := range points {
+		points[j].x = math.Round(points[j].x - shift)
+		points[j].y = math.Round(points[j].y)
+	}
+	switch {
+	case len(data) == 0:
+		lsb = 0
+	case contours >= 0:
+		data, lsb = encodeSimpleGlyph(endPts, points, overlap)
+	default:
+		lsb = xMin - int(math.Round(shift))
+		data = encodeCompositeGlyph(data, components, points, lsb-xMin)
+	}
+	return
+}
+
+// parseSimpleGlyph returns the contour end points and the points of the
+// simple glyph in data.
+func parseSimpleGlyph(data []byte) (endPts []int, points []glyfPointType, overlap bool, err error) {
+	invalid := fmt.Errorf("invalid glyph outline")
+	contours := int(binary.BigEndian.Uint16(data))
+	pos := 10
+	if pos+2*contours+2 > len(data) {
+		return nil, nil, false, invalid
+	}
+	count := 0
+	for j := 0; j < contours; j++ {
+		endPts = append(endPts, int(binary.BigEndian.Uint16(data[pos:])))
+		pos += 2
+		if endPts[j] < count-1 {
+			return nil, nil, false, invalid
+		}
+		count = endPts[j] + 1
+	}
+	pos += 2 + int(binary.BigEndian.Uint16(data[pos:]))
+	flags := make([]byte, 0, count)
+	for len(flags) < count {
+		if pos >= len(data) {
+			return nil, nil, false, invalid
+		}
+		flag := data[pos]
+		pos++
+		flags = append(flags, flag)
+		if flag&glyfRepeat != 0 {
+			if pos >= len(data) {
+				return nil, nil, false, invalid
+			}
+			for k := 0; k < int(data[pos]); k++ {
+				flags = append(flags, flag)
+			}
+			pos++
+		}
+	}
+	flags = flags[:count]
+	overlap = count > 0 && flags[0]&glyfOverlap != 0
+	points = make([]glyfPointType, count)
+	readCoords := func(short, same byte, set func(j int, c float64)) bool {
+		c := 0
+		for j, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if pos >= len(data) {
+					return false
+				}
+				if flag&same != 0 {
+					c += int(data[pos])
+				} else {
+					c -= int(data[pos])
+				}
+				pos++
+			case flag&same == 0:
+				if pos+2 > len(data) {
+					return false
+				}
+				c += int(int16(binary.BigEndian.Uint16(data[pos:])))
+				pos += 2
+			}
+			set(j, float64(c))
+		}
+		return true
+	}
+	if !readCoords(glyfXShort, glyfXSame, func(j int, c float64) { points[j].x = c }) ||
+		!readCoords(glyfYShort, glyfYSame, func(j int, c float64) { points[j].y = c }) {
+		return nil, nil, false, invalid
+	}
+	for j, flag := range flags {
+		points[j].onCurve = flag&glyfOnCurve != 0
+	}
+	return
+}
+
+// encodeSimpleGlyph returns the glyf data of a simple glyph without hinting
+// instructions, and its smallest x coordinate.
+func encodeSimpleGlyph(endPts []int, points []glyfPointType, overlap bool) (data []byte, xMin int) {
+	var xMax, yMin, yMax int
+	for j, p := range points {
+		x, y := int(p.x), int(p.y)
+		if j == 0 || x < xMin {
+			xMin = x
+		}
+		if j == 0 || x > xMax {
+			xMax = x
+		}
+		if j == 0 || y < yMin {
+			yMin = y
+		}
+		if j == 0 || y > yMax {
+			yMax = y
+		}
+	}
+	data = packUint16(len(endPts))
+	for _, v := range []int{xMin, yMin, xMax, yMax} {
+		data = append(data, packUint16(v)...)
+	}
+	for _, end := range endPts {
+		data = append(data, packUint16(end)...)
+	}
+	data = append(data, 0, 0)
+	var flags, xs, ys []byte
+	encode := func(d int, short, same byte, coords []byte) (byte, []byte) {
+		switch {
+		case d == 0:
+			return same, coords
+		case d > -256 && d < 256:
+			if d > 0 {
+				return short | same, append(coords, byte(d))
+			}
+			return short, append(coords, byte(-d))
+		}
+		return 0, append(coords, packUint16(d)...)
+	}
+	x, y := 0, 0
+	for j, p := range points {
+		var flag, fx, fy byte
+		if p.onCurve {
+			flag = glyfOnCurve
+		}
+		if j == 0 && overlap {
+			flag |= glyfOverlap
+		}
+		fx, xs = encode(int(p.x)-x, glyfXShort, glyfXSame, xs)
+		fy, ys = encode(int(p.y)-y, glyfYShort, glyfYSame, ys)
+		flags = append(flags, flag|fx|fy)
+		x, y = int(p.x), int(p.y)
+	}
+	data = append(append(append(data, flags...), xs...), ys...)
+	return
+}
+
+// parseCompositeGlyph returns the components of the composite glyph in data.
+// The offset of each component is returned as a point.
+func parseCompositeGlyph(data []byte) (components []glyfComponentType, points []glyfPointType, err error) {
+	pos := 10
+	for more := true; more; {
+		if pos+4 > len(data) {
+			return nil, nil, fmt.Errorf("invalid composite glyph")
+		}
+		c := glyfComponentType{
+			flags: int(binary.BigEndian.Uint16(data[pos:])),
+			gid:   int(binary.BigEndian.Uint16(data[pos+2:])),
+		}
+		pos += 4
+		var p glyfPointType
+		size := 2
+		if c.flags&symbolWords != 0 {
+			size = 4
+		}
+		if pos+size > len(data) {
+			return nil, nil, fmt.Errorf("invalid composite glyph")
+		}
+		switch {
+		case c.flags&glyfArgsXY == 0:
+			// Point numbers, which do not vary
+		case size == 4:
+			p.x = float64(int16(binary.BigEndian.Uint16(data[pos:])))
+			p.y = float64(int16(binary.BigEndian.Uint16(data[pos+2:])))
+		default:
+			p.x, p.y = float64(int8(data[pos])), float64(int8(data[pos+1]))
+		}
+		args := data[pos : pos+size]
+		pos += size
+		switch {
+		case c.flags&symbolScale != 0:
+			size = 2
+		case c.flags&symbolAllScale != 0:
+			size = 4
+		case c.flags&symbol2x2 != 0:
+			size = 8
+		default:
+			size = 0
+		}
+		if pos+size > len(data) {
+			return nil, nil, fmt.Errorf("invalid composite glyph")
+		}
+		if c.flags&glyfArgsXY == 0 {
+			c.transform = append(append([]byte{}, args...), data[pos:pos+size]...)
+		} else {
+			c.transform = data[pos : pos+size]
+		}
+		pos += size
+		more = c.flags&symbolContinue != 0
+		components = append(components, c)
+		points = append(points, p)
+	}
+	return
+}
+
+// encodeCompositeGlyph returns the glyf data of the composite glyph orig with
+// the component offsets replaced by points. The bounding box is moved by dx.
+// Hinting instructions are removed.
+func encodeCompositeGlyph(orig []byte, components []glyfComponentType, points []glyfPointType, dx int) (data []byte) {
+	data = append(data, orig[0:2]...)
+	for j := 0; j < 4; j++ {
+		v := int(int16(binary.BigEndian.Uint16(orig[2+2*j:])))
+		if j%2 == 0 {
+			v += dx
+		}
+		data = append(data, packUint16(v)...)
+	}
+	for j, c := range components {
+		flags := c.flags &^ glyfInstruction
+		if flags&glyfArgsXY != 0 {
+			flags |= symbolWords
+		}
+		data = append(append(data, packUint16(flags)...), packUint16(c.gid)...)
+		if flags&glyfArgsXY != 0 {
+			data = append(append(data, packUint16(int(points[j].x))...), packUint16(int(points[j].y))...)
+		}
+		data = append(data, c.transform...)
+	}
+	return
+}
+
+// glyphDeltas returns the displacement of each point of glyph gid in the
+// instance. points includes the four phantom points. Points whose
+// displacement is not given explicitly are interpolated within the contours
+// specified by endPts.
+func (v *fontVariationType) glyphDeltas(gid int, points []glyfPointType, endPts []int) (deltas []glyfPointType, err error) {
+	deltas = make([]glyfPointType, len(points))
+	if v.gvar == nil {
+		return
+	}
+	g := v.gvar
+	invalid := fmt.Errorf("invalid gvar table")
+	axisCount := int(binary.BigEndian.Uint16(g[4:]))
+	sharedCount := int(binary.BigEndian.Uint16(g[6:]))
+	sharedPos := int(binary.BigEndian.Uint32(g[8:]))
+	glyphCount := int(binary.BigEndian.Uint16(g[12:]))
+	longOffsets := binary.BigEndian.Uint16(g[14:])&1 != 0
+	dataPos := int(binary.BigEndian.Uint32(g[16:]))
+	if gid >= glyphCount {
+		return
+	}
+	offset := func(j int) int {
+		if longOffsets {
+			return int(binary.BigEndian.Uint32(g[20+4*j:]))
+		}
+		return 2 * int(binary.BigEndian.Uint16(g[20+2*j:]))
+	}
+	if longOffsets && 20+4*glyphCount+4 > len(g) || !longOffsets && 20+2*glyphCount+2 > len(g) ||
+		sharedPos+2*axisCount*sharedCount > len(g) {
+		return nil, invalid
+	}
+	start, end := dataPos+offset(gid), dataPos+offset(gid+1)
+	if start >= end {
+		return
+	}
+	if end > len(g) || start+4 > end {
+		return nil, invalid
+	}
+	g = g[start:end]
+	tupleCount := int(binary.BigEndian.Uint16(g))
+	pos := 4
+	serial := int(binary.BigEndian.Uint16(g[2:]))
+	var sharedPoints []int
+	if tupleCount&0x8000 != 0 {
+		if sharedPoints, serial, err = gvarPoints(g, serial); err != nil {
+			return
+		}
+	}
+	tuple := func(data []byte, pos int) []variationRegionType {
+		region := make([]variationRegionType, axisCount)
+		for j := range region {
+			region[j].peak = f2dot14(data, pos+2*j)
+		}
+		return region
+	}
+	for j := 0; j < tupleCount&0x0FFF; j++ {
+		if pos+4 > len(g) {
+			return nil, invalid
+		}
+		size := int(binary.BigEndian.Uint16(g[pos:]))
+		index := int(binary.BigEndian.Uint16(g[pos+2:]))
+		pos += 4
+		var region []variationRegionType
+		if index&0x8000 != 0 {
+			if pos+2*axisCount > len(g) {
+				return nil, invalid
+			}
+			region = tuple(g, pos)
+			pos += 2 * axisCount
+		} else {
+			if index&0x0FFF >= sharedCount {
+				return nil, invalid
+			}
+			region = tuple(v.gvar, sharedPos+2*axisCount*(index&0x0FFF))
+		}
+		if index&0x4000 != 0 {
+			if pos+4*axisCount > len(g) {
+				return nil, invalid
+			}
+			for k := range region {
+				region[k].start = f2dot14(g, pos+2*k)
+				region[k].end = f2dot14(g, pos+2*axisCount+2*k)
+			}
+			pos += 4 * axisCount
+		} else {
+			for k, r := range region {
+				region[k].start, region[k].end = math.Min(0, r.peak), math.Max(0, r.peak)
+			}
+		}
+		tupleData := serial
+		serial += size
+		if serial > len(g) {
+			return nil, invalid
+		}
+		scalar := tupleScalar(v.coords, region)
+		if scalar == 0 {
+			continue
+		}
+		tupleEnd := serial
+		pointNums := sharedPoints
+		if index&0x2000 != 0 {
+			if pointNums, tupleData, err = gvarPoints(g[:tupleEnd], tupleData); err != nil {
+				return
+			}
+		}
+		count := len(pointNums)
+		if pointNums == nil {
+			count = len(points)
+		}
+		var dx, dy []int
+		if dx, tupleData, err = gvarDeltas(g[:tupleEnd], tupleData, count); err != nil {
+			return
+		}
+		if dy, _, err = gvarDeltas(g[:tupleEnd], tupleData, count); err != nil {
+			return
+		}
+		td := make([]glyfPointType, len(points))
+		touched := make([]bool, len(points))
+		for k := 0; k < count; k++ {
+			p := k
+			if pointNums != nil {
+				p = pointNums[k]
+			}
+			if p < len(points) {
+				td[p].x, td[p].y = float64(dx[k]), float64(dy[k])
+				touched[p] = true
+			}
+		}
+		if pointNums != nil {
+			first := 0
+			for _, last := range endPts {
+				if last < len(points)-4 {
+					iupContour(points, td, touched, first, last)
+				}
+				first = last + 1
+			}
+		}
+		for k := range deltas {
+			deltas[k].x += scalar * td[k].x
+			deltas[k].y += scalar * td[k].y
+		}
+	}
+	return
+}
+
+// gvarPoints reads packed point numbers at position pos of data. The returned
+// slice is nil if the numbers refer to all points of a glyph.
+func gvarPoints(data []byte, pos int) (points []int, end int, err error) {
+	invalid := fmt.Errorf("invalid gvar point numbers")
+	if pos >= len(data) {
+		return nil, 0, invalid
+	}
+	count := int(data[pos])
+	pos++
+	if count == 0 {
+		return nil, pos, nil
+	}
+	if count&0x80 != 0 {
+		if pos >= len(data) {
+			return nil, 0, invalid
+		}
+		count = (count&0x7F)<<8 | int(data[pos])
+		pos++
+	}
+	points = make([]int, 0, count)
+	p := 0
+	for len(points) < count {
+		if pos >= len(data) {
+			return nil, 0, invalid
+		}
+		control := data[pos]
+		pos++
+		for j := 0; j <= int(control&0x7F) && len(points) < count; j++ {
+			if control&0x80 != 0 {
+				if pos+2 > len(data) {
+					return nil, 0, invalid
+				}
+				p += int(binary.BigEndian.Uint16(data[pos:]))
+				pos += 2
+			} else {
+				if pos >= len(data) {
+					return nil, 0, invalid
+				}
+				p += int(data[pos])
+				pos++
+			}
+			points = append(points, p)
+		}
+	}
+	return points, pos, nil
+}
+
+// gvarDeltas reads count packed deltas at position pos of data.
+func gvarDeltas(data []byte, pos, count int) (deltas []int, end int, err error) {
+	deltas = make([]int, 0, count)
+	for len(deltas) < count {
+		if pos >= len(data) {
+			return nil, 0, fmt.Errorf("invalid gvar deltas")
+		}
+		control := data[pos]
+		pos++
+		for j := 0; j <= int(control&0x3F) && len(deltas) < count; j++ {
+			switch {
+			case control&0x80 != 0:
+				deltas = append(deltas, 0)
+			case control&0x40 != 0:
+				if pos+2 > len(data) {
+					return nil, 0, fmt.Errorf("invalid gvar deltas")
+				}
+				deltas = append(deltas, int(int16(binary.BigEndian.Uint16(data[pos:]))))
+				pos += 2
+			default:
+				if pos >= len(data) {
+					return nil, 0, fmt.Errorf("invalid gvar deltas")
+				}
+				deltas = append(deltas, int(int8(data[pos])))
+				pos++
+			}
+		}
+	}
+	return deltas, pos, nil
+}
+
+// iupContour interpolates the deltas of the points of the contour from first
+// to last that have not been touched, from the deltas of the nearest touched
+// points before and after them.
+func iupContour(points, deltas []glyfPointType, touched []bool, first, last int) {
+	var refs []int
+	for j := first; j <= last; j++ {
+		if touched[j] {
+			refs = append(refs, j)
+		}
+	}
+	if len(refs) == 0 || len(refs) == last-first+1 {
+		return
+	}
+	sort.Ints(refs)
+	interpolate := func(c, c1, c2, d1, d2 float64) float64 {
+		if c1 == c2 {
+			if d1 == d2 {
+				return d1
+			}
+			return 0
+		}
+		if c1 > c2 {
+			c1, c2, d1, d2 = c2, c1, d2, d1
+		}
+		switch {
+		case c <= c1:
+			return d1
+		case c >= c2:
+			return d2
+		}
+		return d1 + (c-c1)*(d2-d1)/(c2-c1)
+	}
+	for k, ref := range refs {
+		next := refs[(k+1)%len(refs)]
+		for j := ref + 1; ; j++ {
+			if j > last {
+				j = first
+			}
+			if j == next {
+				break
+			}
+			deltas[j].x = interpolate(points[j].x, points[ref].x, points[next].x, deltas[ref].x, deltas[next].x)
+			deltas[j].y = interpolate(points[j].y, points[ref].y, points[next].y, deltas[ref].y, deltas[next].y)
+		}
+	}
+}
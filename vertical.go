@@ -0,0 +1,666 @@
+package gofpdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// vertMetricType holds the vertical metrics of a glyph in thousandths of an
+// em: the advance height and the height of the vertical origin, which is the
+// top center of the glyph box, above the baseline.
+type vertMetricType struct {
+	advance, originY int
+}
+
+// verticalType holds the data of a UTF-8 font that is needed to set text
+// vertically. Vertical alternates, which the GSUB table of the font defines
+// for glyphs such as punctuation and brackets, have no rune of their own, so
+// each one is assigned an unused CID in the Private Use Area. The cmap of the
+// font subset maps these CIDs to the alternates.
+type verticalType struct {
+	vmtx        []byte                 // vmtx table, nil if the font has no vertical metrics
+	longMetrics int                    // number of advance heights in vmtx
+	scale       float64                // thousandths of an em per font unit
+	dflt        vertMetricType         // metrics of glyphs without vmtx entry
+	metrics     map[int]vertMetricType // metrics of the CIDs used in vertical text
+	alternates  map[int]int            // vertical alternate glyph by glyph ID
+	cids        map[rune]int           // CID assigned to the vertical alternate of a rune
+	runes       map[int]rune           // rune of each CID assigned to an alternate
+	gids        map[int]int            // alternate glyph of each assigned CID
+	next        int                    // next CID to examine for assignment
+}
+
+// CellFormatVertical prints a rectangular cell with optional borders,
+// background color and text that is set vertically, from top to bottom, in a
+// column that runs down the cell. The upper-left corner of the cell
+// corresponds to the current position. Vertical text requires a font added
+// with AddUTF8Font(), one of its variants or AddCJKFont().
+//
+// Characters of East Asian scripts, such as ideographs, kana and Hangul, as
+// well as fullwidth forms and symbols, are set upright with the Identity-V
+// encoding of the font. They advance by the vertical metrics of the vhea and
+// vmtx tables of the font, or by one em if it has none, and punctuation and
+// brackets are replaced by the vertical alternates of the GSUB 'vert' or
+// 'vrt2' feature of the font. Runs of other characters, such as Latin words
+// and numbers, are rotated clockwise and advance by their width. Font
+// fallbacks, word spacing and the underline and strikeout styles are not
+// applied to vertical text.
+//
+// w and h specify the width and height of the cell. If w is 0, the cell
+// extends up to the right margin. If automatic page breaking is enabled and
+// the cell goes beyond the limit, a page break is done before outputting.
+//
+// borderStr, fill, link and linkStr are interpreted as they are in
+// CellFormat().
+//
+// ln indicates where the current position should go after the call. Possible
+// values are 0 (below the cell) and 1 (to the top of the column to the left
+// of the cell, where the next column of Japanese or Chinese text begins).
+//
+// alignStr specifies how the text is to be positioned within the cell. The
+// placement of the column is controlled by including "L", "C" or "R" (left,
+// center, right) in alignStr, and the position of the text in the column by
+// including "T", "M" or "B" (top, middle, bottom). The default alignment is
+// center top.
+func (f *Fpdf) CellFormatVertical(w, h float64, txtStr, borderStr string, ln int,
+	alignStr string, fill bool, link int, linkStr string) {
+	if f.err != nil {
+		return
+	}
+	if f.currentFont.Name != "" && !f.isCurrentUTF8 {
+		f.err = fmt.Errorf("vertical text requires a UTF-8 or CJK font")
+		return
+	}
+	x := f.x
+	// Borders, background and page break are handled as for horizontal cells
+	f.CellFormat(w, h, "", borderStr, 0, "", fill, 0, "")
+	if f.err != nil {
+		return
+	}
+	w = f.x - x
+	y := f.y
+	if len(txtStr) > 0 && h > 0 {
+		length := f.GetStringLengthVertical(txtStr)
+		var cx, dy float64
+		switch {
+		case strings.Contains(alignStr, "L"):
+			cx = x + f.cMargin + f.fontSize/2
+		case strings.Contains(alignStr, "R"):
+			cx = x + w - f.cMargin - f.fontSize/2
+		default:
+			cx = x + w/2
+		}
+		switch {
+		case strings.Contains(alignStr, "M"):
+			dy = (h - length) / 2
+		case strings.Contains(alignStr, "B"):
+			dy = h - f.cMargin - length
+		default:
+			dy = f.cMargin
+		}
+		var s fmtBuffer
+		if f.colorFlag {
+			s.printf("q %s ", f.color.text.str)
+		}
+		s.printf("%s", f.syntheticBoldBegin())
+		s.printf("%s", f.verticalText(cx, y+dy, txtStr))
+		s.printf("%s", f.syntheticBoldEnd())
+		if f.colorFlag {
+			s.printf(" Q")
+		}
+		f.out(s.String())
+		if link > 0 || len(linkStr) > 0 {
+			f.newLink(cx-f.fontSize/2, y+dy, f.fontSize, length, link, linkStr)
+		}
+	}
+	f.lasth = h
+	if ln > 0 {
+		f.x = x - w
+	} else {
+		f.x = x
+		f.y = y + h
+	}
+}
+
+// CellVertical is a simpler version of CellFormatVertical with no fill,
+// border, links or special alignment.
+func (f *Fpdf) CellVertical(w, h float64, txtStr string) {
+	f.CellFormatVertical(w, h, txtStr, "", 0, "", false, 0, "")
+}
+
+// MultiCellVertical supports printing text that is set vertically in several
+// columns, the way Japanese and Chinese text is laid out in books and forms.
+// The text is broken into columns of length h, which run from top to bottom
+// and follow each other from right to left. The first column begins at the
+// current position, so that its upper-left corner corresponds to it, and each
+// following column is placed w to the left of the previous one. Columns break
+// between East Asian characters and at spaces, and automatically at newline
+// characters; a column does not begin with closing punctuation or end with
+// an opening bracket. Characters are set as described for
+// CellFormatVertical().
+//
+// w is the width of each column. If h is 0, the columns extend down to the
+// bottom margin.
+//
+// borderStr specifies how the text block border will be drawn. An empty
+// string indicates no border, "1" indicates a full border, and one or more of
+// "L", "T", "R" and "B" indicate the left, top, right and bottom sides of the
+// border.
+//
+// alignStr specifies how the text of each column is positioned: "T" (top, the
+// default), "M" (middle) or "B" (bottom).
+//
+// fill is true to paint the block background or false to leave it
+// transparent.
+//
+// If automatic page breaking is enabled and a column would cross the left
+// margin, a page is added and the block continues at the top of the new page,
+// with its first column at the right margin.
+//
+// After the call, the current position is at the top of the column to the
+// left of the block.
+func (f *Fpdf) MultiCellVertical(w, h float64, txtStr, borderStr, alignStr string, fill bool) {
+	if f.err != nil {
+		return
+	}
+	if f.currentFont.Name == "" {
+		f.err = fmt.Errorf("font has not been set; unable to render text")
+		return
+	}
+	if h == 0 {
+		h = f.pageBreakLimit() - f.y
+	}
+	borderStr = strings.ToUpper(borderStr)
+	if borderStr == "1" {
+		borderStr = "LTRB"
+	}
+	columns := f.splitVertical(txtStr, h-2*f.cMargin)
+	// A column that would cross the left margin continues the block on a new
+	// page, as a line of MultiCell() does at the bottom margin
+	pageBreak := func(x float64) bool {
+		return x < f.lMargin && !f.inHeader && !f.inFooter && f.acceptPageBreak()
+	}
+	newPage := func() {
+		f.AddPageFormat(f.curOrientation, f.curPageSize)
+		f.x = f.w - f.rMargin - w
+	}
+	if pageBreak(f.x) {
+		newPage()
+	}
+	first := true
+	for j, column := range columns {
+		if f.err != nil {
+			return
+		}
+		brk := j < len(columns)-1 && pageBreak(f.x-w)
+		var b string
+		for _, side := range "TB" {
+			if strings.ContainsRune(borderStr, side) {
+				b += string(side)
+			}
+		}
+		if first && strings.Contains(borderStr, "R") {
+			b += "R"
+		}
+		if (j == len(columns)-1 || brk) && strings.Contains(borderStr, "L") {
+			b += "L"
+		}
+		f.CellFormatVertical(w, h, column, b, 1, alignStr, fill, 0, "")
+		first = brk
+		if brk {
+			newPage()
+		}
+	}
+}
+
+// GetStringLengthVertical returns the length in user units of the column
+// that s occupies when it is set vertically with the current UTF-8 font, as
+// done by CellFormatVertical().
+func (f *Fpdf) GetStringLengthVertical(s string) float64 {
+	if f.err != nil || !f.isCurrentUTF8 {
+		return 0
+	}
+	var length int
+	for _, r := range s {
+		length += f.verticalAdvance(&f.currentFont, r)
+	}
+	return float64(length) * f.fontSize / 1000
+}
+
+// splitVertical breaks txtStr into columns that are no longer than length
+// user units when set vertically with the current font.
+func (f *Fpdf) splitVertical(txtStr string, length float64) (columns []string) {
+	max := length * 1000 / f.fontSize
+	runes := []rune(strings.Replace(txtStr, "\r", "", -1))
+	start, sep := 0, 0
+	pos := 0.0
+	for j := 0; j < len(runes); j++ {
+		r := runes[j]
+		if r == '\n' {
+			columns = append(columns, string(runes[start:j]))
+			start, sep, pos = j+1, 0, 0
+			continue
+		}
+		if j > start && verticalBreakable(runes[j-1], r) {
+			sep = j
+		}
+		pos += float64(f.verticalAdvance(&f.currentFont, r))
+		if pos > max && j > start {
+			if sep <= start {
+				sep = j
+			}
+			columns = append(columns, strings.TrimRight(string(runes[start:sep]), " "))
+			for sep < len(runes) && runes[sep] == ' ' {
+				sep++
+			}
+			start, pos = sep, 0
+			j = start - 1
+		}
+	}
+	if start < len(runes) || len(columns) == 0 {
+		columns = append(columns, string(runes[start:]))
+	}
+	return
+}
+
+// verticalBreakable returns true if a column of vertical text may break
+// between a and b.
+func verticalBreakable(a, b rune) bool {
+	if b == ' ' || strings.ContainsRune("、。，．・：；？！ー）」』】〕〉》］｝〙〗ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ々,.:;!?)]}", b) ||
+		strings.ContainsRune("（「『【〔〈《［｛〘〖([{", a) {
+		return false
+	}
+	return a == ' ' || verticalUpright(a) || verticalUpright(b)
+}
+
+// verticalUprightRanges holds the ranges of characters that are set upright
+// in vertical text, following the Vertical_Orientation property of Unicode.
+var verticalUprightRanges = [][2]rune{
+	{0x1100, 0x11FF},   // Hangul Jamo
+	{0x2460, 0x24FF},   // Enclosed Alphanumerics
+	{0x25A0, 0x27BF},   // Geometric Shapes, Miscellaneous Symbols, Dingbats
+	{0x2E80, 0xA4CF},   // CJK Radicals through Yi Radicals
+	{0xA960, 0xA97F},   // Hangul Jamo Extended-A
+	{0xAC00, 0xD7FF},   // Hangul Syllables, Hangul Jamo Extended-B
+	{0xE000, 0xFAFF},   // Private Use Area, CJK Compatibility Ideographs
+	{0xFE10, 0xFE1F},   // Vertical Forms
+	{0xFE30, 0xFE4F},   // CJK Compatibility Forms
+	{0xFF01, 0xFF60},   // Fullwidth Forms
+	{0xFFE0, 0xFFE7},   // Fullwidth Signs
+	{0x1F000, 0x1FAFF}, // Symbols and emoji
+	{0x20000, 0x3FFFD}, // Supplementary ideographs
+}
+
+// verticalUpright returns true if r is set upright in vertical text rather
+// than rotated clockwise.
+func verticalUpright(r rune) bool {
+	for _, rng := range verticalUprightRanges {
+		if r >= rng[0] && r <= rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// verticalAdvance returns the distance, in thousandths of an em, by which r
+// advances the text position in vertical text set with font.
+func (f *Fpdf) verticalAdvance(font *fontDefType, r rune) int {
+	if !verticalUpright(r) {
+		return fontRuneWidth(font, r)
+	}
+	return fontVerticalMetric(font, f.verticalCID(font, r)).advance
+}
+
+// fontVerticalMetric returns the vertical metrics of the glyph shown by cid in
+// vertical text set with font. The glyphs of fonts imported with AddCJKFont()
+// have the default metrics of the CIDFont dictionary.
+func fontVerticalMetric(font *fontDefType, cid int) vertMetricType {
+	if font.utf8File == nil {
+		return vertMetricType{advance: 1000, originY: 880}
+	}
+	return font.utf8File.verticalMetric(cid)
+}
+
+// verticalCID returns the CID that shows r upright in vertical text set with
+// font, which is the CID of its vertical alternate if the font has one.
+func (f *Fpdf) verticalCID(font *fontDefType, r rune) int {
+	if font.utf8File == nil {
+		// The vertical CMap selects the vertical forms
+		return int(r)
+	}
+	cid := fontVertical(font).cid(font.utf8File, r)
+	if cid != int(r) && int(r) < len(font.Cw) && font.Cw[cid] == 0 {
+		font.Cw[cid] = font.Cw[r]
+	}
+	return cid
+}
+
+// verticalText returns the operators that show txtStr in a column whose
+// center line is at x and which begins at y, in user units. Upright runs are
+// shown with the vertical font, whose glyphs are positioned at their top
+// center, and rotated runs with the horizontal font and a rotated text
+// matrix.
+func (f *Fpdf) verticalText(x, y float64, txtStr string) string {
+	var s fmtBuffer
+	font := &f.currentFont
+	if _, ok := f.verticalFonts[font.i]; !ok {
+		f.verticalFonts[font.i] = 0
+	}
+	k := f.k
+	d := font.Desc
+	// Baseline of rotated runs, which centers their em box on the center line
+	base := x*k - float64(d.Ascent+d.Descent)/2*f.fontSizePt/1000
+	// Distance from the top in thousandths of a text space unit
+	pos := 0.0
+	var b strings.Builder
+	flush := func(rotated bool) {
+		if b.Len() == 0 {
+			return
+		}
+		top := (f.h-y)*k - pos*f.fontSizePt/1000
+		if rotated {
+			str := b.String()
+			for _, r := range str {
+				font.usedRunes[int(r)] = int(r)
+				pos += float64(fontRuneWidth(font, r))
+			}
+			s.printf("BT 0 -1 1 0 %.2f %.2f Tm /F%s %.2f Tf (%s)Tj ET ", base, top, font.i, f.fontSizePt,
+				f.escape(utf8toutf16(str, false)))
+		} else {
+			var cids []uint16
+			for _, r := range b.String() {
+				cid := f.verticalCID(font, r)
+				font.usedRunes[cid] = cid
+				pos += float64(fontVerticalMetric(font, cid).advance)
+				cids = append(cids, utf16.Encode([]rune{rune(cid)})...)
+			}
+			buf := make([]byte, 2*len(cids))
+			for j, c := range cids {
+				binary.BigEndian.PutUint16(buf[2*j:], c)
+			}
+			s.printf("BT /F%sV %.2f Tf %.2f %.2f Td (%s)Tj ET ", font.i, f.fontSizePt, x*k, top, f.escape(string(buf)))
+		}
+		b.Reset()
+	}
+	rotated := false
+	for _, r := range txtStr {
+		if upright := verticalUpright(r); upright == rotated {
+			flush(rotated)
+			rotated = !upright
+		}
+		b.WriteRune(r)
+	}
+	flush(rotated)
+	s.printf("BT /F%s %.2f Tf ET", font.i, f.fontSizePt)
+	return s.String()
+}
+
+// fontVertical returns the vertical writing data of the UTF-8 font, which is
+// read when this function is first called for the font.
+func fontVertical(font *fontDefType) *verticalType {
+	utf := font.utf8File
+	if utf.vertical == nil {
+		// Glyphs without vertical metrics are centered in an em box that is
+		// centered on the ascender and descender of the font
+		d := font.Desc
+		utf.vertical = utf.parseVertical(vertMetricType{
+			advance: 1000,
+			originY: d.Ascent + (1000-d.Ascent+d.Descent)/2,
+		})
+	}
+	return utf.vertical
+}
+
+// parseVertical reads the vertical metrics and the vertical alternates of the
+// font.
+func (utf *utf8FontFile) parseVertical(dflt vertMetricType) *verticalType {
+	v := &verticalType{
+		scale:      1000 / float64(utf.fontElementSize),
+		dflt:       dflt,
+		metrics:    make(map[int]vertMetricType),
+		alternates: parseGSUBVertical(utf.getTableData("GSUB")),
+		cids:       make(map[rune]int),
+		runes:      make(map[int]rune),
+		gids:       make(map[int]int),
+		next:       0xF8FF,
+	}
+	if vhea := utf.getTableData("vhea"); len(vhea) >= 36 {
+		v.longMetrics = int(binary.BigEndian.Uint16(vhea[34:]))
+		if vmtx := utf.getTableData("vmtx"); v.longMetrics > 0 && len(vmtx) >= 4*v.longMetrics {
+			v.vmtx = vmtx
+		}
+	}
+	return v
+}
+
+// cid returns the CID that shows r in vertical text. It is r itself unless
+// the font has a vertical alternate for its glyph, in which case the CID
+// assigned to the alternate is returned.
+func (v *verticalType) cid(utf *utf8FontFile, r rune) int {
+	if cid, ok := v.cids[r]; ok {
+		return cid
+	}
+	gid, ok := utf.glyphIndex(r)
+	alt, found := v.alternates[gid]
+	if !ok || !found {
+		return int(r)
+	}
+	for ; v.next >= 0xE000; v.next-- {
+		if _, used := utf.glyphIndex(rune(v.next)); !used {
+			break
+		}
+	}
+	if v.next < 0xE000 {
+		// No unused CID is left; the horizontal glyph is shown
+		v.cids[r] = int(r)
+		return int(r)
+	}
+	cid := v.next
+	v.next--
+	v.cids[r] = cid
+	v.runes[cid] = r
+	v.gids[cid] = alt
+	utf.charSymbolDictionary[cid] = alt
+	return cid
+}
+
+// verticalMetric returns the vertical metrics of the glyph shown by cid.
+func (utf *utf8FontFile) verticalMetric(cid int) vertMetricType {
+	v := utf.vertical
+	if m, ok := v.metrics[cid]; ok {
+		return m
+	}
+	m := v.dflt
+	gid, ok := v.gids[cid]
+	if !ok {
+		gid, ok = utf.glyphIndex(rune(cid))
+	}
+	if ok && v.vmtx != nil {
+		pos := 4 * v.longMetrics
+		if gid < v.longMetrics {
+			m.advance = int(math.Round(v.scale * float64(binary.BigEndian.Uint16(v.vmtx[4*gid:]))))
+			pos = 4*gid + 2
+		} else {
+			m.advance = int(math.Round(v.scale * float64(binary.BigEndian.Uint16(v.vmtx[4*v.longMetrics-4:]))))
+			pos += 2 * (gid - v.longMetrics)
+		}
+		if contours, err := utf.glyphOutline(gid); err == nil && pos+2 <= len(v.vmtx) {
+			if _, _, _, yMax, ok := outlineBounds(contours, 0); ok {
+				tsb := float64(int16(binary.BigEndian.Uint16(v.vmtx[pos:])))
+				m.originY = int(math.Round(v.scale * (tsb + yMax)))
+			}
+		}
+	}
+	v.metrics[cid] = m
+	return m
+}
+
+// verticalWidths returns the DW2 and W2 entries of the CIDFont dictionary of
+// font, which hold the vertical metrics of the CIDs used in vertical text.
+func verticalWidths(font *fontDefType) string {
+	v := font.utf8File.vertical
+	var s fmtBuffer
+	s.printf("/DW2 [%d %d]", v.dflt.originY, -v.dflt.advance)
+	var cids []int
+	for cid, m := range v.metrics {
+		if _, used := font.usedRunes[cid]; used && m != v.dflt {
+			cids = append(cids, cid)
+		}
+	}
+	if len(cids) == 0 {
+		return s.String()
+	}
+	sort.Ints(cids)
+	s.printf("\n/W2 [")
+	for _, cid := range cids {
+		m := v.metrics[cid]
+		width := 0
+		if cid < len(font.Cw) && font.Cw[cid] != 65535 {
+			width = font.Cw[cid]
+		}
+		s.printf("%d [%d %d %d] ", cid, -m.advance, width/2, m.originY)
+	}
+	s.printf("]")
+	return s.String()
+}
+
+// toUnicode returns the ToUnicode CMap of a font that is used for vertical
+// text. It maps each CID to the rune that equals it, except for the CIDs
+// assigned to vertical alternates, which are mapped to the runes they stand
+// for.
+func (v *verticalType) toUnicode() string {
+	if len(v.runes) == 0 {
+		return toUnicode
+	}
+	var cids []int
+	for cid := range v.runes {
+		cids = append(cids, cid)
+	}
+	sort.Ints(cids)
+	var ranges, chars []string
+	start := 0
+	for _, cid := range cids {
+		if cid > start {
+			ranges = append(ranges, sprintf("<%04X> <%04X> <%04X>", start, cid-1, start))
+		}
+		var hex string
+		for _, u := range utf16.Encode([]rune{v.runes[cid]}) {
+			hex += sprintf("%04X", u)
+		}
+		chars = append(chars, sprintf("<%04X> <%s>", cid, hex))
+		start = cid + 1
+	}
+	ranges = append(ranges, sprintf("<%04X> <FFFF> <%04X>", start, start))
+	var s fmtBuffer
+	// Each section of a CMap holds at most 100 mappings
+	for _, list := range []struct {
+		op    string
+		items []string
+	}{{"bfrange", ranges}, {"bfchar", chars}} {
+		for j := 0; j < len(list.items); j += 100 {
+			end := j + 100
+			if end > len(list.items) {
+				end = len(list.items)
+			}
+			s.printf("%d begin%s\n%s\nend%s\n", end-j, list.op, strings.Join(list.items[j:end], "\n"), list.op)
+		}
+	}
+	return strings.Replace(toUnicode, "1 beginbfrange\n<0000> <FFFF> <0000>\nendbfrange\n", s.String(), 1)
+}
+
+// parseGSUBVertical returns the vertical alternates that the 'vert' and
+// 'vrt2' features of a GSUB table define with single substitutions, by glyph
+// ID. Damaged parts of the table are ignored.
+func parseGSUBVertical(data []byte) (alternates map[int]int) {
+	alternates = make(map[int]int)
+	u16 := func(pos int) int {
+		if pos < 0 || pos+2 > len(data) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(data[pos:]))
+	}
+	if len(data) < 10 {
+		return
+	}
+	featureList, lookupList := u16(6), u16(8)
+	var lookups []int
+	for j, count := 0, u16(featureList); j < count; j++ {
+		rec := featureList + 2 + 6*j
+		if rec+6 > len(data) {
+			break
+		}
+		if tag := string(data[rec : rec+4]); tag != "vert" && tag != "vrt2" {
+			continue
+		}
+		feature := featureList + u16(rec+4)
+		for n, index := 0, u16(feature+2); n < index; n++ {
+			lookups = append(lookups, u16(feature+4+2*n))
+		}
+	}
+	for _, index := range lookups {
+		if index >= u16(lookupList) {
+			continue
+		}
+		lookup := lookupList + u16(lookupList+2+2*index)
+		lookupType := u16(lookup)
+		for n, count := 0, u16(lookup+4); n < count; n++ {
+			sub, tp := lookup+u16(lookup+6+2*n), lookupType
+			if tp == 7 && u16(sub) == 1 {
+				// Extension substitution
+				tp = u16(sub + 2)
+				sub += u16(sub+4)<<16 | u16(sub+6)
+			}
+			if tp != 1 {
+				continue
+			}
+			glyphs := gsubCoverage(data, sub+u16(sub+2))
+			for j, gid := range glyphs {
+				if _, ok := alternates[gid]; ok || gid < 0 {
+					continue
+				}
+				switch u16(sub) {
+				case 1:
+					alternates[gid] = (gid + int(int16(u16(sub+4)))) & 0xFFFF
+				case 2:
+					if j < u16(sub+4) {
+						alternates[gid] = u16(sub + 6 + 2*j)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// gsubCoverage returns the glyph IDs of the coverage table at pos, in the
+// order of their coverage index.
+func gsubCoverage(data []byte, pos int) (glyphs []int) {
+	u16 := func(pos int) int {
+		if pos < 0 || pos+2 > len(data) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(data[pos:]))
+	}
+	switch u16(pos) {
+	case 1:
+		for j, count := 0, u16(pos+2); j < count && pos+6+2*j <= len(data); j++ {
+			glyphs = append(glyphs, u16(pos+4+2*j))
+		}
+	case 2:
+		for j, count := 0, u16(pos+2); j < count && pos+10+6*j <= len(data); j++ {
+			rec := pos + 4 + 6*j
+			for gid := u16(rec); gid <= u16(rec+2); gid++ {
+				index := u16(rec+4) + gid - u16(rec)
+				for len(glyphs) <= index {
+					glyphs = append(glyphs, -1)
+				}
+				glyphs[index] = gid
+			}
+		}
+	}
+	return
+}